@@ -36,12 +36,15 @@ func AuthMethodTypesToAMR(methodTypes []domain.UserAuthMethodType) []string {
 			factors++
 		case domain.UserAuthMethodTypeTOTP,
 			domain.UserAuthMethodTypeOTPSMS,
-			domain.UserAuthMethodTypeOTPEmail:
+			domain.UserAuthMethodTypeOTPEmail,
+			domain.UserAuthMethodTypeRecoveryCode:
 			// a user could use multiple (t)otp, which is a factor, but still will be returned as a single `otp` entry
 			otp++
 			factors++
-		case domain.UserAuthMethodTypeIDP:
+		case domain.UserAuthMethodTypeIDP,
+			domain.UserAuthMethodTypeClientCert:
 			// no AMR value according to specification
+			// (a client certificate's key might be hardware or software secured, which is not known)
 			factors++
 		case domain.UserAuthMethodTypeUnspecified:
 			// ignore
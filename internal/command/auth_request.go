@@ -101,13 +101,19 @@ func (c *Commands) LinkSessionToAuthRequest(ctx context.Context, id, sessionID,
 	if err := c.sessionPermission(ctx, sessionWriteModel, sessionToken, domain.PermissionSessionWrite); err != nil {
 		return nil, nil, err
 	}
+	if sessionToken != "" {
+		if err := c.sessionTokenUsed(ctx, sessionWriteModel); err != nil {
+			return nil, nil, err
+		}
+	}
 
+	tokenContext := sessionWriteModel.TokenContext()
 	if err := c.pushAppendAndReduce(ctx, writeModel, authrequest.NewSessionLinkedEvent(
 		ctx, &authrequest.NewAggregate(id, authz.GetInstance(ctx).InstanceID()).Aggregate,
 		sessionID,
-		sessionWriteModel.UserID,
-		sessionWriteModel.AuthenticationTime(),
-		sessionWriteModel.AuthMethodTypes(),
+		tokenContext.UserID,
+		tokenContext.AuthTime,
+		tokenContext.AuthMethodTypes,
 	)); err != nil {
 		return nil, nil, err
 	}
@@ -169,6 +169,7 @@ func TestCommands_LinkSessionToAuthRequest(t *testing.T) {
 		eventstore      *eventstore.Eventstore
 		tokenVerifier   func(ctx context.Context, sessionToken, sessionID, tokenID string) (err error)
 		checkPermission domain.PermissionCheck
+		now             func() time.Time
 	}
 	type args struct {
 		ctx              context.Context
@@ -358,7 +359,7 @@ func TestCommands_LinkSessionToAuthRequest(t *testing.T) {
 					),
 					expectFilter(
 						eventFromEventPusher(
-							session.NewAddedEvent(mockCtx, &session.NewAggregate("sessionID", "org1").Aggregate)),
+							session.NewAddedEvent(mockCtx, &session.NewAggregate("sessionID", "orgID").Aggregate, 0, "", "", "", nil)),
 					),
 				),
 				tokenVerifier: func(ctx context.Context, sessionToken, sessionID, tokenID string) (err error) {
@@ -401,7 +402,7 @@ func TestCommands_LinkSessionToAuthRequest(t *testing.T) {
 					),
 					expectFilter(
 						eventFromEventPusher(
-							session.NewAddedEvent(mockCtx, &session.NewAggregate("sessionID", "org1").Aggregate)),
+							session.NewAddedEvent(mockCtx, &session.NewAggregate("sessionID", "orgID").Aggregate, 0, "", "", "", nil)),
 					),
 				),
 				tokenVerifier: func(ctx context.Context, sessionToken, sessionID, tokenID string) (err error) {
@@ -444,15 +445,15 @@ func TestCommands_LinkSessionToAuthRequest(t *testing.T) {
 					),
 					expectFilter(
 						eventFromEventPusher(
-							session.NewAddedEvent(mockCtx, &session.NewAggregate("sessionID", "org1").Aggregate),
+							session.NewAddedEvent(mockCtx, &session.NewAggregate("sessionID", "orgID").Aggregate, 0, "", "", "", nil),
 						),
 						eventFromEventPusher(
-							session.NewUserCheckedEvent(mockCtx, &session.NewAggregate("sessionID", "org1").Aggregate,
+							session.NewUserCheckedEvent(mockCtx, &session.NewAggregate("sessionID", "orgID").Aggregate,
 								"userID", testNow),
 						),
 						eventFromEventPusher(
-							session.NewPasswordCheckedEvent(mockCtx, &session.NewAggregate("sessionID", "org1").Aggregate,
-								testNow),
+							session.NewPasswordCheckedEvent(mockCtx, &session.NewAggregate("sessionID", "orgID").Aggregate,
+								testNow, ""),
 						),
 					),
 					expectPush(
@@ -497,6 +498,159 @@ func TestCommands_LinkSessionToAuthRequest(t *testing.T) {
 				},
 			},
 		},
+		{
+			"linked, activity recorded",
+			fields{
+				eventstore: eventstoreExpect(t,
+					expectFilter(
+						eventFromEventPusher(
+							authrequest.NewAddedEvent(mockCtx, &authrequest.NewAggregate("V2_id", "instanceID").Aggregate,
+								"loginClient",
+								"clientID",
+								"redirectURI",
+								"state",
+								"nonce",
+								[]string{"openid"},
+								[]string{"audience"},
+								domain.OIDCResponseTypeCode,
+								nil,
+								nil,
+								nil,
+								nil,
+								nil,
+								nil,
+							),
+						),
+					),
+					expectFilter(
+						eventFromEventPusher(
+							session.NewAddedEvent(mockCtx, &session.NewAggregate("sessionID", "orgID").Aggregate, 0, "", "", "", nil),
+						),
+						eventFromEventPusher(
+							session.NewUserCheckedEvent(mockCtx, &session.NewAggregate("sessionID", "orgID").Aggregate,
+								"userID", testNow),
+						),
+						eventFromEventPusher(
+							session.NewPasswordCheckedEvent(mockCtx, &session.NewAggregate("sessionID", "orgID").Aggregate,
+								testNow, ""),
+						),
+						eventFromEventPusherWithCreationDate(
+							session.NewLifetimeSetEvent(mockCtx, &session.NewAggregate("sessionID", "orgID").Aggregate,
+								0, time.Hour),
+							testNow,
+						),
+					),
+					expectPush(
+						[]*repository.Event{eventFromEventPusherWithInstanceID(
+							"instanceID",
+							session.NewActivityEvent(mockCtx, &session.NewAggregate("sessionID", "orgID").Aggregate),
+						)}),
+					expectPush(
+						[]*repository.Event{eventFromEventPusherWithInstanceID(
+							"instanceID",
+							authrequest.NewSessionLinkedEvent(mockCtx, &authrequest.NewAggregate("V2_id", "instanceID").Aggregate,
+								"sessionID",
+								"userID",
+								testNow,
+								[]domain.UserAuthMethodType{domain.UserAuthMethodTypePassword},
+							),
+						)}),
+				),
+				tokenVerifier: func(ctx context.Context, sessionToken, sessionID, tokenID string) (err error) {
+					return nil
+				},
+				checkPermission: newMockPermissionCheckAllowed(),
+				now: func() time.Time {
+					return testNow.Add(30 * time.Minute)
+				},
+			},
+			args{
+				ctx:          mockCtx,
+				id:           "V2_id",
+				sessionID:    "sessionID",
+				sessionToken: "token",
+			},
+			res{
+				details: &domain.ObjectDetails{ResourceOwner: "instanceID"},
+				authReq: &CurrentAuthRequest{
+					AuthRequest: &AuthRequest{
+						ID:           "V2_id",
+						LoginClient:  "loginClient",
+						ClientID:     "clientID",
+						RedirectURI:  "redirectURI",
+						State:        "state",
+						Nonce:        "nonce",
+						Scope:        []string{"openid"},
+						Audience:     []string{"audience"},
+						ResponseType: domain.OIDCResponseTypeCode,
+					},
+					SessionID:   "sessionID",
+					UserID:      "userID",
+					AuthMethods: []domain.UserAuthMethodType{domain.UserAuthMethodTypePassword},
+				},
+			},
+		},
+		{
+			"session idle expired",
+			fields{
+				eventstore: eventstoreExpect(t,
+					expectFilter(
+						eventFromEventPusher(
+							authrequest.NewAddedEvent(mockCtx, &authrequest.NewAggregate("V2_id", "instanceID").Aggregate,
+								"loginClient",
+								"clientID",
+								"redirectURI",
+								"state",
+								"nonce",
+								[]string{"openid"},
+								[]string{"audience"},
+								domain.OIDCResponseTypeCode,
+								nil,
+								nil,
+								nil,
+								nil,
+								nil,
+								nil,
+							),
+						),
+					),
+					expectFilter(
+						eventFromEventPusher(
+							session.NewAddedEvent(mockCtx, &session.NewAggregate("sessionID", "orgID").Aggregate, 0, "", "", "", nil),
+						),
+						eventFromEventPusher(
+							session.NewUserCheckedEvent(mockCtx, &session.NewAggregate("sessionID", "orgID").Aggregate,
+								"userID", testNow),
+						),
+						eventFromEventPusher(
+							session.NewPasswordCheckedEvent(mockCtx, &session.NewAggregate("sessionID", "orgID").Aggregate,
+								testNow, ""),
+						),
+						eventFromEventPusherWithCreationDate(
+							session.NewLifetimeSetEvent(mockCtx, &session.NewAggregate("sessionID", "orgID").Aggregate,
+								0, time.Hour),
+							testNow,
+						),
+					),
+				),
+				tokenVerifier: func(ctx context.Context, sessionToken, sessionID, tokenID string) (err error) {
+					return nil
+				},
+				checkPermission: newMockPermissionCheckAllowed(),
+				now: func() time.Time {
+					return testNow.Add(2 * time.Hour)
+				},
+			},
+			args{
+				ctx:          mockCtx,
+				id:           "V2_id",
+				sessionID:    "sessionID",
+				sessionToken: "token",
+			},
+			res{
+				wantErr: ErrSessionIdleExpired,
+			},
+		},
 		{
 			"linked with login client check",
 			fields{
@@ -523,15 +677,15 @@ func TestCommands_LinkSessionToAuthRequest(t *testing.T) {
 					),
 					expectFilter(
 						eventFromEventPusher(
-							session.NewAddedEvent(mockCtx, &session.NewAggregate("sessionID", "org1").Aggregate),
+							session.NewAddedEvent(mockCtx, &session.NewAggregate("sessionID", "orgID").Aggregate, 0, "", "", "", nil),
 						),
 						eventFromEventPusher(
-							session.NewUserCheckedEvent(mockCtx, &session.NewAggregate("sessionID", "org1").Aggregate,
+							session.NewUserCheckedEvent(mockCtx, &session.NewAggregate("sessionID", "orgID").Aggregate,
 								"userID", testNow),
 						),
 						eventFromEventPusher(
-							session.NewPasswordCheckedEvent(mockCtx, &session.NewAggregate("sessionID", "org1").Aggregate,
-								testNow),
+							session.NewPasswordCheckedEvent(mockCtx, &session.NewAggregate("sessionID", "orgID").Aggregate,
+								testNow, ""),
 						),
 					),
 					expectPush(
@@ -584,6 +738,7 @@ func TestCommands_LinkSessionToAuthRequest(t *testing.T) {
 				eventstore:           tt.fields.eventstore,
 				sessionTokenVerifier: tt.fields.tokenVerifier,
 				checkPermission:      tt.fields.checkPermission,
+				now:                  tt.fields.now,
 			}
 			details, got, err := c.LinkSessionToAuthRequest(tt.args.ctx, tt.args.id, tt.args.sessionID, tt.args.sessionToken, tt.args.checkLoginClient)
 			require.ErrorIs(t, err, tt.res.wantErr)
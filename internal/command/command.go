@@ -61,6 +61,13 @@ type Commands struct {
 	defaultAccessTokenLifetime      time.Duration
 	defaultRefreshTokenLifetime     time.Duration
 	defaultRefreshTokenIdleLifetime time.Duration
+	webauthnChallengeLifetime       time.Duration
+	maxSessionsPerUser              int
+	maxSessionPasswordCheckFailures int
+	maxUserPasswordCheckFailures    int
+	userPasswordCheckFailuresWindow time.Duration
+	// now is the clock of the session commands, it can be replaced in tests
+	now func() time.Time
 
 	multifactors         domain.MultifactorConfigs
 	webauthnConfig       *webauthn_helper.Config
@@ -125,6 +132,12 @@ func StartCommands(
 		defaultAccessTokenLifetime:      defaultAccessTokenLifetime,
 		defaultRefreshTokenLifetime:     defaultRefreshTokenLifetime,
 		defaultRefreshTokenIdleLifetime: defaultRefreshTokenIdleLifetime,
+		webauthnChallengeLifetime:       defaults.Session.WebAuthNChallengeLifetime,
+		maxSessionsPerUser:              defaults.Session.MaxSessionsPerUser,
+		maxSessionPasswordCheckFailures: defaults.Session.MaxPasswordCheckFailures,
+		maxUserPasswordCheckFailures:    defaults.Session.MaxUserPasswordCheckFailures,
+		userPasswordCheckFailuresWindow: defaults.Session.UserPasswordCheckFailuresWindow,
+		now:                             time.Now,
 	}
 
 	instance_repo.RegisterEventMappers(repo.eventstore)
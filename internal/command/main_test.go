@@ -218,6 +218,12 @@ func eventFromEventPusherWithCreationDateNow(event eventstore.Command) *reposito
 	return e
 }
 
+func eventFromEventPusherWithCreationDate(event eventstore.Command, creationDate time.Time) *repository.Event {
+	e := eventFromEventPusher(event)
+	e.CreationDate = creationDate
+	return e
+}
+
 func uniqueConstraintsFromEventConstraint(constraint *eventstore.EventUniqueConstraint) *repository.UniqueConstraint {
 	return &repository.UniqueConstraint{
 		UniqueType:   constraint.UniqueType,
@@ -274,16 +274,17 @@ type OIDCSessionEvents struct {
 }
 
 func (c *OIDCSessionEvents) AddSession(ctx context.Context) {
+	tokenContext := c.sessionWriteModel.TokenContext()
 	c.events = append(c.events, oidcsession.NewAddedEvent(
 		ctx,
 		c.oidcSessionWriteModel.aggregate,
-		c.sessionWriteModel.UserID,
+		tokenContext.UserID,
 		c.sessionWriteModel.AggregateID,
 		c.authRequestWriteModel.ClientID,
 		c.authRequestWriteModel.Audience,
 		c.authRequestWriteModel.Scope,
-		c.sessionWriteModel.AuthMethodTypes(),
-		c.sessionWriteModel.AuthenticationTime(),
+		tokenContext.AuthMethodTypes,
+		tokenContext.AuthTime,
 	))
 }
 
@@ -5,9 +5,13 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
+	"sort"
 	"time"
 
+	"github.com/zitadel/logging"
+
 	"github.com/zitadel/zitadel/internal/api/authz"
+	http_mw "github.com/zitadel/zitadel/internal/api/http/middleware"
 	"github.com/zitadel/zitadel/internal/crypto"
 	"github.com/zitadel/zitadel/internal/domain"
 	caos_errs "github.com/zitadel/zitadel/internal/errors"
@@ -29,12 +33,20 @@ type SessionCommands struct {
 	totpWriteModel     *HumanTOTPWriteModel
 	eventstore         *eventstore.Eventstore
 	eventCommands      []eventstore.Command
+	// failedCheckCommands are the events of failed checks, which are pushed even though the update fails
+	failedCheckCommands []eventstore.Command
 
 	hasher      *crypto.PasswordHasher
 	intentAlg   crypto.EncryptionAlgorithm
 	totpAlg     crypto.EncryptionAlgorithm
 	createToken func(sessionID string) (id string, token string, err error)
 	now         func() time.Time
+
+	maxPasswordCheckFailures int
+	// userPasswordChecks counts the password checks of the user across all sessions (see [Commands.UserPasswordChecks])
+	userPasswordChecks              func(ctx context.Context, userID, resourceOwner string, since time.Time) (*UserPasswordChecks, error)
+	maxUserPasswordCheckFailures    int
+	userPasswordCheckFailuresWindow time.Duration
 }
 
 func (c *Commands) NewSessionCommands(cmds []SessionCommand, session *SessionWriteModel) *SessionCommands {
@@ -46,8 +58,21 @@ func (c *Commands) NewSessionCommands(cmds []SessionCommand, session *SessionWri
 		intentAlg:         c.idpConfigEncryption,
 		totpAlg:           c.multifactors.OTP.CryptoMFA,
 		createToken:       c.sessionTokenCreator,
-		now:               time.Now,
+		now:               c.timeNow,
+
+		maxPasswordCheckFailures:        c.maxSessionPasswordCheckFailures,
+		userPasswordChecks:              c.UserPasswordChecks,
+		maxUserPasswordCheckFailures:    c.maxUserPasswordCheckFailures,
+		userPasswordCheckFailuresWindow: c.userPasswordCheckFailuresWindow,
+	}
+}
+
+// timeNow returns the current time of the clock of the [Commands]
+func (c *Commands) timeNow() time.Time {
+	if c.now != nil {
+		return c.now()
 	}
+	return time.Now()
 }
 
 // CheckUser defines a user check to be executed for a session update
@@ -62,10 +87,25 @@ func CheckUser(id string) SessionCommand {
 
 // CheckPassword defines a password check to be executed for a session update
 func CheckPassword(password string) SessionCommand {
+	return CheckPasswordIdempotent(password, "")
+}
+
+// CheckPasswordIdempotent defines a password check to be executed for a session update.
+// The password is always verified, but a retry with the same idempotencyKey will not record a second check.
+func CheckPasswordIdempotent(password, idempotencyKey string) SessionCommand {
 	return func(ctx context.Context, cmd *SessionCommands) error {
 		if cmd.sessionWriteModel.UserID == "" {
 			return caos_errs.ThrowPreconditionFailed(nil, "COMMAND-Sfw3f", "Errors.User.UserIDMissing")
 		}
+		if err := cmd.sessionWriteModel.CheckAuthMethodAllowed(domain.UserAuthMethodTypePassword); err != nil {
+			return err
+		}
+		if cmd.sessionWriteModel.PasswordLocked(cmd.maxPasswordCheckFailures) {
+			return ErrSessionPasswordLocked
+		}
+		if err := cmd.checkUserPasswordLockout(ctx); err != nil {
+			return err
+		}
 		cmd.passwordWriteModel = NewHumanPasswordWriteModel(cmd.sessionWriteModel.UserID, "")
 		err := cmd.eventstore.FilterToQueryReducer(ctx, cmd.passwordWriteModel)
 		if err != nil {
@@ -83,23 +123,49 @@ func CheckPassword(password string) SessionCommand {
 		spanPasswordComparison.EndWithError(err)
 		if err != nil {
 			//TODO: maybe we want to reset the session in the future https://github.com/zitadel/zitadel/issues/5807
+			cmd.PasswordCheckFailed(ctx, cmd.now())
 			return caos_errs.ThrowInvalidArgument(err, "COMMAND-SAF3g", "Errors.User.Password.Invalid")
 		}
 		if updated != "" {
 			cmd.eventCommands = append(cmd.eventCommands, user.NewHumanPasswordHashUpdatedEvent(ctx, UserAggregateFromWriteModel(&cmd.passwordWriteModel.WriteModel), updated))
 		}
 
-		cmd.PasswordChecked(ctx, cmd.now())
+		if cmd.sessionWriteModel.IsRetriedCheck(domain.UserAuthMethodTypePassword, idempotencyKey, cmd.now()) {
+			return nil
+		}
+		cmd.PasswordChecked(ctx, cmd.now(), idempotencyKey)
 		return nil
 	}
 }
 
+// CheckUserAndPassword defines a combined user and password check (verify credentials) to be executed for a session update.
+// Both checks are recorded together in a single push, so either both or none of them are stored.
+func CheckUserAndPassword(userID, password string) SessionCommand {
+	return func(ctx context.Context, cmd *SessionCommands) error {
+		if err := CheckUser(userID)(ctx, cmd); err != nil {
+			return err
+		}
+		return CheckPassword(password)(ctx, cmd)
+	}
+}
+
+// SetMetadataWithTTL defines the change of the metadata key to be executed for a session update,
+// whose value expires after the ttl (e.g. for temporary tokens).
+func SetMetadataWithTTL(key string, value []byte, ttl time.Duration) SessionCommand {
+	return func(ctx context.Context, cmd *SessionCommands) error {
+		return cmd.SetMetadataWithExpiration(ctx, key, value, cmd.now().Add(ttl))
+	}
+}
+
 // CheckIntent defines a check for a succeeded intent to be executed for a session update
 func CheckIntent(intentID, token string) SessionCommand {
 	return func(ctx context.Context, cmd *SessionCommands) error {
 		if cmd.sessionWriteModel.UserID == "" {
 			return caos_errs.ThrowPreconditionFailed(nil, "COMMAND-Sfw3r", "Errors.User.UserIDMissing")
 		}
+		if err := cmd.sessionWriteModel.CheckAuthMethodAllowed(domain.UserAuthMethodTypeIDP); err != nil {
+			return err
+		}
 		if err := crypto.CheckToken(cmd.intentAlg, token, intentID); err != nil {
 			return err
 		}
@@ -125,7 +191,12 @@ func CheckIntent(intentID, token string) SessionCommand {
 				return caos_errs.ThrowPreconditionFailed(nil, "COMMAND-O8xk3w", "Errors.Intent.OtherUser")
 			}
 		}
-		cmd.IntentChecked(ctx, cmd.now())
+		// the protocol is derived from the type of the identity provider of the intent
+		idpWriteModel := NewIDPTypeWriteModel(cmd.intentWriteModel.IDPID)
+		if err := cmd.eventstore.FilterToQueryReducer(ctx, idpWriteModel); err != nil {
+			return err
+		}
+		cmd.IntentChecked(ctx, cmd.now(), cmd.intentWriteModel.IDPID, idpWriteModel.Type.IntentProtocol())
 		return nil
 	}
 }
@@ -135,6 +206,9 @@ func CheckTOTP(code string) SessionCommand {
 		if cmd.sessionWriteModel.UserID == "" {
 			return caos_errs.ThrowPreconditionFailed(nil, "COMMAND-Neil7", "Errors.User.UserIDMissing")
 		}
+		if err = cmd.sessionWriteModel.CheckAuthMethodAllowed(domain.UserAuthMethodTypeTOTP); err != nil {
+			return err
+		}
 		cmd.totpWriteModel = NewHumanTOTPWriteModel(cmd.sessionWriteModel.UserID, "")
 		err = cmd.eventstore.FilterToQueryReducer(ctx, cmd.totpWriteModel)
 		if err != nil {
@@ -143,11 +217,15 @@ func CheckTOTP(code string) SessionCommand {
 		if cmd.totpWriteModel.State != domain.MFAStateReady {
 			return caos_errs.ThrowPreconditionFailed(nil, "COMMAND-eej1U", "Errors.User.MFA.OTP.NotReady")
 		}
-		err = domain.VerifyTOTP(code, cmd.totpWriteModel.Secret, cmd.totpAlg)
+		step, err := domain.VerifyTOTPStep(code, cmd.totpWriteModel.Secret, cmd.totpAlg, cmd.now())
 		if err != nil {
 			return err
 		}
-		cmd.TOTPChecked(ctx, cmd.now())
+		// a code must not be reused, neither the same nor any code of an earlier time-step
+		if step <= cmd.sessionWriteModel.TOTPLastStep {
+			return caos_errs.ThrowInvalidArgument(nil, "COMMAND-Uu3ie", "Errors.Session.TOTP.CodeReused")
+		}
+		cmd.TOTPChecked(ctx, cmd.now(), cmd.totpWriteModel.DeviceID, step)
 		return nil
 	}
 }
@@ -162,8 +240,14 @@ func (s *SessionCommands) Exec(ctx context.Context) error {
 	return nil
 }
 
-func (s *SessionCommands) Start(ctx context.Context) {
-	s.eventCommands = append(s.eventCommands, session.NewAddedEvent(ctx, s.sessionWriteModel.aggregate))
+// Start creates the session. The user agent (fingerprint), the (auth) request the session is created for
+// and the client of the caller are recorded to be able to trace its origin.
+// If allowedAuthMethods are provided, only these factors can be checked on the session.
+func (s *SessionCommands) Start(ctx context.Context, lifetime time.Duration, createdFromRequestID string, allowedAuthMethods []domain.UserAuthMethodType) {
+	userAgentID, _ := http_mw.UserAgentIDFromCtx(ctx)
+	s.eventCommands = append(s.eventCommands, session.NewAddedEvent(ctx, s.sessionWriteModel.aggregate, lifetime, userAgentID, createdFromRequestID, authz.GetCtxData(ctx).AgentID, allowedAuthMethods))
+	// set the allowed auth methods so the checks of the creation can use them
+	s.sessionWriteModel.AllowedAuthMethods = allowedAuthMethods
 }
 
 func (s *SessionCommands) UserChecked(ctx context.Context, userID string, checkedAt time.Time) error {
@@ -173,23 +257,45 @@ func (s *SessionCommands) UserChecked(ctx context.Context, userID string, checke
 	return nil
 }
 
-func (s *SessionCommands) PasswordChecked(ctx context.Context, checkedAt time.Time) {
-	s.eventCommands = append(s.eventCommands, session.NewPasswordCheckedEvent(ctx, s.sessionWriteModel.aggregate, checkedAt))
+func (s *SessionCommands) PasswordChecked(ctx context.Context, checkedAt time.Time, idempotencyKey string) {
+	s.eventCommands = append(s.eventCommands, session.NewPasswordCheckedEvent(ctx, s.sessionWriteModel.aggregate, checkedAt, idempotencyKey))
 }
 
-func (s *SessionCommands) IntentChecked(ctx context.Context, checkedAt time.Time) {
-	s.eventCommands = append(s.eventCommands, session.NewIntentCheckedEvent(ctx, s.sessionWriteModel.aggregate, checkedAt))
+// checkUserPasswordLockout returns [ErrUserPasswordLocked] if the user of the session reached the maximum of failed password checks
+// across all of its sessions within the window
+func (s *SessionCommands) checkUserPasswordLockout(ctx context.Context) error {
+	if s.maxUserPasswordCheckFailures <= 0 {
+		return nil
+	}
+	// the sessions of all resource owners are counted
+	checks, err := s.userPasswordChecks(ctx, s.sessionWriteModel.UserID, "", s.now().Add(-s.userPasswordCheckFailuresWindow))
+	if err != nil {
+		return err
+	}
+	if checks.Failed >= s.maxUserPasswordCheckFailures {
+		return ErrUserPasswordLocked
+	}
+	return nil
+}
+
+// PasswordCheckFailed records a failed password check, which is pushed even though the session update fails
+func (s *SessionCommands) PasswordCheckFailed(ctx context.Context, checkedAt time.Time) {
+	s.failedCheckCommands = append(s.failedCheckCommands, session.NewPasswordCheckFailedEvent(ctx, s.sessionWriteModel.aggregate, checkedAt))
 }
 
-func (s *SessionCommands) WebAuthNChallenged(ctx context.Context, challenge string, allowedCrentialIDs [][]byte, userVerification domain.UserVerificationRequirement, rpid string) {
-	s.eventCommands = append(s.eventCommands, session.NewWebAuthNChallengedEvent(ctx, s.sessionWriteModel.aggregate, challenge, allowedCrentialIDs, userVerification, rpid))
+func (s *SessionCommands) IntentChecked(ctx context.Context, checkedAt time.Time, idpID string, protocol domain.IDPIntentProtocol) {
+	s.eventCommands = append(s.eventCommands, session.NewIntentCheckedEvent(ctx, s.sessionWriteModel.aggregate, checkedAt, idpID, protocol))
 }
 
-func (s *SessionCommands) WebAuthNChecked(ctx context.Context, checkedAt time.Time, tokenID string, signCount uint32, userVerified bool) {
+func (s *SessionCommands) WebAuthNChallenged(ctx context.Context, challenge string, allowedCrentialIDs [][]byte, allowedTransports map[string][]string, userVerification domain.UserVerificationRequirement, rpid string, expiration time.Time) {
+	s.eventCommands = append(s.eventCommands, session.NewWebAuthNChallengedEvent(ctx, s.sessionWriteModel.aggregate, challenge, allowedCrentialIDs, allowedTransports, userVerification, rpid, expiration))
+}
+
+func (s *SessionCommands) WebAuthNChecked(ctx context.Context, checkedAt time.Time, challenge *WebAuthNChallengeModel, tokenID string, signCount uint32, userVerified, userPresent bool, attestationFormat string, extensionResults map[string]interface{}) {
 	s.eventCommands = append(s.eventCommands,
-		session.NewWebAuthNCheckedEvent(ctx, s.sessionWriteModel.aggregate, checkedAt, userVerified),
+		session.NewWebAuthNCheckedEvent(ctx, s.sessionWriteModel.aggregate, checkedAt, userVerified, userPresent, signCount, attestationFormat, challenge.Challenge, extensionResults),
 	)
-	if s.sessionWriteModel.WebAuthNChallenge.UserVerification == domain.UserVerificationRequirementRequired {
+	if challenge.UserVerification == domain.UserVerificationRequirementRequired {
 		s.eventCommands = append(s.eventCommands,
 			user.NewHumanPasswordlessSignCountChangedEvent(ctx, s.sessionWriteModel.aggregate, tokenID, signCount),
 		)
@@ -200,15 +306,42 @@ func (s *SessionCommands) WebAuthNChecked(ctx context.Context, checkedAt time.Ti
 	}
 }
 
-func (s *SessionCommands) TOTPChecked(ctx context.Context, checkedAt time.Time) {
-	s.eventCommands = append(s.eventCommands, session.NewTOTPCheckedEvent(ctx, s.sessionWriteModel.aggregate, checkedAt))
+func (s *SessionCommands) TOTPChecked(ctx context.Context, checkedAt time.Time, deviceID string, step uint64) {
+	s.eventCommands = append(s.eventCommands, session.NewTOTPCheckedEvent(ctx, s.sessionWriteModel.aggregate, checkedAt, deviceID, step))
+}
+
+// ClientCertChecked adds the authentication by a client certificate (mTLS) with the provided fingerprint.
+// The caller is responsible to verify the certificate (e.g. during the TLS handshake) and that it belongs to the user.
+func (s *SessionCommands) ClientCertChecked(ctx context.Context, checkedAt time.Time, fingerprint string) {
+	s.eventCommands = append(s.eventCommands, session.NewClientCertCheckedEvent(ctx, s.sessionWriteModel.aggregate, checkedAt, fingerprint))
+}
+
+// RecoveryCodeChecked adds the check of a recovery (backup) code.
+// Since recovery codes are single-use, the caller is responsible to verify that the code
+// has not been used before and to consume it.
+func (s *SessionCommands) RecoveryCodeChecked(ctx context.Context, checkedAt time.Time) {
+	s.eventCommands = append(s.eventCommands, session.NewRecoveryCodeCheckedEvent(ctx, s.sessionWriteModel.aggregate, checkedAt))
+}
+
+// OTPSMSChecked adds the check of the OTP sent to the phone number identified by the [HumanPhoneWriteModel.PhoneChangedSequence].
+// Only the sequence and not the phone number itself is stored, so the check can be invalidated if the user changes the phone number.
+func (s *SessionCommands) OTPSMSChecked(ctx context.Context, checkedAt time.Time, phoneChangedSequence uint64) {
+	s.eventCommands = append(s.eventCommands, session.NewOTPSMSCheckedEvent(ctx, s.sessionWriteModel.aggregate, checkedAt, phoneChangedSequence))
+}
+
+func (s *SessionCommands) OTPEmailChecked(ctx context.Context, checkedAt time.Time) {
+	s.eventCommands = append(s.eventCommands, session.NewOTPEmailCheckedEvent(ctx, s.sessionWriteModel.aggregate, checkedAt))
 }
 
 func (s *SessionCommands) SetToken(ctx context.Context, tokenID string) {
 	s.eventCommands = append(s.eventCommands, session.NewTokenSetEvent(ctx, s.sessionWriteModel.aggregate, tokenID))
 }
 
-func (s *SessionCommands) ChangeMetadata(ctx context.Context, metadata map[string][]byte) {
+func (s *SessionCommands) SetIdleTimeout(ctx context.Context, idleTimeout time.Duration) {
+	s.eventCommands = append(s.eventCommands, session.NewLifetimeSetEvent(ctx, s.sessionWriteModel.aggregate, 0, idleTimeout))
+}
+
+func (s *SessionCommands) ChangeMetadata(ctx context.Context, metadata map[string][]byte) error {
 	var changed bool
 	for key, value := range metadata {
 		currentValue, exists := s.sessionWriteModel.Metadata[key]
@@ -216,19 +349,92 @@ func (s *SessionCommands) ChangeMetadata(ctx context.Context, metadata map[strin
 		if len(value) != 0 {
 			// if a value is provided, and it's not equal, change it
 			if !bytes.Equal(currentValue, value) {
-				s.sessionWriteModel.Metadata[key] = value
+				if err := s.sessionWriteModel.SetMetadata(key, value); err != nil {
+					return err
+				}
 				changed = true
 			}
 		} else {
 			// if there's no / an empty value, we only need to remove it on existing entries
 			if exists {
-				delete(s.sessionWriteModel.Metadata, key)
+				s.sessionWriteModel.RemoveMetadata(key)
 				changed = true
 			}
 		}
 	}
 	if changed {
-		s.eventCommands = append(s.eventCommands, session.NewMetadataSetEvent(ctx, s.sessionWriteModel.aggregate, s.sessionWriteModel.Metadata))
+		s.eventCommands = append(s.eventCommands, session.NewMetadataSetEvent(ctx, s.sessionWriteModel.aggregate, s.sessionWriteModel.Metadata, s.sessionWriteModel.MetadataExpirations))
+	}
+	return nil
+}
+
+// SetMetadataWithExpiration sets the value for the key, which will be treated as absent after the expiration.
+// Other keys, which have already expired, are removed.
+func (s *SessionCommands) SetMetadataWithExpiration(ctx context.Context, key string, value []byte, expiration time.Time) error {
+	for expiredKey := range s.sessionWriteModel.MetadataExpirations {
+		if _, ok := s.sessionWriteModel.GetMetadata(expiredKey, s.now()); !ok {
+			s.sessionWriteModel.RemoveMetadata(expiredKey)
+		}
+	}
+	if err := s.sessionWriteModel.SetMetadataWithExpiration(key, value, expiration); err != nil {
+		return err
+	}
+	s.eventCommands = append(s.eventCommands, session.NewMetadataSetEvent(ctx, s.sessionWriteModel.aggregate, s.sessionWriteModel.Metadata, s.sessionWriteModel.MetadataExpirations))
+	return nil
+}
+
+// FactorInvalidated invalidates the check of the factor, e.g. because the user removed the used credential
+func (s *SessionCommands) FactorInvalidated(ctx context.Context, factor domain.UserAuthMethodType) {
+	s.eventCommands = append(s.eventCommands, session.NewFactorInvalidatedEvent(ctx, s.sessionWriteModel.aggregate, factor))
+}
+
+// TrustDevice marks the device of the session as trusted until the expiration (zero for no expiration)
+func (s *SessionCommands) TrustDevice(ctx context.Context, expiration time.Time) {
+	s.eventCommands = append(s.eventCommands, session.NewDeviceTrustedEvent(ctx, s.sessionWriteModel.aggregate, expiration))
+}
+
+// Fork marks the session as child of the parent session and records the user of the parent as delegator.
+// If inheritAuthMethods is set, the auth method types of the parent will be reported by the session as well.
+func (s *SessionCommands) Fork(ctx context.Context, parent *SessionWriteModel, inheritAuthMethods bool) {
+	var inherited []domain.UserAuthMethodType
+	if inheritAuthMethods {
+		inherited = parent.AuthMethodTypes()
+	}
+	s.eventCommands = append(s.eventCommands, session.NewForkedEvent(ctx, s.sessionWriteModel.aggregate, parent.AggregateID, parent.UserID, inherited))
+}
+
+// SetMetadataBulk sets all provided entries in a single event.
+// Entries already set to the same value will be ignored, the budget of the metadata is validated before the event is appended.
+func (s *SessionCommands) SetMetadataBulk(ctx context.Context, metadata map[string][]byte) error {
+	changed := make(map[string][]byte, len(metadata))
+	for key, value := range metadata {
+		if currentValue, exists := s.sessionWriteModel.Metadata[key]; exists && bytes.Equal(currentValue, value) {
+			continue
+		}
+		changed[key] = value
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+	if err := s.sessionWriteModel.SetMetadataBulk(changed); err != nil {
+		return err
+	}
+	s.eventCommands = append(s.eventCommands, session.NewMetadataBulkSetEvent(ctx, s.sessionWriteModel.aggregate, changed))
+	return nil
+}
+
+// RemoveMetadata removes the provided keys from the metadata. Keys not present will be ignored.
+func (s *SessionCommands) RemoveMetadata(ctx context.Context, keys ...string) {
+	removed := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if _, exists := s.sessionWriteModel.Metadata[key]; !exists {
+			continue
+		}
+		s.sessionWriteModel.RemoveMetadata(key)
+		removed = append(removed, key)
+	}
+	if len(removed) > 0 {
+		s.eventCommands = append(s.eventCommands, session.NewMetadataRemovedEvent(ctx, s.sessionWriteModel.aggregate, removed))
 	}
 }
 
@@ -251,6 +457,10 @@ func (s *SessionCommands) commands(ctx context.Context) (string, []eventstore.Co
 	if len(s.eventCommands) == 0 {
 		return "", nil, nil
 	}
+	// no token must be handed out for an expired session
+	if err := s.sessionWriteModel.checkNotExpired(s.now()); err != nil {
+		return "", nil, err
+	}
 
 	tokenID, token, err := s.createToken(s.sessionWriteModel.AggregateID)
 	if err != nil {
@@ -260,7 +470,10 @@ func (s *SessionCommands) commands(ctx context.Context) (string, []eventstore.Co
 	return token, s.eventCommands, nil
 }
 
-func (c *Commands) CreateSession(ctx context.Context, cmds []SessionCommand, metadata map[string][]byte) (set *SessionChanged, err error) {
+// CreateSession creates a new session and executes the provided commands on it.
+// A lifetime greater than zero will limit the session to that duration (from its creation on).
+// If allowedAuthMethods are provided, only these factors can be checked on the session (e.g. for kiosks).
+func (c *Commands) CreateSession(ctx context.Context, cmds []SessionCommand, metadata map[string][]byte, lifetime time.Duration, allowedAuthMethods []domain.UserAuthMethodType) (set *SessionChanged, err error) {
 	sessionID, err := c.idGenerator.Next()
 	if err != nil {
 		return nil, err
@@ -271,13 +484,12 @@ func (c *Commands) CreateSession(ctx context.Context, cmds []SessionCommand, met
 		return nil, err
 	}
 	cmd := c.NewSessionCommands(cmds, sessionWriteModel)
-	cmd.Start(ctx)
+	cmd.Start(ctx, lifetime, "", allowedAuthMethods)
 	return c.updateSession(ctx, cmd, metadata)
 }
 
 func (c *Commands) UpdateSession(ctx context.Context, sessionID, sessionToken string, cmds []SessionCommand, metadata map[string][]byte) (set *SessionChanged, err error) {
-	sessionWriteModel := NewSessionWriteModel(sessionID, authz.GetCtxData(ctx).OrgID)
-	err = c.eventstore.FilterToQueryReducer(ctx, sessionWriteModel)
+	sessionWriteModel, err := c.sessionWriteModelFromSnapshot(ctx, sessionID, authz.GetCtxData(ctx).OrgID)
 	if err != nil {
 		return nil, err
 	}
@@ -288,17 +500,233 @@ func (c *Commands) UpdateSession(ctx context.Context, sessionID, sessionToken st
 	return c.updateSession(ctx, cmd, metadata)
 }
 
+// RotateSessionToken replaces the token of the session by a new one.
+// The previous token will be recorded as revoked and can no longer be used.
+func (c *Commands) RotateSessionToken(ctx context.Context, sessionID, sessionToken string) (set *SessionChanged, err error) {
+	sessionWriteModel, err := c.sessionWriteModelFromSnapshot(ctx, sessionID, authz.GetCtxData(ctx).OrgID)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.sessionPermission(ctx, sessionWriteModel, sessionToken, domain.PermissionSessionWrite); err != nil {
+		return nil, err
+	}
+	if sessionWriteModel.State == domain.SessionStateTerminated {
+		return nil, caos_errs.ThrowPreconditionFailed(nil, "COMMAND-Aiph3", "Errors.Session.Terminated")
+	}
+	if sessionWriteModel.State == domain.SessionStateLocked {
+		return nil, ErrSessionLocked
+	}
+	if err = sessionWriteModel.checkNotExpired(c.timeNow()); err != nil {
+		return nil, err
+	}
+	tokenID, token, err := c.sessionTokenCreator(sessionWriteModel.AggregateID)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.pushAppendAndReduce(ctx, sessionWriteModel, session.NewTokenSetEvent(ctx, &session.NewAggregate(sessionWriteModel.AggregateID, sessionWriteModel.ResourceOwner).Aggregate, tokenID)); err != nil {
+		return nil, err
+	}
+	changed := sessionWriteModelToSessionChanged(sessionWriteModel)
+	changed.NewToken = token
+	return changed, nil
+}
+
+// RevokeSessionToken revokes the current token of the session without terminating it.
+// The session stays active, so a new token can be issued (e.g. by [Commands.RotateSessionToken]) without any further authentication.
+func (c *Commands) RevokeSessionToken(ctx context.Context, sessionID string) (*domain.ObjectDetails, error) {
+	sessionWriteModel, err := c.sessionWriteModelFromSnapshot(ctx, sessionID, "")
+	if err != nil {
+		return nil, err
+	}
+	if err := c.sessionPermission(ctx, sessionWriteModel, "", domain.PermissionSessionWrite); err != nil {
+		return nil, err
+	}
+	if err = sessionWriteModel.CheckActive(c.timeNow()); err != nil {
+		return nil, err
+	}
+	if sessionWriteModel.TokenID == "" {
+		return writeModelToObjectDetails(&sessionWriteModel.WriteModel), nil
+	}
+	if err = c.pushAppendAndReduce(ctx, sessionWriteModel, session.NewTokenSetEvent(ctx, &session.NewAggregate(sessionWriteModel.AggregateID, sessionWriteModel.ResourceOwner).Aggregate, "")); err != nil {
+		return nil, err
+	}
+	return writeModelToObjectDetails(&sessionWriteModel.WriteModel), nil
+}
+
+// LockSession temporarily suspends the session (e.g. because of suspicious activity) without terminating it.
+// While locked, the session can neither be checked nor can a token be set, until it's unlocked by [Commands.UnlockSession].
+func (c *Commands) LockSession(ctx context.Context, sessionID string) (*domain.ObjectDetails, error) {
+	sessionWriteModel, err := c.sessionWriteModelFromSnapshot(ctx, sessionID, "")
+	if err != nil {
+		return nil, err
+	}
+	if err := c.sessionPermission(ctx, sessionWriteModel, "", domain.PermissionSessionWrite); err != nil {
+		return nil, err
+	}
+	if sessionWriteModel.State == domain.SessionStateLocked {
+		return writeModelToObjectDetails(&sessionWriteModel.WriteModel), nil
+	}
+	if err = sessionWriteModel.CheckActive(c.timeNow()); err != nil {
+		return nil, err
+	}
+	if err = c.pushAppendAndReduce(ctx, sessionWriteModel, session.NewLockedEvent(ctx, &session.NewAggregate(sessionWriteModel.AggregateID, sessionWriteModel.ResourceOwner).Aggregate)); err != nil {
+		return nil, err
+	}
+	return writeModelToObjectDetails(&sessionWriteModel.WriteModel), nil
+}
+
+// UnlockSession resumes a session locked by [Commands.LockSession].
+// Sessions, which are not locked, are left unchanged.
+func (c *Commands) UnlockSession(ctx context.Context, sessionID string) (*domain.ObjectDetails, error) {
+	sessionWriteModel, err := c.sessionWriteModelFromSnapshot(ctx, sessionID, "")
+	if err != nil {
+		return nil, err
+	}
+	if err := c.sessionPermission(ctx, sessionWriteModel, "", domain.PermissionSessionWrite); err != nil {
+		return nil, err
+	}
+	switch sessionWriteModel.State {
+	case domain.SessionStateLocked:
+	case domain.SessionStateActive, domain.SessionStatePending:
+		return writeModelToObjectDetails(&sessionWriteModel.WriteModel), nil
+	case domain.SessionStateTerminated:
+		return nil, ErrSessionTerminated
+	default:
+		return nil, ErrSessionNotExisting
+	}
+	if err = c.pushAppendAndReduce(ctx, sessionWriteModel, session.NewUnlockedEvent(ctx, &session.NewAggregate(sessionWriteModel.AggregateID, sessionWriteModel.ResourceOwner).Aggregate)); err != nil {
+		return nil, err
+	}
+	return writeModelToObjectDetails(&sessionWriteModel.WriteModel), nil
+}
+
+// RecordSessionActivity verifies the provided sessionToken and records its use on the session,
+// which moves the idle expiration without any (re-)authentication.
+// Validations of the session token by the commands (e.g. [Commands.LinkSessionToAuthRequest]) record it on their own,
+// see [Commands.sessionTokenUsed].
+func (c *Commands) RecordSessionActivity(ctx context.Context, sessionID, sessionToken string) (*domain.ObjectDetails, error) {
+	if sessionToken == "" {
+		return nil, caos_errs.ThrowInvalidArgument(nil, "COMMAND-Ahx4o", "Errors.Session.Token.Invalid")
+	}
+	sessionWriteModel, err := c.sessionWriteModelFromSnapshot(ctx, sessionID, "")
+	if err != nil {
+		return nil, err
+	}
+	if err = c.sessionPermission(ctx, sessionWriteModel, sessionToken, ""); err != nil {
+		return nil, err
+	}
+	if err = sessionWriteModel.CheckActive(c.timeNow()); err != nil {
+		return nil, err
+	}
+	if err = c.pushAppendAndReduce(ctx, sessionWriteModel, session.NewActivityEvent(ctx, &session.NewAggregate(sessionWriteModel.AggregateID, sessionWriteModel.ResourceOwner).Aggregate)); err != nil {
+		return nil, err
+	}
+	return writeModelToObjectDetails(&sessionWriteModel.WriteModel), nil
+}
+
+// sessionTokenUsed records the use of a validated session token on the session,
+// so that a session with an idle timeout does not expire while it's in use.
+// Sessions without an idle timeout are left untouched.
+func (c *Commands) sessionTokenUsed(ctx context.Context, sessionWriteModel *SessionWriteModel) error {
+	if sessionWriteModel.IdleTimeout <= 0 {
+		return nil
+	}
+	if err := sessionWriteModel.CheckActive(c.timeNow()); err != nil {
+		return err
+	}
+	return c.pushAppendAndReduce(ctx, sessionWriteModel, session.NewActivityEvent(ctx, &session.NewAggregate(sessionWriteModel.AggregateID, sessionWriteModel.ResourceOwner).Aggregate))
+}
+
+// RefreshWebAuthNChallenge replaces the current WebAuthN challenge of the session by the provided challenge, e.g. after it expired.
+// The user verification and relying party of the current challenge are kept,
+// as well as its allowed credentials, unless allowedCredentialIDs are provided.
+func (c *Commands) RefreshWebAuthNChallenge(ctx context.Context, sessionID, challenge string, allowedCredentialIDs [][]byte) (*domain.ObjectDetails, error) {
+	if challenge == "" {
+		return nil, caos_errs.ThrowInvalidArgument(nil, "COMMAND-Eew2u", "Errors.Session.WebAuthN.NoChallenge")
+	}
+	sessionWriteModel, err := c.sessionWriteModelFromSnapshot(ctx, sessionID, "")
+	if err != nil {
+		return nil, err
+	}
+	if err := c.sessionPermission(ctx, sessionWriteModel, "", domain.PermissionSessionWrite); err != nil {
+		return nil, err
+	}
+	now := c.timeNow()
+	if err = sessionWriteModel.CheckActive(now); err != nil {
+		return nil, err
+	}
+	refreshed, err := webAuthNChallengeRefreshed(ctx, sessionWriteModel, challenge, allowedCredentialIDs, now.Add(c.webAuthNChallengeLifetime()))
+	if err != nil {
+		return nil, err
+	}
+	if err = c.pushAppendAndReduce(ctx, sessionWriteModel, refreshed); err != nil {
+		return nil, err
+	}
+	return writeModelToObjectDetails(&sessionWriteModel.WriteModel), nil
+}
+
+// webAuthNChallengeRefreshed creates the event replacing the current WebAuthN challenge of the session,
+// see [Commands.RefreshWebAuthNChallenge].
+func webAuthNChallengeRefreshed(ctx context.Context, wm *SessionWriteModel, challenge string, allowedCredentialIDs [][]byte, expiration time.Time) (*session.WebAuthNChallengedEvent, error) {
+	current, ok := wm.ActiveWebAuthNChallenge()
+	if !ok {
+		return nil, caos_errs.ThrowPreconditionFailed(nil, "COMMAND-ooP6g", "Errors.Session.WebAuthN.NoChallenge")
+	}
+	var allowedTransports map[string][]string
+	if len(allowedCredentialIDs) == 0 {
+		allowedCredentialIDs = current.AllowedCredentialIDs
+		allowedTransports = current.AllowedTransports
+	}
+	return session.NewWebAuthNChallengeRefreshedEvent(ctx, &session.NewAggregate(wm.AggregateID, wm.ResourceOwner).Aggregate,
+		current.Challenge, challenge, allowedCredentialIDs, allowedTransports, current.UserVerification, current.RPID, expiration), nil
+}
+
+// SetSessionLifetime moves the expiration of the session to now plus the provided lifetime.
+// A terminated session cannot be extended.
+func (c *Commands) SetSessionLifetime(ctx context.Context, sessionID string, lifetime time.Duration) (*domain.ObjectDetails, error) {
+	if lifetime <= 0 {
+		return nil, caos_errs.ThrowInvalidArgument(nil, "COMMAND-ieJ1o", "Errors.Session.LifetimeInvalid")
+	}
+	sessionWriteModel, err := c.sessionWriteModelFromSnapshot(ctx, sessionID, "")
+	if err != nil {
+		return nil, err
+	}
+	if err := c.sessionPermission(ctx, sessionWriteModel, "", domain.PermissionSessionWrite); err != nil {
+		return nil, err
+	}
+	switch sessionWriteModel.State {
+	case domain.SessionStateActive, domain.SessionStatePending:
+	case domain.SessionStateTerminated:
+		return nil, caos_errs.ThrowPreconditionFailed(nil, "COMMAND-Uo8ee", "Errors.Session.Terminated")
+	case domain.SessionStateLocked:
+		return nil, ErrSessionLocked
+	default:
+		return nil, caos_errs.ThrowNotFound(nil, "COMMAND-aeL0a", "Errors.Session.NotExisting")
+	}
+	lifetimeSet := session.NewLifetimeSetEvent(ctx, &session.NewAggregate(sessionWriteModel.AggregateID, sessionWriteModel.ResourceOwner).Aggregate, lifetime, sessionWriteModel.IdleTimeout)
+	if err = c.pushAppendAndReduce(ctx, sessionWriteModel, lifetimeSet); err != nil {
+		return nil, err
+	}
+	return writeModelToObjectDetails(&sessionWriteModel.WriteModel), nil
+}
+
+// TerminateSession terminates the session.
+// If the sessionToken is provided, it's considered a logout, otherwise a revocation based on the caller's permission.
 func (c *Commands) TerminateSession(ctx context.Context, sessionID string, sessionToken string) (*domain.ObjectDetails, error) {
-	return c.terminateSession(ctx, sessionID, sessionToken, true)
+	reason := domain.SessionTerminationTypeLogout
+	if sessionToken == "" {
+		reason = domain.SessionTerminationTypeRevoked
+	}
+	return c.terminateSession(ctx, sessionID, sessionToken, true, reason)
 }
 
 func (c *Commands) TerminateSessionWithoutTokenCheck(ctx context.Context, sessionID string) (*domain.ObjectDetails, error) {
-	return c.terminateSession(ctx, sessionID, "", false)
+	return c.terminateSession(ctx, sessionID, "", false, domain.SessionTerminationTypeLogout)
 }
 
-func (c *Commands) terminateSession(ctx context.Context, sessionID, sessionToken string, mustCheckToken bool) (*domain.ObjectDetails, error) {
-	sessionWriteModel := NewSessionWriteModel(sessionID, "")
-	if err := c.eventstore.FilterToQueryReducer(ctx, sessionWriteModel); err != nil {
+func (c *Commands) terminateSession(ctx context.Context, sessionID, sessionToken string, mustCheckToken bool, reason domain.SessionTerminationType) (*domain.ObjectDetails, error) {
+	sessionWriteModel, err := c.sessionWriteModelFromSnapshot(ctx, sessionID, "")
+	if err != nil {
 		return nil, err
 	}
 	if mustCheckToken {
@@ -306,10 +734,10 @@ func (c *Commands) terminateSession(ctx context.Context, sessionID, sessionToken
 			return nil, err
 		}
 	}
-	if sessionWriteModel.State != domain.SessionStateActive {
+	if !sessionWriteModel.State.IsOpen() {
 		return writeModelToObjectDetails(&sessionWriteModel.WriteModel), nil
 	}
-	terminate := session.NewTerminateEvent(ctx, &session.NewAggregate(sessionWriteModel.AggregateID, sessionWriteModel.ResourceOwner).Aggregate)
+	terminate := session.NewTerminateEvent(ctx, &session.NewAggregate(sessionWriteModel.AggregateID, sessionWriteModel.ResourceOwner).Aggregate, reason)
 	pushedEvents, err := c.eventstore.Push(ctx, terminate)
 	if err != nil {
 		return nil, err
@@ -321,16 +749,426 @@ func (c *Commands) terminateSession(ctx context.Context, sessionID, sessionToken
 	return writeModelToObjectDetails(&sessionWriteModel.WriteModel), nil
 }
 
+// TerminateSessions terminates all provided sessions, for which the caller is granted the necessary permission.
+// The sessions are loaded with a single query and all terminations are pushed at once.
+func (c *Commands) TerminateSessions(ctx context.Context, sessionIDs []string) (*domain.ObjectDetails, error) {
+	sessionWriteModels, err := c.sessionWriteModels(ctx, sessionIDs)
+	if err != nil {
+		return nil, err
+	}
+	cmds := make([]eventstore.Command, 0, len(sessionWriteModels))
+	for _, sessionWriteModel := range sessionWriteModels {
+		if !sessionWriteModel.State.IsOpen() {
+			continue
+		}
+		if err := c.sessionPermission(ctx, sessionWriteModel, "", domain.PermissionSessionDelete); err != nil {
+			return nil, err
+		}
+		cmds = append(cmds, session.NewTerminateEvent(ctx, &session.NewAggregate(sessionWriteModel.AggregateID, sessionWriteModel.ResourceOwner).Aggregate, domain.SessionTerminationTypeRevoked))
+	}
+	if len(cmds) == 0 {
+		return &domain.ObjectDetails{}, nil
+	}
+	pushedEvents, err := c.eventstore.Push(ctx, cmds...)
+	if err != nil {
+		return nil, err
+	}
+	return pushedEventsToObjectDetails(pushedEvents), nil
+}
+
+// TerminateSessionsOfRemovedUser terminates all active sessions of the provided (removed) user,
+// so they do not linger until they expire.
+func (c *Commands) TerminateSessionsOfRemovedUser(ctx context.Context, userID string) (*domain.ObjectDetails, error) {
+	sessionIDs, err := c.sessionIDsOfUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	sessionWriteModels, err := c.sessionWriteModels(ctx, sessionIDs)
+	if err != nil {
+		return nil, err
+	}
+	cmds := make([]eventstore.Command, 0, len(sessionWriteModels))
+	for _, sessionWriteModel := range sessionWriteModels {
+		// the user of a session can only be checked once, but make sure to terminate only the ones of the user
+		if !sessionWriteModel.State.IsOpen() || sessionWriteModel.UserID != userID {
+			continue
+		}
+		cmds = append(cmds, session.NewTerminateEvent(ctx, &session.NewAggregate(sessionWriteModel.AggregateID, sessionWriteModel.ResourceOwner).Aggregate, domain.SessionTerminationTypeUserRemoved))
+	}
+	if len(cmds) == 0 {
+		return &domain.ObjectDetails{}, nil
+	}
+	pushedEvents, err := c.eventstore.Push(ctx, cmds...)
+	if err != nil {
+		return nil, err
+	}
+	return pushedEventsToObjectDetails(pushedEvents), nil
+}
+
+// InvalidateSessionPasswordChecks notifies all active sessions of the provided user about the change of the password,
+// so the password checks made before the change are no longer valid.
+func (c *Commands) InvalidateSessionPasswordChecks(ctx context.Context, userID string, changedAt time.Time) (*domain.ObjectDetails, error) {
+	sessionIDs, err := c.sessionIDsOfUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	sessionWriteModels, err := c.sessionWriteModels(ctx, sessionIDs)
+	if err != nil {
+		return nil, err
+	}
+	cmds := make([]eventstore.Command, 0, len(sessionWriteModels))
+	for _, sessionWriteModel := range sessionWriteModels {
+		if !sessionWriteModel.State.IsOpen() ||
+			sessionWriteModel.UserID != userID ||
+			sessionWriteModel.PasswordCheckedAt.IsZero() ||
+			sessionWriteModel.PasswordCheckedAt.After(changedAt) {
+			continue
+		}
+		cmds = append(cmds, session.NewPasswordChangedEvent(ctx, &session.NewAggregate(sessionWriteModel.AggregateID, sessionWriteModel.ResourceOwner).Aggregate, changedAt))
+	}
+	if len(cmds) == 0 {
+		return &domain.ObjectDetails{}, nil
+	}
+	pushedEvents, err := c.eventstore.Push(ctx, cmds...)
+	if err != nil {
+		return nil, err
+	}
+	return pushedEventsToObjectDetails(pushedEvents), nil
+}
+
+// TerminateUserSessionsExcept terminates all active sessions of the provided user, except the one to keep (e.g. the current one),
+// for which the caller is granted the necessary permission.
+func (c *Commands) TerminateUserSessionsExcept(ctx context.Context, userID, keepSessionID string) (*domain.ObjectDetails, error) {
+	sessionIDs, err := c.sessionIDsOfUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	sessionWriteModels, err := c.sessionWriteModels(ctx, sessionIDs)
+	if err != nil {
+		return nil, err
+	}
+	cmds := make([]eventstore.Command, 0, len(sessionWriteModels))
+	for _, sessionWriteModel := range sessionWriteModels {
+		if sessionWriteModel.AggregateID == keepSessionID ||
+			!sessionWriteModel.State.IsOpen() ||
+			sessionWriteModel.UserID != userID {
+			continue
+		}
+		if err := c.sessionPermission(ctx, sessionWriteModel, "", domain.PermissionSessionDelete); err != nil {
+			return nil, err
+		}
+		cmds = append(cmds, session.NewTerminateEvent(ctx, &session.NewAggregate(sessionWriteModel.AggregateID, sessionWriteModel.ResourceOwner).Aggregate, domain.SessionTerminationTypeLogout))
+	}
+	if len(cmds) == 0 {
+		return &domain.ObjectDetails{}, nil
+	}
+	pushedEvents, err := c.eventstore.Push(ctx, cmds...)
+	if err != nil {
+		return nil, err
+	}
+	return pushedEventsToObjectDetails(pushedEvents), nil
+}
+
+// EnforceMaxSessionsPerUser terminates the oldest active sessions of the user exceeding the configured maximum
+// of concurrent sessions. The sessions are ordered by their authentication time
+// (or the time the user was checked, if no factor was checked yet). The session to keep (e.g. the new one) is never terminated.
+func (c *Commands) EnforceMaxSessionsPerUser(ctx context.Context, userID, keepSessionID string) (*domain.ObjectDetails, error) {
+	if c.maxSessionsPerUser <= 0 {
+		return &domain.ObjectDetails{}, nil
+	}
+	sessionIDs, err := c.sessionIDsOfUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	sessionWriteModels, err := c.sessionWriteModels(ctx, sessionIDs)
+	if err != nil {
+		return nil, err
+	}
+	now := c.timeNow()
+	active := make([]*SessionWriteModel, 0, len(sessionWriteModels))
+	for _, sessionWriteModel := range sessionWriteModels {
+		if sessionWriteModel.UserID != userID || sessionWriteModel.CheckActive(now) != nil {
+			continue
+		}
+		active = append(active, sessionWriteModel)
+	}
+	exceeding := len(active) - c.maxSessionsPerUser
+	if exceeding <= 0 {
+		return &domain.ObjectDetails{}, nil
+	}
+	sort.SliceStable(active, func(i, j int) bool {
+		return sessionAge(active[i]).Before(sessionAge(active[j]))
+	})
+	cmds := make([]eventstore.Command, 0, exceeding)
+	for _, sessionWriteModel := range active {
+		if len(cmds) == exceeding {
+			break
+		}
+		if sessionWriteModel.AggregateID == keepSessionID {
+			continue
+		}
+		cmds = append(cmds, session.NewTerminateEvent(ctx, &session.NewAggregate(sessionWriteModel.AggregateID, sessionWriteModel.ResourceOwner).Aggregate, domain.SessionTerminationTypeLimitExceeded))
+	}
+	pushedEvents, err := c.eventstore.Push(ctx, cmds...)
+	if err != nil {
+		return nil, err
+	}
+	return pushedEventsToObjectDetails(pushedEvents), nil
+}
+
+// sessionAge returns the time used to order the sessions by their age in [Commands.EnforceMaxSessionsPerUser]
+func sessionAge(wm *SessionWriteModel) time.Time {
+	if authTime := wm.AuthenticationTime(); !authTime.IsZero() {
+		return authTime
+	}
+	return wm.UserCheckedAt
+}
+
+// SessionMetadataMergeConflict defines which value is kept by [Commands.MergeSession],
+// if the metadata key is set on both sessions
+type SessionMetadataMergeConflict int
+
+const (
+	// SessionMetadataMergePreferTarget keeps the value of the target session
+	SessionMetadataMergePreferTarget SessionMetadataMergeConflict = iota
+	// SessionMetadataMergePreferSource overwrites the value of the target session by the one of the source
+	SessionMetadataMergePreferSource
+)
+
+// MergeSession copies the metadata of the (anonymous) source session into the target session and terminates the source,
+// e.g. to keep the metadata (cart, preferences) collected before the login.
+// If the source session already has a user, it must be the same as the one of the target session.
+func (c *Commands) MergeSession(ctx context.Context, targetID, sourceID string, conflict SessionMetadataMergeConflict) (*domain.ObjectDetails, error) {
+	if targetID == sourceID {
+		return nil, caos_errs.ThrowInvalidArgument(nil, "COMMAND-ohT4e", "Errors.Session.Merge.SameSession")
+	}
+	sessionWriteModels, err := c.sessionWriteModels(ctx, []string{targetID, sourceID})
+	if err != nil {
+		return nil, err
+	}
+	target, source := sessionWriteModels[0], sessionWriteModels[1]
+	now := c.timeNow()
+	for _, sessionWriteModel := range sessionWriteModels {
+		if err = sessionWriteModel.CheckActive(now); err != nil {
+			return nil, err
+		}
+		if err = c.sessionPermission(ctx, sessionWriteModel, "", domain.PermissionSessionWrite); err != nil {
+			return nil, err
+		}
+	}
+	if source.UserID != "" && source.UserID != target.UserID {
+		return nil, caos_errs.ThrowPreconditionFailed(nil, "COMMAND-Aen0u", "Errors.Session.Merge.OtherUser")
+	}
+	merged := make(map[string][]byte, len(source.Metadata))
+	for key, value := range source.Metadata {
+		current, exists := target.Metadata[key]
+		if exists && (conflict == SessionMetadataMergePreferTarget || bytes.Equal(current, value)) {
+			continue
+		}
+		merged[key] = value
+	}
+	cmds := make([]eventstore.Command, 0, 2)
+	if len(merged) > 0 {
+		// validate the limits without changing the target before the push
+		if err = target.Clone().SetMetadataBulk(merged); err != nil {
+			return nil, err
+		}
+		cmds = append(cmds, session.NewMetadataBulkSetEvent(ctx, &session.NewAggregate(target.AggregateID, target.ResourceOwner).Aggregate, merged))
+	}
+	cmds = append(cmds, session.NewTerminateEvent(ctx, &session.NewAggregate(source.AggregateID, source.ResourceOwner).Aggregate, domain.SessionTerminationTypeMerged))
+	pushedEvents, err := c.eventstore.Push(ctx, cmds...)
+	if err != nil {
+		return nil, err
+	}
+	if len(merged) > 0 {
+		if err = AppendAndReduce(target, pushedEvents[0]); err != nil {
+			return nil, err
+		}
+	}
+	return writeModelToObjectDetails(&target.WriteModel), nil
+}
+
+// UserPasswordChecks contains the amount of password checks of a user across all of its sessions
+type UserPasswordChecks struct {
+	Succeeded int
+	Failed    int
+}
+
+// UserPasswordChecks counts the succeeded and failed password checks of the user across all of its sessions
+// (of the provided resource owner, if set) since the provided time, so a lockout can be enforced independent of the session.
+func (c *Commands) UserPasswordChecks(ctx context.Context, userID, resourceOwner string, since time.Time) (*UserPasswordChecks, error) {
+	userChecks, err := c.eventstore.Filter(ctx, eventstore.NewSearchQueryBuilder(eventstore.ColumnsEvent).
+		ResourceOwner(resourceOwner).
+		AddQuery().
+		AggregateTypes(session.AggregateType).
+		EventTypes(session.UserCheckedType).
+		EventData(map[string]interface{}{"userID": userID}).
+		Builder(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	checks := new(UserPasswordChecks)
+	if len(userChecks) == 0 {
+		return checks, nil
+	}
+	sessionIDs := make([]string, 0, len(userChecks))
+	for _, event := range userChecks {
+		sessionIDs = append(sessionIDs, event.Aggregate().ID)
+	}
+	events, err := c.eventstore.Filter(ctx, eventstore.NewSearchQueryBuilder(eventstore.ColumnsEvent).
+		ResourceOwner(resourceOwner).
+		AddQuery().
+		AggregateTypes(session.AggregateType).
+		AggregateIDs(sessionIDs...).
+		EventTypes(session.PasswordCheckedType, session.PasswordCheckFailedType).
+		CreationDateAfter(since).
+		Builder(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	for _, event := range events {
+		switch event.Type() {
+		case session.PasswordCheckedType:
+			checks.Succeeded++
+		case session.PasswordCheckFailedType:
+			checks.Failed++
+		}
+	}
+	return checks, nil
+}
+
+// sessionIDsOfUser returns the ids of all sessions, where the provided user was checked
+func (c *Commands) sessionIDsOfUser(ctx context.Context, userID string) ([]string, error) {
+	events, err := c.eventstore.Filter(ctx, eventstore.NewSearchQueryBuilder(eventstore.ColumnsEvent).
+		AddQuery().
+		AggregateTypes(session.AggregateType).
+		EventTypes(session.UserCheckedType).
+		EventData(map[string]interface{}{"userID": userID}).
+		Builder(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	sessionIDs := make([]string, 0, len(events))
+	for _, event := range events {
+		sessionIDs = append(sessionIDs, event.Aggregate().ID)
+	}
+	return sessionIDs, nil
+}
+
+// SessionIDsWithMetadata returns the ids of all sessions (of the resource owner, if provided),
+// on which the metadata key was set to the provided value.
+// Since the metadata might have been changed or removed afterwards, the ids are only candidates,
+// whose sessions need to be reduced (e.g. using [Commands.sessionWriteModels]) to get their current metadata.
+func (c *Commands) SessionIDsWithMetadata(ctx context.Context, resourceOwner, key string, value []byte) ([]string, error) {
+	events, err := c.eventstore.Filter(ctx, eventstore.NewSearchQueryBuilder(eventstore.ColumnsEvent).
+		ResourceOwner(resourceOwner).
+		AddQuery().
+		AggregateTypes(session.AggregateType).
+		EventTypes(session.MetadataSetType, session.MetadataBulkSetType).
+		Builder(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	sessionIDs := make([]string, 0)
+	found := make(map[string]bool)
+	for _, event := range events {
+		var metadata map[string][]byte
+		switch e := event.(type) {
+		case *session.MetadataSetEvent:
+			metadata = e.Metadata
+		case *session.MetadataBulkSetEvent:
+			metadata = e.Metadata
+		}
+		sessionID := event.Aggregate().ID
+		if current, ok := metadata[key]; !ok || !bytes.Equal(current, value) || found[sessionID] {
+			continue
+		}
+		found[sessionID] = true
+		sessionIDs = append(sessionIDs, sessionID)
+	}
+	return sessionIDs, nil
+}
+
+// sessionWriteModels queries the events of all provided sessions at once
+// and reduces them into a [SessionWriteModel] per session (in the order of the provided ids).
+func (c *Commands) sessionWriteModels(ctx context.Context, sessionIDs []string) ([]*SessionWriteModel, error) {
+	writeModels := make([]*SessionWriteModel, 0, len(sessionIDs))
+	writeModelsByID := make(map[string]*SessionWriteModel, len(sessionIDs))
+	for _, sessionID := range sessionIDs {
+		if _, ok := writeModelsByID[sessionID]; ok {
+			continue
+		}
+		writeModel := NewSessionWriteModel(sessionID, "")
+		writeModels = append(writeModels, writeModel)
+		writeModelsByID[sessionID] = writeModel
+	}
+	if len(writeModels) == 0 {
+		return writeModels, nil
+	}
+	events, err := c.eventstore.Filter(ctx, NewSessionWriteModel("", "").QueryMany(sessionIDs...))
+	if err != nil {
+		return nil, err
+	}
+	for _, event := range events {
+		if writeModel, ok := writeModelsByID[event.Aggregate().ID]; ok {
+			writeModel.AppendEvents(event)
+		}
+	}
+	// the reduction is in memory, only the single query above involves the database
+	for _, writeModel := range writeModels {
+		if err := writeModel.Reduce(); err != nil {
+			return nil, err
+		}
+	}
+	return writeModels, nil
+}
+
+// sessionSnapshotInterval is the amount of events after which a new [session.SnapshotEvent] will be created
+const sessionSnapshotInterval = 100
+
+// sessionWriteModelFromSnapshot loads the session from its latest [session.SnapshotEvent] (if any)
+// and only reduces the events created after it.
+func (c *Commands) sessionWriteModelFromSnapshot(ctx context.Context, sessionID, resourceOwner string) (*SessionWriteModel, error) {
+	sessionWriteModel := NewSessionWriteModel(sessionID, resourceOwner)
+	snapshots, err := c.eventstore.Filter(ctx, sessionWriteModel.snapshotQuery())
+	if err != nil {
+		return nil, err
+	}
+	if len(snapshots) > 0 {
+		if err = AppendAndReduce(sessionWriteModel, snapshots[0]); err != nil {
+			return nil, err
+		}
+	}
+	if err = c.eventstore.FilterToQueryReducer(ctx, sessionWriteModel); err != nil {
+		return nil, err
+	}
+	return sessionWriteModel, nil
+}
+
 // updateSession execute the [SessionCommands] where new events will be created and as well as for metadata (changes)
 func (c *Commands) updateSession(ctx context.Context, checks *SessionCommands, metadata map[string][]byte) (set *SessionChanged, err error) {
 	if checks.sessionWriteModel.State == domain.SessionStateTerminated {
 		return nil, caos_errs.ThrowPreconditionFailed(nil, "COMAND-SAjeh", "Errors.Session.Terminated")
 	}
+	if checks.sessionWriteModel.State == domain.SessionStateLocked {
+		return nil, ErrSessionLocked
+	}
+	userBefore := checks.sessionWriteModel.UserID
 	if err := checks.Exec(ctx); err != nil {
-		// TODO: how to handle failed checks (e.g. pw wrong) https://github.com/zitadel/zitadel/issues/5807
+		// only the failed checks (e.g. pw wrong) are recorded, so they count towards the lockout of the session
+		if len(checks.failedCheckCommands) > 0 {
+			_, pushErr := c.eventstore.Push(ctx, checks.failedCheckCommands...)
+			logging.WithFields("sessionID", checks.sessionWriteModel.AggregateID).OnError(pushErr).Error("could not record failed session checks")
+		}
+		return nil, err
+	}
+	if err := checks.ChangeMetadata(ctx, metadata); err != nil {
 		return nil, err
 	}
-	checks.ChangeMetadata(ctx, metadata)
 	sessionToken, cmds, err := checks.commands(ctx)
 	if err != nil {
 		return nil, err
@@ -338,6 +1176,11 @@ func (c *Commands) updateSession(ctx context.Context, checks *SessionCommands, m
 	if len(cmds) == 0 {
 		return sessionWriteModelToSessionChanged(checks.sessionWriteModel), nil
 	}
+	if checks.sessionWriteModel.eventsSinceSnapshot >= sessionSnapshotInterval {
+		// the snapshot contains the state before the new events and is therefore pushed first
+		snapshot := session.NewSnapshotEvent(ctx, checks.sessionWriteModel.aggregate, checks.sessionWriteModel.snapshotState())
+		cmds = append([]eventstore.Command{snapshot}, cmds...)
+	}
 	pushedEvents, err := c.eventstore.Push(ctx, cmds...)
 	if err != nil {
 		return nil, err
@@ -346,6 +1189,12 @@ func (c *Commands) updateSession(ctx context.Context, checks *SessionCommands, m
 	if err != nil {
 		return nil, err
 	}
+	// the session counts towards the maximum of concurrent sessions as soon as the user is checked
+	if userID := checks.sessionWriteModel.UserID; userID != "" && userID != userBefore {
+		_, err = c.EnforceMaxSessionsPerUser(ctx, userID, checks.sessionWriteModel.AggregateID)
+		// the session itself was already updated, so the failed enforcement is only logged
+		logging.WithFields("sessionID", checks.sessionWriteModel.AggregateID).OnError(err).Warn("could not enforce max sessions per user")
+	}
 	changed := sessionWriteModelToSessionChanged(checks.sessionWriteModel)
 	changed.NewToken = sessionToken
 	return changed, nil
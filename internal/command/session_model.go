@@ -1,50 +1,389 @@
 package command
 
 import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"math"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/zitadel/zitadel/internal/domain"
+	caos_errs "github.com/zitadel/zitadel/internal/errors"
 	"github.com/zitadel/zitadel/internal/eventstore"
 	"github.com/zitadel/zitadel/internal/repository/session"
 )
 
 type WebAuthNChallengeModel struct {
-	Challenge          string
-	AllowedCrentialIDs [][]byte
-	UserVerification   domain.UserVerificationRequirement
-	RPID               string
+	Challenge            string
+	AllowedCredentialIDs [][]byte
+	// AllowedTransports are the transports (e.g. usb, nfc, ble or internal) of the allowed credentials
+	// by their (base64 URL encoded) credential id
+	AllowedTransports map[string][]string
+	UserVerification  domain.UserVerificationRequirement
+	RPID              string
+	Expiration        time.Time
 }
 
-func (p *WebAuthNChallengeModel) WebAuthNLogin(human *domain.Human, credentialAssertionData []byte) *domain.WebAuthNLogin {
-	return &domain.WebAuthNLogin{
+// IsValid returns false if the challenge has expired at the provided time.
+// Challenges created without an expiration are always valid.
+func (p *WebAuthNChallengeModel) IsValid(now time.Time) bool {
+	return p.Expiration.IsZero() || !now.After(p.Expiration)
+}
+
+// AllowedCrentialIDs returns the [WebAuthNChallengeModel.AllowedCredentialIDs].
+//
+// Deprecated: use the AllowedCredentialIDs field instead
+func (p *WebAuthNChallengeModel) AllowedCrentialIDs() [][]byte {
+	return p.AllowedCredentialIDs
+}
+
+// SetAllowedCrentialIDs sets the [WebAuthNChallengeModel.AllowedCredentialIDs].
+//
+// Deprecated: use the AllowedCredentialIDs field instead
+func (p *WebAuthNChallengeModel) SetAllowedCrentialIDs(ids [][]byte) {
+	p.AllowedCredentialIDs = ids
+}
+
+// IsConditional returns true if the challenge does not restrict the credentials (no AllowedCredentialIDs),
+// e.g. for conditional mediation (autofill), where the user picks any of their discoverable credentials.
+func (p *WebAuthNChallengeModel) IsConditional() bool {
+	return len(p.AllowedCredentialIDs) == 0
+}
+
+// Transports returns the [WebAuthNChallengeModel.AllowedTransports] of the provided credential
+func (p *WebAuthNChallengeModel) Transports(credentialID []byte) []string {
+	return p.AllowedTransports[base64.RawURLEncoding.EncodeToString(credentialID)]
+}
+
+// AuthMethodType returns the factor a check of the challenge is intended for:
+// passwordless if user verification is required, U2F otherwise.
+func (p *WebAuthNChallengeModel) AuthMethodType() domain.UserAuthMethodType {
+	if p.UserVerification == domain.UserVerificationRequirementRequired {
+		return domain.UserAuthMethodTypePasswordless
+	}
+	return domain.UserAuthMethodTypeU2F
+}
+
+// ValidateRPID checks that the RPID of the challenge is the host of the provided origin
+// or a registrable suffix of it (e.g. RPID `example.com` for origin `https://login.example.com`).
+// A challenge without RPID is bound to the requested host and therefore not checked.
+func (p *WebAuthNChallengeModel) ValidateRPID(origin string) error {
+	if p.RPID == "" {
+		return nil
+	}
+	originURL, err := url.Parse(origin)
+	if err != nil || originURL.Hostname() == "" {
+		return caos_errs.ThrowPreconditionFailed(err, "COMMAND-eiF6u", "Errors.Session.WebAuthN.RPIDMismatch")
+	}
+	host := strings.ToLower(originURL.Hostname())
+	rpid := strings.ToLower(p.RPID)
+	if host != rpid && !strings.HasSuffix(host, "."+rpid) {
+		return caos_errs.ThrowPreconditionFailed(nil, "COMMAND-ahT4o", "Errors.Session.WebAuthN.RPIDMismatch")
+	}
+	return nil
+}
+
+// WebAuthNLogin returns the login for the challenge.
+// It fails if the policy requires user verification, but the challenge was not issued with it,
+// to prevent a downgrade of the user verification.
+func (p *WebAuthNChallengeModel) WebAuthNLogin(human *domain.Human, credentialAssertionData []byte, policyUserVerification domain.UserVerificationRequirement) (*domain.WebAuthNLogin, error) {
+	// defense in depth: the user verification is already checked on creation of the challenge,
+	// but the policy might have changed since then
+	if err := checkPolicyUserVerification(p.UserVerification, policyUserVerification); err != nil {
+		return nil, err
+	}
+	login := &domain.WebAuthNLogin{
 		ObjectRoot:              human.ObjectRoot,
 		CredentialAssertionData: credentialAssertionData,
 		Challenge:               p.Challenge,
-		AllowedCredentialIDs:    p.AllowedCrentialIDs,
 		UserVerification:        p.UserVerification,
 		RPID:                    p.RPID,
 	}
+	// a conditional challenge accepts any credential of the user, which is checked on validation of the assertion
+	if !p.IsConditional() {
+		login.AllowedCredentialIDs = p.AllowedCredentialIDs
+	}
+	return login, nil
+}
+
+// MarshalBinary implements [encoding.BinaryMarshaler],
+// so the challenge can be stored in a cache shared by all nodes and completed on another node.
+func (p *WebAuthNChallengeModel) MarshalBinary() ([]byte, error) {
+	data, err := json.Marshal(&session.SnapshotWebAuthNChallenge{
+		Challenge:            p.Challenge,
+		AllowedCredentialIDs: p.AllowedCredentialIDs,
+		AllowedTransports:    p.AllowedTransports,
+		UserVerification:     p.UserVerification,
+		RPID:                 p.RPID,
+		Expiration:           p.Expiration,
+	})
+	if err != nil {
+		return nil, caos_errs.ThrowInternal(err, "COMMAND-Phe4u", "Errors.Internal")
+	}
+	return data, nil
+}
+
+// UnmarshalBinary implements [encoding.BinaryUnmarshaler] for challenges marshalled by [WebAuthNChallengeModel.MarshalBinary].
+func (p *WebAuthNChallengeModel) UnmarshalBinary(data []byte) error {
+	challenge := new(session.SnapshotWebAuthNChallenge)
+	if err := json.Unmarshal(data, challenge); err != nil {
+		return caos_errs.ThrowInternal(err, "COMMAND-Eix7a", "Errors.Internal")
+	}
+	*p = WebAuthNChallengeModel{
+		Challenge:            challenge.Challenge,
+		AllowedCredentialIDs: challenge.AllowedCredentialIDs,
+		AllowedTransports:    challenge.AllowedTransports,
+		UserVerification:     challenge.UserVerification,
+		RPID:                 challenge.RPID,
+		Expiration:           challenge.Expiration,
+	}
+	return nil
+}
+
+func (p *WebAuthNChallengeModel) clone() *WebAuthNChallengeModel {
+	challenge := *p
+	if p.AllowedCredentialIDs != nil {
+		challenge.AllowedCredentialIDs = make([][]byte, len(p.AllowedCredentialIDs))
+		for i, id := range p.AllowedCredentialIDs {
+			challenge.AllowedCredentialIDs[i] = bytes.Clone(id)
+		}
+	}
+	if p.AllowedTransports != nil {
+		challenge.AllowedTransports = make(map[string][]string, len(p.AllowedTransports))
+		for id, transports := range p.AllowedTransports {
+			challenge.AllowedTransports[id] = append([]string(nil), transports...)
+		}
+	}
+	return &challenge
+}
+
+// defaultSessionCheckHistoryLimit is the amount of entries kept in the [SessionWriteModel.CheckHistory]
+// if no [SessionWriteModel.CheckHistoryLimit] is set
+const defaultSessionCheckHistoryLimit = 50
+
+// idempotentCheckWindow is the time span, in which a check with the same idempotency key is considered a retry
+const idempotentCheckWindow = 5 * time.Minute
+
+// IdempotentCheck is the latest check of a factor made with an idempotency key
+type IdempotentCheck struct {
+	IdempotencyKey string
+	CheckedAt      time.Time
+}
+
+// FactorCheck is a single (succeeded or failed) check of a factor on the session
+type FactorCheck struct {
+	Factor    domain.UserAuthMethodType
+	CheckedAt time.Time
+	Succeeded bool
+}
+
+const (
+	defaultSessionMetadataMaxKeyLength   = 200
+	defaultSessionMetadataMaxValueLength = 16 * 1024
+	defaultSessionMetadataMaxTotalSize   = 64 * 1024
+	defaultSessionMetadataMaxEntries     = 100
+)
+
+// SessionMetadataLimits restricts the size of the metadata of a session.
+// Limits not set (zero) will fall back to the defaults.
+type SessionMetadataLimits struct {
+	MaxKeyLength   int
+	MaxValueLength int
+	// MaxTotalSize is the maximum sum of the length of all keys and values
+	MaxTotalSize int
+	// MaxEntries is the maximum amount of distinct keys
+	MaxEntries int
+}
+
+func (l SessionMetadataLimits) maxKeyLength() int {
+	if l.MaxKeyLength > 0 {
+		return l.MaxKeyLength
+	}
+	return defaultSessionMetadataMaxKeyLength
+}
+
+func (l SessionMetadataLimits) maxValueLength() int {
+	if l.MaxValueLength > 0 {
+		return l.MaxValueLength
+	}
+	return defaultSessionMetadataMaxValueLength
+}
+
+func (l SessionMetadataLimits) maxTotalSize() int {
+	if l.MaxTotalSize > 0 {
+		return l.MaxTotalSize
+	}
+	return defaultSessionMetadataMaxTotalSize
+}
+
+func (l SessionMetadataLimits) maxEntries() int {
+	if l.MaxEntries > 0 {
+		return l.MaxEntries
+	}
+	return defaultSessionMetadataMaxEntries
+}
+
+func (l SessionMetadataLimits) validateEntry(key string, value []byte) error {
+	if key == "" || len(key) > l.maxKeyLength() {
+		return caos_errs.ThrowInvalidArgument(nil, "COMMAND-Oow8i", "Errors.Session.Metadata.KeyInvalid")
+	}
+	if len(value) > l.maxValueLength() {
+		return caos_errs.ThrowInvalidArgument(nil, "COMMAND-ieM3a", "Errors.Session.Metadata.ValueTooLong")
+	}
+	return nil
+}
+
+func (l SessionMetadataLimits) validate(metadata map[string][]byte) error {
+	if len(metadata) > l.maxEntries() {
+		return caos_errs.ThrowInvalidArgument(nil, "COMMAND-Ohb3e", "Errors.Session.Metadata.TooManyEntries")
+	}
+	var size int
+	for key, value := range metadata {
+		if err := l.validateEntry(key, value); err != nil {
+			return err
+		}
+		size += len(key) + len(value)
+	}
+	if size > l.maxTotalSize() {
+		return caos_errs.ThrowInvalidArgument(nil, "COMMAND-Gae4u", "Errors.Session.Metadata.TooLarge")
+	}
+	return nil
 }
 
 type SessionWriteModel struct {
 	eventstore.WriteModel
 
-	TokenID              string
-	UserID               string
-	UserCheckedAt        time.Time
-	PasswordCheckedAt    time.Time
-	IntentCheckedAt      time.Time
-	WebAuthNCheckedAt    time.Time
-	TOTPCheckedAt        time.Time
+	TokenID string
+	// PreviousTokenID is the id of the token replaced by the current one
+	PreviousTokenID string
+	// RevokedTokenIDs are the ids of all tokens replaced by a newer one (in the order of their replacement)
+	RevokedTokenIDs []string
+	UserID          string
+	// UserAgentFingerprintID is the id of the user agent (browser / device) the session was created with
+	UserAgentFingerprintID string
+	// CreatedFromRequestID is the id of the (auth) request the session was created for
+	CreatedFromRequestID string
+	// ClientID is the id of the client (application) the session was created by
+	ClientID string
+	// AllowedAuthMethods restricts the factors, which can be checked on the session (e.g. for kiosks), empty allows all
+	AllowedAuthMethods []domain.UserAuthMethodType
+	UserCheckedAt      time.Time
+	PasswordCheckedAt  time.Time
+	// PasswordCheckFailures is the amount of failed password checks since the last succeeded one
+	PasswordCheckFailures int
+	// PasswordChangedAt is the latest time the password of the user was changed during the session,
+	// invalidating the password checks made before
+	PasswordChangedAt time.Time
+	IntentCheckedAt   time.Time
+	// IntentIDPID is the id of the identity provider of the checked intent
+	IntentIDPID string
+	// IntentProtocol is the federation protocol (e.g. OIDC or SAML) of the checked intent
+	IntentProtocol    domain.IDPIntentProtocol
+	WebAuthNCheckedAt time.Time
+	TOTPCheckedAt     time.Time
+	// TOTPDeviceID identifies the TOTP authenticator of the latest TOTP check
+	TOTPDeviceID string
+	// TOTPLastStep is the latest time-step a TOTP code was verified for, so no code can be reused on the session.
+	// It's kept even if the TOTP check is invalidated.
+	TOTPLastStep          uint64
+	RecoveryCodeCheckedAt time.Time
+	// ClientCertCheckedAt is the time of the latest authentication by a client certificate (mTLS)
+	ClientCertCheckedAt time.Time
+	// ClientCertFingerprint is the fingerprint of the certificate of the latest client certificate check
+	ClientCertFingerprint string
+	OTPSMSCheckedAt       time.Time
+	// OTPSMSPhoneSequence is the sequence of the latest change of the phone number the checked OTP SMS was sent to
+	// (see [HumanPhoneWriteModel.PhoneChangedSequence])
+	OTPSMSPhoneSequence uint64
+	OTPEmailCheckedAt   time.Time
+	// WebAuthNUserVerified states if the user was verified on any WebAuthN check since the last invalidation,
+	// a later check without user verification does not reset it
 	WebAuthNUserVerified bool
-	Metadata             map[string][]byte
-	State                domain.SessionState
+	// WebAuthNUserPresent states if the user was present during the WebAuthN check.
+	// It does not imply user verification and therefore a presence only check will never be passwordless.
+	WebAuthNUserPresent bool
+	// WebAuthNSignCount is the signature counter of the authenticator reported on the latest WebAuthN check
+	WebAuthNSignCount uint32
+	// WebAuthNAttestationFormat is the attestation format of the credential used on the latest WebAuthN check
+	WebAuthNAttestationFormat string
+	// WebAuthNExtensionResults are the client extension outputs (e.g. credProps) of the latest WebAuthN check
+	WebAuthNExtensionResults map[string]interface{}
+	// WebAuthNIsPasswordless is derived from the challenges the WebAuthN checks were made for
+	// and states if any was intended as passwordless (and not as second factor) authentication
+	WebAuthNIsPasswordless bool
+	Metadata               map[string][]byte
+	State                  domain.SessionState
+	TerminationReason      domain.SessionTerminationType
+	Expiration             time.Time
+	IdleTimeout            time.Duration
+	IdleExpiration         time.Time
+	// LastActivityAt is the latest time the session was used without a check, e.g. on validation of its token
+	LastActivityAt time.Time
+	// SchemaVersion is the aggregate version of the latest reduced event.
+	// Events of older versions might lack fields, which are then reduced with defaults (e.g. the creation date as check time).
+	SchemaVersion eventstore.Version
 
+	// WebAuthNChallenge is the latest issued and not yet checked challenge
 	WebAuthNChallenge *WebAuthNChallengeModel
+	// WebAuthNChallenges contains all issued and not yet checked challenges by their challenge
+	WebAuthNChallenges map[string]*WebAuthNChallengeModel
+
+	// MetadataExpirations contains the time until the value of the key is valid, for keys set with a TTL.
+	// Expired keys are kept in the Metadata until they are removed, but treated as absent by [SessionWriteModel.GetMetadata].
+	MetadataExpirations map[string]time.Time
+	MetadataLimits      SessionMetadataLimits
+	// ScopeAuthLevels maps scopes (e.g. an admin scope) to the [domain.AuthLevel] required to be granted,
+	// scopes not contained do not require a specific level
+	ScopeAuthLevels map[string]domain.AuthLevel
+
+	// CheckHistory contains the checks of the factors in the order they were reduced,
+	// limited to the latest CheckHistoryLimit entries
+	CheckHistory []FactorCheck
+	// CheckHistoryLimit is the maximum amount of entries in the CheckHistory (default: 50)
+	CheckHistoryLimit int
+
+	// IdempotentChecks contains the latest check per factor, which was made with an idempotency key
+	IdempotentChecks map[domain.UserAuthMethodType]IdempotentCheck
+
+	// ParentSessionID is the id of the session this session was forked from (e.g. for impersonation)
+	ParentSessionID string
+	// DelegatorUserID is the user of the parent session
+	DelegatorUserID string
+	// InheritedAuthMethodTypes are the auth method types of the parent session at the time of the fork
+	InheritedAuthMethodTypes []domain.UserAuthMethodType
+
+	// TrustedDevice states if the device of the session was marked as trusted (remembered)
+	TrustedDevice bool
+	// TrustedDeviceExpiration is the time until the device is trusted (zero if it never expires)
+	TrustedDeviceExpiration time.Time
+
+	// SecurityLevelDegraded states if a check of the session was invalidated (e.g. the used credential was removed),
+	// so the user might need to re-authenticate
+	SecurityLevelDegraded bool
+	// UserMismatch states if a user was checked, which differs from the user checked before.
+	// The user of a session is expected to be stable, so it's an anomaly which should be alerted on.
+	UserMismatch bool
+
+	// ReduceObserver is notified after each applied event, if set
+	ReduceObserver ReduceObserver
+
+	// eventsSinceSnapshot is the amount of reduced events since the latest snapshot (or the creation)
+	eventsSinceSnapshot int
 
 	aggregate *eventstore.Aggregate
 }
 
+// ReduceObserver is notified by [SessionWriteModel.Reduce] after each applied event,
+// e.g. to emit metrics or traces on the state transitions of the session.
+// It must not modify the write model.
+type ReduceObserver interface {
+	OnEvent(eventType string, wm *SessionWriteModel)
+}
+
 func NewSessionWriteModel(sessionID string, resourceOwner string) *SessionWriteModel {
 	return &SessionWriteModel{
 		WriteModel: eventstore.WriteModel{
@@ -56,15 +395,158 @@ func NewSessionWriteModel(sessionID string, resourceOwner string) *SessionWriteM
 	}
 }
 
+// Reset clears all state of the write model, so it can be reused for the session with the provided id.
+// The allocated metadata map and events slice are kept to reduce allocations.
+// The configuration (MetadataLimits, ScopeAuthLevels, CheckHistoryLimit and the [ReduceObserver]) is kept as well,
+// so the reused model behaves the same as a new one configured the same way.
+func (wm *SessionWriteModel) Reset(sessionID, resourceOwner string) {
+	metadata := wm.Metadata
+	for key := range metadata {
+		delete(metadata, key)
+	}
+	if metadata == nil {
+		metadata = make(map[string][]byte)
+	}
+	events := wm.Events[:0]
+	*wm = SessionWriteModel{
+		WriteModel: eventstore.WriteModel{
+			AggregateID:   sessionID,
+			ResourceOwner: resourceOwner,
+			Events:        events,
+		},
+		Metadata:          metadata,
+		MetadataLimits:    wm.MetadataLimits,
+		ScopeAuthLevels:   wm.ScopeAuthLevels,
+		CheckHistoryLimit: wm.CheckHistoryLimit,
+		ReduceObserver:    wm.ReduceObserver,
+		aggregate:         &session.NewAggregate(sessionID, resourceOwner).Aggregate,
+	}
+}
+
+// Clone returns a deep copy of the write model,
+// so that changes on the copy (e.g. during speculative checks) will not affect the original.
+func (wm *SessionWriteModel) Clone() *SessionWriteModel {
+	clone := *wm
+	if wm.Events != nil {
+		clone.Events = make([]eventstore.Event, len(wm.Events))
+		copy(clone.Events, wm.Events)
+	}
+	if wm.RevokedTokenIDs != nil {
+		clone.RevokedTokenIDs = make([]string, len(wm.RevokedTokenIDs))
+		copy(clone.RevokedTokenIDs, wm.RevokedTokenIDs)
+	}
+	if wm.AllowedAuthMethods != nil {
+		clone.AllowedAuthMethods = make([]domain.UserAuthMethodType, len(wm.AllowedAuthMethods))
+		copy(clone.AllowedAuthMethods, wm.AllowedAuthMethods)
+	}
+	if wm.InheritedAuthMethodTypes != nil {
+		clone.InheritedAuthMethodTypes = make([]domain.UserAuthMethodType, len(wm.InheritedAuthMethodTypes))
+		copy(clone.InheritedAuthMethodTypes, wm.InheritedAuthMethodTypes)
+	}
+	if wm.CheckHistory != nil {
+		clone.CheckHistory = make([]FactorCheck, len(wm.CheckHistory))
+		copy(clone.CheckHistory, wm.CheckHistory)
+	}
+	if wm.IdempotentChecks != nil {
+		clone.IdempotentChecks = make(map[domain.UserAuthMethodType]IdempotentCheck, len(wm.IdempotentChecks))
+		for factor, check := range wm.IdempotentChecks {
+			clone.IdempotentChecks[factor] = check
+		}
+	}
+	if wm.Metadata != nil {
+		clone.Metadata = make(map[string][]byte, len(wm.Metadata))
+		for key, value := range wm.Metadata {
+			clone.Metadata[key] = bytes.Clone(value)
+		}
+	}
+	if wm.MetadataExpirations != nil {
+		clone.MetadataExpirations = make(map[string]time.Time, len(wm.MetadataExpirations))
+		for key, expiration := range wm.MetadataExpirations {
+			clone.MetadataExpirations[key] = expiration
+		}
+	}
+	if wm.ScopeAuthLevels != nil {
+		clone.ScopeAuthLevels = make(map[string]domain.AuthLevel, len(wm.ScopeAuthLevels))
+		for scope, level := range wm.ScopeAuthLevels {
+			clone.ScopeAuthLevels[scope] = level
+		}
+	}
+	if wm.WebAuthNExtensionResults != nil {
+		clone.WebAuthNExtensionResults = make(map[string]interface{}, len(wm.WebAuthNExtensionResults))
+		for key, value := range wm.WebAuthNExtensionResults {
+			clone.WebAuthNExtensionResults[key] = value
+		}
+	}
+	if wm.WebAuthNChallenges != nil {
+		clone.WebAuthNChallenges = make(map[string]*WebAuthNChallengeModel, len(wm.WebAuthNChallenges))
+		for id, challenge := range wm.WebAuthNChallenges {
+			clone.WebAuthNChallenges[id] = challenge.clone()
+		}
+	}
+	if wm.WebAuthNChallenge != nil {
+		clone.WebAuthNChallenge = clone.WebAuthNChallenges[wm.WebAuthNChallenge.Challenge]
+		if clone.WebAuthNChallenge == nil {
+			clone.WebAuthNChallenge = wm.WebAuthNChallenge.clone()
+		}
+	}
+	if wm.aggregate != nil {
+		aggregate := *wm.aggregate
+		clone.aggregate = &aggregate
+	}
+	return &clone
+}
+
+// Diff returns the names of the (exported) fields, which differ between the session and the provided one,
+// e.g. a [SessionWriteModel.Clone] taken before reducing further events.
+// The embedded [eventstore.WriteModel] is not compared, so only changes of the session state itself are returned.
+func (wm *SessionWriteModel) Diff(other *SessionWriteModel) []string {
+	current := reflect.ValueOf(wm).Elem()
+	previous := reflect.ValueOf(other).Elem()
+	var changed []string
+	for i := 0; i < current.NumField(); i++ {
+		field := current.Type().Field(i)
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+		if !fieldEqual(current.Field(i).Interface(), previous.Field(i).Interface()) {
+			changed = append(changed, field.Name)
+		}
+	}
+	return changed
+}
+
+func fieldEqual(a, b interface{}) bool {
+	if t, ok := a.(time.Time); ok {
+		return t.Equal(b.(time.Time))
+	}
+	return reflect.DeepEqual(a, b)
+}
+
 func (wm *SessionWriteModel) Reduce() error {
 	for _, event := range wm.Events {
+		// defense in depth: events of another resource owner (e.g. because of a wrong query) must never change the session
+		if wm.ResourceOwner != "" && event.Aggregate().ResourceOwner != wm.ResourceOwner {
+			return caos_errs.ThrowInternal(nil, "COMMAND-Ooy6u", "Errors.Session.ResourceOwnerMismatch")
+		}
+		wm.eventsSinceSnapshot++
+		wm.SchemaVersion = event.Aggregate().Version
+		// events (e.g. checks) created concurrently to and stored after the termination must not change the session
+		if _, isSnapshot := event.(*session.SnapshotEvent); wm.State == domain.SessionStateTerminated && !isSnapshot {
+			continue
+		}
 		switch e := event.(type) {
+		case *session.SnapshotEvent:
+			wm.reduceSnapshot(e)
 		case *session.AddedEvent:
 			wm.reduceAdded(e)
 		case *session.UserCheckedEvent:
 			wm.reduceUserChecked(e)
 		case *session.PasswordCheckedEvent:
 			wm.reducePasswordChecked(e)
+		case *session.PasswordCheckFailedEvent:
+			wm.reducePasswordCheckFailed(e)
+		case *session.PasswordChangedEvent:
+			wm.reducePasswordChanged(e)
 		case *session.IntentCheckedEvent:
 			wm.reduceIntentChecked(e)
 		case *session.WebAuthNChallengedEvent:
@@ -73,32 +555,132 @@ func (wm *SessionWriteModel) Reduce() error {
 			wm.reduceWebAuthNChecked(e)
 		case *session.TOTPCheckedEvent:
 			wm.reduceTOTPChecked(e)
+		case *session.RecoveryCodeCheckedEvent:
+			wm.reduceRecoveryCodeChecked(e)
+		case *session.ClientCertCheckedEvent:
+			wm.reduceClientCertChecked(e)
+		case *session.OTPSMSCheckedEvent:
+			wm.reduceOTPSMSChecked(e)
+		case *session.OTPEmailCheckedEvent:
+			wm.reduceOTPEmailChecked(e)
 		case *session.TokenSetEvent:
 			wm.reduceTokenSet(e)
+		case *session.MetadataSetEvent:
+			wm.reduceMetadataSet(e)
+		case *session.MetadataBulkSetEvent:
+			wm.reduceMetadataBulkSet(e)
+		case *session.MetadataRemovedEvent:
+			wm.reduceMetadataRemoved(e)
+		case *session.LifetimeSetEvent:
+			wm.reduceLifetimeSet(e)
+		case *session.ForkedEvent:
+			wm.reduceForked(e)
+		case *session.DeviceTrustedEvent:
+			wm.reduceDeviceTrusted(e)
+		case *session.FactorInvalidatedEvent:
+			wm.reduceFactorInvalidated(e)
+		case *session.LockedEvent:
+			wm.reduceLocked()
+		case *session.UnlockedEvent:
+			wm.reduceUnlocked()
+		case *session.ActivityEvent:
+			wm.reduceActivity(e)
 		case *session.TerminateEvent:
-			wm.reduceTerminate()
+			wm.reduceTerminate(e)
+		}
+		if wm.ReduceObserver != nil {
+			wm.ReduceObserver.OnEvent(string(event.Type()), wm)
 		}
 	}
 	return wm.WriteModel.Reduce()
 }
 
+// ApplyEvents reduces the provided (new) events onto the current state without querying the eventstore,
+// e.g. for events pushed by a subscription to watch the session.
+// The events must be ordered by their sequence. Events already reduced (sequence not greater than the processed one)
+// are skipped, so overlapping batches can be applied.
+func (wm *SessionWriteModel) ApplyEvents(events []eventstore.Event) error {
+	for _, event := range events {
+		if wm.ProcessedSequence > 0 && event.Sequence() <= wm.ProcessedSequence {
+			continue
+		}
+		wm.AppendEvents(event)
+	}
+	return wm.Reduce()
+}
+
 func (wm *SessionWriteModel) Query() *eventstore.SearchQueryBuilder {
+	return wm.query(wm.ProcessedSequence, wm.AggregateID)
+}
+
+// DebugQuery describes the eventstore query [SessionWriteModel.Query] uses to rebuild the session.
+// It helps diagnosing why a session could not be loaded.
+func (wm *SessionWriteModel) DebugQuery() string {
+	return wm.Query().String()
+}
+
+// QueryMany returns the query for the events of all provided sessions.
+// Like [SessionWriteModel.Query], it's restricted to the resource owner of the write model (if set).
+func (wm *SessionWriteModel) QueryMany(ids ...string) *eventstore.SearchQueryBuilder {
+	return wm.query(0, ids...)
+}
+
+func (wm *SessionWriteModel) query(sequenceGreater uint64, ids ...string) *eventstore.SearchQueryBuilder {
+	query := eventstore.NewSearchQueryBuilder(eventstore.ColumnsEvent).
+		AddQuery().
+		AggregateTypes(session.AggregateType).
+		AggregateIDs(ids...).
+		EventTypes(sessionWriteModelEventTypes()...).
+		SequenceGreater(sequenceGreater).
+		Builder()
+
+	if wm.ResourceOwner != "" {
+		query.ResourceOwner(wm.ResourceOwner)
+	}
+	return query
+}
+
+// sessionWriteModelEventTypes returns the types of all events reduced by the [SessionWriteModel]
+func sessionWriteModelEventTypes() []eventstore.EventType {
+	return []eventstore.EventType{
+		session.AddedType,
+		session.UserCheckedType,
+		session.PasswordCheckedType,
+		session.PasswordCheckFailedType,
+		session.PasswordChangedType,
+		session.IntentCheckedType,
+		session.WebAuthNChallengedType,
+		session.WebAuthNCheckedType,
+		session.TOTPCheckedType,
+		session.OTPSMSCheckedType,
+		session.OTPEmailCheckedType,
+		session.RecoveryCodeCheckedType,
+		session.ClientCertCheckedType,
+		session.TokenSetType,
+		session.LifetimeSetType,
+		session.MetadataSetType,
+		session.MetadataBulkSetType,
+		session.MetadataRemovedType,
+		session.ForkedType,
+		session.DeviceTrustedType,
+		session.FactorInvalidatedType,
+		session.LockedType,
+		session.UnlockedType,
+		session.ActivityType,
+		session.TerminateType,
+		session.SnapshotType,
+	}
+}
+
+// snapshotQuery returns the query for the latest [session.SnapshotEvent] of the session
+func (wm *SessionWriteModel) snapshotQuery() *eventstore.SearchQueryBuilder {
 	query := eventstore.NewSearchQueryBuilder(eventstore.ColumnsEvent).
+		OrderDesc().
+		Limit(1).
 		AddQuery().
 		AggregateTypes(session.AggregateType).
 		AggregateIDs(wm.AggregateID).
-		EventTypes(
-			session.AddedType,
-			session.UserCheckedType,
-			session.PasswordCheckedType,
-			session.IntentCheckedType,
-			session.WebAuthNChallengedType,
-			session.WebAuthNCheckedType,
-			session.TOTPCheckedType,
-			session.TokenSetType,
-			session.MetadataSetType,
-			session.TerminateType,
-		).
+		EventTypes(session.SnapshotType).
 		Builder()
 
 	if wm.ResourceOwner != "" {
@@ -107,75 +689,849 @@ func (wm *SessionWriteModel) Query() *eventstore.SearchQueryBuilder {
 	return query
 }
 
+// reduceSnapshot replaces the whole state of the session by the state of the snapshot
+func (wm *SessionWriteModel) reduceSnapshot(e *session.SnapshotEvent) {
+	wm.TokenID = e.TokenID
+	wm.PreviousTokenID = e.PreviousTokenID
+	wm.RevokedTokenIDs = e.RevokedTokenIDs
+	wm.UserID = e.UserID
+	wm.UserAgentFingerprintID = e.UserAgentFingerprintID
+	wm.CreatedFromRequestID = e.CreatedFromRequestID
+	wm.ClientID = e.ClientID
+	wm.AllowedAuthMethods = e.AllowedAuthMethods
+	wm.UserCheckedAt = e.UserCheckedAt
+	wm.PasswordCheckedAt = e.PasswordCheckedAt
+	wm.PasswordCheckFailures = e.PasswordCheckFailures
+	wm.PasswordChangedAt = e.PasswordChangedAt
+	wm.IntentCheckedAt = e.IntentCheckedAt
+	wm.IntentIDPID = e.IntentIDPID
+	wm.IntentProtocol = e.IntentProtocol
+	wm.WebAuthNCheckedAt = e.WebAuthNCheckedAt
+	wm.WebAuthNUserVerified = e.WebAuthNUserVerified
+	wm.WebAuthNUserPresent = e.WebAuthNUserPresent
+	wm.WebAuthNSignCount = e.WebAuthNSignCount
+	wm.WebAuthNAttestationFormat = e.WebAuthNAttestationFormat
+	wm.WebAuthNExtensionResults = e.WebAuthNExtensionResults
+	wm.WebAuthNIsPasswordless = e.WebAuthNIsPasswordless
+	wm.TOTPCheckedAt = e.TOTPCheckedAt
+	wm.TOTPDeviceID = e.TOTPDeviceID
+	wm.TOTPLastStep = e.TOTPLastStep
+	wm.RecoveryCodeCheckedAt = e.RecoveryCodeCheckedAt
+	wm.ClientCertCheckedAt = e.ClientCertCheckedAt
+	wm.ClientCertFingerprint = e.ClientCertFingerprint
+	wm.OTPSMSCheckedAt = e.OTPSMSCheckedAt
+	wm.OTPSMSPhoneSequence = e.OTPSMSPhoneSequence
+	wm.OTPEmailCheckedAt = e.OTPEmailCheckedAt
+	wm.Metadata = make(map[string][]byte, len(e.Metadata))
+	for key, value := range e.Metadata {
+		wm.Metadata[key] = value
+	}
+	wm.MetadataExpirations = e.MetadataExpirations
+	wm.State = e.State
+	wm.TerminationReason = e.TerminationReason
+	wm.Expiration = e.Expiration
+	wm.IdleTimeout = e.IdleTimeout
+	wm.IdleExpiration = e.IdleExpiration
+	wm.LastActivityAt = e.LastActivityAt
+	wm.WebAuthNChallenges = nil
+	for _, challenge := range e.WebAuthNChallenges {
+		if wm.WebAuthNChallenges == nil {
+			wm.WebAuthNChallenges = make(map[string]*WebAuthNChallengeModel, len(e.WebAuthNChallenges))
+		}
+		wm.WebAuthNChallenges[challenge.Challenge] = &WebAuthNChallengeModel{
+			Challenge:            challenge.Challenge,
+			AllowedCredentialIDs: challenge.AllowedCredentialIDs,
+			AllowedTransports:    challenge.AllowedTransports,
+			UserVerification:     challenge.UserVerification,
+			RPID:                 challenge.RPID,
+			Expiration:           challenge.Expiration,
+		}
+	}
+	wm.WebAuthNChallenge = wm.WebAuthNChallenges[e.LatestWebAuthNChallenge]
+	wm.ParentSessionID = e.ParentSessionID
+	wm.DelegatorUserID = e.DelegatorUserID
+	wm.InheritedAuthMethodTypes = e.InheritedAuthMethodTypes
+	wm.TrustedDevice = e.TrustedDevice
+	wm.TrustedDeviceExpiration = e.TrustedDeviceExpiration
+	wm.SecurityLevelDegraded = e.SecurityLevelDegraded
+	wm.UserMismatch = e.UserMismatch
+	wm.CheckHistory = nil
+	for _, check := range e.CheckHistory {
+		wm.appendCheckHistory(check.Factor, check.CheckedAt, check.Succeeded)
+	}
+	wm.IdempotentChecks = nil
+	for factor, check := range e.IdempotentChecks {
+		wm.setIdempotentCheck(factor, check.IdempotencyKey, check.CheckedAt)
+	}
+	wm.eventsSinceSnapshot = 0
+}
+
+// snapshotState returns the current state of the session to be stored in a [session.SnapshotEvent]
+func (wm *SessionWriteModel) snapshotState() session.SnapshotState {
+	state := session.SnapshotState{
+		TokenID:                   wm.TokenID,
+		PreviousTokenID:           wm.PreviousTokenID,
+		RevokedTokenIDs:           wm.RevokedTokenIDs,
+		UserID:                    wm.UserID,
+		UserAgentFingerprintID:    wm.UserAgentFingerprintID,
+		CreatedFromRequestID:      wm.CreatedFromRequestID,
+		ClientID:                  wm.ClientID,
+		AllowedAuthMethods:        wm.AllowedAuthMethods,
+		UserCheckedAt:             wm.UserCheckedAt,
+		PasswordCheckedAt:         wm.PasswordCheckedAt,
+		PasswordCheckFailures:     wm.PasswordCheckFailures,
+		PasswordChangedAt:         wm.PasswordChangedAt,
+		IntentCheckedAt:           wm.IntentCheckedAt,
+		IntentIDPID:               wm.IntentIDPID,
+		IntentProtocol:            wm.IntentProtocol,
+		WebAuthNCheckedAt:         wm.WebAuthNCheckedAt,
+		WebAuthNUserVerified:      wm.WebAuthNUserVerified,
+		WebAuthNUserPresent:       wm.WebAuthNUserPresent,
+		WebAuthNSignCount:         wm.WebAuthNSignCount,
+		WebAuthNAttestationFormat: wm.WebAuthNAttestationFormat,
+		WebAuthNExtensionResults:  wm.WebAuthNExtensionResults,
+		WebAuthNIsPasswordless:    wm.WebAuthNIsPasswordless,
+		TOTPCheckedAt:             wm.TOTPCheckedAt,
+		TOTPDeviceID:              wm.TOTPDeviceID,
+		TOTPLastStep:              wm.TOTPLastStep,
+		RecoveryCodeCheckedAt:     wm.RecoveryCodeCheckedAt,
+		ClientCertCheckedAt:       wm.ClientCertCheckedAt,
+		ClientCertFingerprint:     wm.ClientCertFingerprint,
+		OTPSMSCheckedAt:           wm.OTPSMSCheckedAt,
+		OTPSMSPhoneSequence:       wm.OTPSMSPhoneSequence,
+		OTPEmailCheckedAt:         wm.OTPEmailCheckedAt,
+		Metadata:                  wm.Metadata,
+		MetadataExpirations:       wm.MetadataExpirations,
+		State:                     wm.State,
+		TerminationReason:         wm.TerminationReason,
+		Expiration:                wm.Expiration,
+		IdleTimeout:               wm.IdleTimeout,
+		IdleExpiration:            wm.IdleExpiration,
+		LastActivityAt:            wm.LastActivityAt,
+	}
+	for _, challenge := range wm.WebAuthNChallenges {
+		state.WebAuthNChallenges = append(state.WebAuthNChallenges, &session.SnapshotWebAuthNChallenge{
+			Challenge:            challenge.Challenge,
+			AllowedCredentialIDs: challenge.AllowedCredentialIDs,
+			AllowedTransports:    challenge.AllowedTransports,
+			UserVerification:     challenge.UserVerification,
+			RPID:                 challenge.RPID,
+			Expiration:           challenge.Expiration,
+		})
+	}
+	sort.Slice(state.WebAuthNChallenges, func(i, j int) bool {
+		return state.WebAuthNChallenges[i].Challenge < state.WebAuthNChallenges[j].Challenge
+	})
+	if wm.WebAuthNChallenge != nil {
+		state.LatestWebAuthNChallenge = wm.WebAuthNChallenge.Challenge
+	}
+	state.ParentSessionID = wm.ParentSessionID
+	state.DelegatorUserID = wm.DelegatorUserID
+	state.InheritedAuthMethodTypes = wm.InheritedAuthMethodTypes
+	state.TrustedDevice = wm.TrustedDevice
+	state.TrustedDeviceExpiration = wm.TrustedDeviceExpiration
+	state.SecurityLevelDegraded = wm.SecurityLevelDegraded
+	state.UserMismatch = wm.UserMismatch
+	for _, check := range wm.CheckHistory {
+		state.CheckHistory = append(state.CheckHistory, &session.SnapshotFactorCheck{
+			Factor:    check.Factor,
+			CheckedAt: check.CheckedAt,
+			Succeeded: check.Succeeded,
+		})
+	}
+	for factor, check := range wm.IdempotentChecks {
+		if state.IdempotentChecks == nil {
+			state.IdempotentChecks = make(map[domain.UserAuthMethodType]*session.SnapshotIdempotentCheck, len(wm.IdempotentChecks))
+		}
+		state.IdempotentChecks[factor] = &session.SnapshotIdempotentCheck{
+			IdempotencyKey: check.IdempotencyKey,
+			CheckedAt:      check.CheckedAt,
+		}
+	}
+	return state
+}
+
 func (wm *SessionWriteModel) reduceAdded(e *session.AddedEvent) {
-	wm.State = domain.SessionStateActive
+	// sessions loaded without resource owner (e.g. by cross instance tooling) are scoped to the one of the session,
+	// so all following events are checked against it
+	if wm.ResourceOwner == "" {
+		wm.ResourceOwner = e.Aggregate().ResourceOwner
+	}
+	// the session becomes active as soon as the user is checked
+	wm.State = domain.SessionStatePending
+	wm.UserAgentFingerprintID = e.UserAgentFingerprintID
+	wm.CreatedFromRequestID = e.CreatedFromRequestID
+	wm.ClientID = e.ClientID
+	wm.AllowedAuthMethods = e.AllowedAuthMethods
+	if e.Lifetime > 0 {
+		wm.Expiration = e.CreationDate().Add(e.Lifetime)
+	}
 }
 
 func (wm *SessionWriteModel) reduceUserChecked(e *session.UserCheckedEvent) {
+	checkedAt := checkedAtOrCreationDate(e.CheckedAt, e)
+	if wm.State == domain.SessionStatePending {
+		wm.State = domain.SessionStateActive
+	}
+	if wm.UserID != "" && wm.UserID != e.UserID {
+		wm.UserMismatch = true
+	}
 	wm.UserID = e.UserID
-	wm.UserCheckedAt = e.CheckedAt
+	wm.UserCheckedAt = checkedAt
+	wm.refreshIdleExpiration(checkedAt)
 }
 
 func (wm *SessionWriteModel) reducePasswordChecked(e *session.PasswordCheckedEvent) {
-	wm.PasswordCheckedAt = e.CheckedAt
+	checkedAt := checkedAtOrCreationDate(e.CheckedAt, e)
+	wm.PasswordCheckedAt = checkedAt
+	wm.PasswordCheckFailures = 0
+	wm.appendCheckHistory(domain.UserAuthMethodTypePassword, checkedAt, true)
+	wm.setIdempotentCheck(domain.UserAuthMethodTypePassword, e.IdempotencyKey, checkedAt)
+	wm.refreshIdleExpiration(checkedAt)
+}
+
+func (wm *SessionWriteModel) reducePasswordCheckFailed(e *session.PasswordCheckFailedEvent) {
+	checkedAt := checkedAtOrCreationDate(e.CheckedAt, e)
+	wm.PasswordCheckFailures++
+	wm.appendCheckHistory(domain.UserAuthMethodTypePassword, checkedAt, false)
+}
+
+// reducePasswordChanged removes a password check made before the change,
+// so the password is no longer part of the [SessionWriteModel.AuthMethodTypes]
+func (wm *SessionWriteModel) reducePasswordChanged(e *session.PasswordChangedEvent) {
+	changedAt := checkedAtOrCreationDate(e.ChangedAt, e)
+	wm.PasswordChangedAt = changedAt
+	if wm.PasswordCheckedAt.IsZero() || wm.PasswordCheckedAt.After(changedAt) {
+		return
+	}
+	wm.PasswordCheckedAt = time.Time{}
+	wm.SecurityLevelDegraded = true
 }
 
 func (wm *SessionWriteModel) reduceIntentChecked(e *session.IntentCheckedEvent) {
-	wm.IntentCheckedAt = e.CheckedAt
+	checkedAt := checkedAtOrCreationDate(e.CheckedAt, e)
+	wm.IntentCheckedAt = checkedAt
+	wm.IntentIDPID = e.IDPID
+	wm.IntentProtocol = e.Protocol
+	wm.appendCheckHistory(domain.UserAuthMethodTypeIDP, checkedAt, true)
+	wm.refreshIdleExpiration(checkedAt)
 }
 
 func (wm *SessionWriteModel) reduceWebAuthNChallenged(e *session.WebAuthNChallengedEvent) {
+	if e.ReplacedChallenge != "" {
+		delete(wm.WebAuthNChallenges, e.ReplacedChallenge)
+	}
+	allowedCredentialIDs := e.AllowedCrentialIDs
+	// conditional (autofill) challenges are issued without credentials,
+	// an empty list is stored as nil, so it's handled the same as if it was omitted
+	if len(allowedCredentialIDs) == 0 {
+		allowedCredentialIDs = nil
+	}
 	wm.WebAuthNChallenge = &WebAuthNChallengeModel{
-		Challenge:          e.Challenge,
-		AllowedCrentialIDs: e.AllowedCrentialIDs,
-		UserVerification:   e.UserVerification,
-		RPID:               e.RPID,
+		Challenge:            e.Challenge,
+		AllowedCredentialIDs: allowedCredentialIDs,
+		AllowedTransports:    e.AllowedTransports,
+		UserVerification:     e.UserVerification,
+		RPID:                 e.RPID,
+		Expiration:           e.Expiration,
+	}
+	if wm.WebAuthNChallenges == nil {
+		wm.WebAuthNChallenges = make(map[string]*WebAuthNChallengeModel)
 	}
+	wm.WebAuthNChallenges[e.Challenge] = wm.WebAuthNChallenge
 }
 
 func (wm *SessionWriteModel) reduceWebAuthNChecked(e *session.WebAuthNCheckedEvent) {
-	wm.WebAuthNChallenge = nil
-	wm.WebAuthNCheckedAt = e.CheckedAt
-	wm.WebAuthNUserVerified = e.UserVerified
+	checkedAt := checkedAtOrCreationDate(e.CheckedAt, e)
+	challenge := wm.WebAuthNChallenge
+	if e.Challenge != "" {
+		challenge = wm.WebAuthNChallenges[e.Challenge]
+		delete(wm.WebAuthNChallenges, e.Challenge)
+	} else {
+		// events created before multiple challenges were supported do not reference the challenge
+		wm.WebAuthNChallenges = nil
+	}
+	if wm.WebAuthNChallenge == challenge || e.Challenge == "" {
+		wm.WebAuthNChallenge = nil
+	}
+	passwordless := challenge != nil &&
+		challenge.UserVerification == domain.UserVerificationRequirementRequired
+	// a later (weaker) check, e.g. U2F without user verification, must not step down a session,
+	// which was already checked passwordless: the flags are only cleared by the invalidation of the factor
+	wm.WebAuthNIsPasswordless = wm.WebAuthNIsPasswordless || passwordless
+	wm.WebAuthNUserVerified = wm.WebAuthNUserVerified || e.UserVerified
+	wm.WebAuthNCheckedAt = checkedAt
+	wm.WebAuthNUserPresent = e.UserPresent
+	wm.WebAuthNSignCount = e.SignCount
+	wm.WebAuthNAttestationFormat = e.AttestationFormat
+	wm.WebAuthNExtensionResults = e.ExtensionResults
+	factor := domain.UserAuthMethodTypeU2F
+	if passwordless && e.UserVerified {
+		factor = domain.UserAuthMethodTypePasswordless
+	}
+	wm.appendCheckHistory(factor, checkedAt, true)
+	wm.refreshIdleExpiration(checkedAt)
+}
+
+// checkedAtOrCreationDate returns the time of the check stored in the event.
+// Events created before the time was part of the payload fall back to their creation date.
+func checkedAtOrCreationDate(checkedAt time.Time, event eventstore.Event) time.Time {
+	if checkedAt.IsZero() {
+		return event.CreationDate()
+	}
+	return checkedAt
+}
+
+// setIdempotentCheck records the check of the factor, if it was made with an idempotency key
+func (wm *SessionWriteModel) setIdempotentCheck(factor domain.UserAuthMethodType, idempotencyKey string, checkedAt time.Time) {
+	if idempotencyKey == "" {
+		return
+	}
+	if wm.IdempotentChecks == nil {
+		wm.IdempotentChecks = make(map[domain.UserAuthMethodType]IdempotentCheck)
+	}
+	wm.IdempotentChecks[factor] = IdempotentCheck{
+		IdempotencyKey: idempotencyKey,
+		CheckedAt:      checkedAt,
+	}
+}
+
+// IsRetriedCheck returns true if the latest check of the factor was made with the same idempotency key
+// within a short window before the provided time, so a new check is a retry and must not be recorded again.
+func (wm *SessionWriteModel) IsRetriedCheck(factor domain.UserAuthMethodType, idempotencyKey string, now time.Time) bool {
+	if idempotencyKey == "" {
+		return false
+	}
+	check, ok := wm.IdempotentChecks[factor]
+	return ok && check.IdempotencyKey == idempotencyKey && now.Sub(check.CheckedAt) <= idempotentCheckWindow
+}
+
+// appendCheckHistory adds the check to the CheckHistory and removes the oldest entries exceeding the CheckHistoryLimit
+func (wm *SessionWriteModel) appendCheckHistory(factor domain.UserAuthMethodType, checkedAt time.Time, succeeded bool) {
+	limit := wm.CheckHistoryLimit
+	if limit <= 0 {
+		limit = defaultSessionCheckHistoryLimit
+	}
+	wm.CheckHistory = append(wm.CheckHistory, FactorCheck{
+		Factor:    factor,
+		CheckedAt: checkedAt,
+		Succeeded: succeeded,
+	})
+	if exceeding := len(wm.CheckHistory) - limit; exceeding > 0 {
+		wm.CheckHistory = append(wm.CheckHistory[:0], wm.CheckHistory[exceeding:]...)
+	}
 }
 
 func (wm *SessionWriteModel) reduceTOTPChecked(e *session.TOTPCheckedEvent) {
-	wm.TOTPCheckedAt = e.CheckedAt
+	checkedAt := checkedAtOrCreationDate(e.CheckedAt, e)
+	wm.TOTPCheckedAt = checkedAt
+	wm.TOTPDeviceID = e.DeviceID
+	if e.Step > wm.TOTPLastStep {
+		wm.TOTPLastStep = e.Step
+	}
+	wm.appendCheckHistory(domain.UserAuthMethodTypeTOTP, checkedAt, true)
+	wm.refreshIdleExpiration(checkedAt)
 }
 
+func (wm *SessionWriteModel) reduceRecoveryCodeChecked(e *session.RecoveryCodeCheckedEvent) {
+	checkedAt := checkedAtOrCreationDate(e.CheckedAt, e)
+	wm.RecoveryCodeCheckedAt = checkedAt
+	wm.appendCheckHistory(domain.UserAuthMethodTypeRecoveryCode, checkedAt, true)
+	wm.refreshIdleExpiration(checkedAt)
+}
+
+func (wm *SessionWriteModel) reduceClientCertChecked(e *session.ClientCertCheckedEvent) {
+	checkedAt := checkedAtOrCreationDate(e.CheckedAt, e)
+	wm.ClientCertCheckedAt = checkedAt
+	wm.ClientCertFingerprint = e.Fingerprint
+	wm.appendCheckHistory(domain.UserAuthMethodTypeClientCert, checkedAt, true)
+	wm.refreshIdleExpiration(checkedAt)
+}
+
+func (wm *SessionWriteModel) reduceOTPSMSChecked(e *session.OTPSMSCheckedEvent) {
+	checkedAt := checkedAtOrCreationDate(e.CheckedAt, e)
+	wm.OTPSMSCheckedAt = checkedAt
+	wm.OTPSMSPhoneSequence = e.PhoneSequence
+	wm.appendCheckHistory(domain.UserAuthMethodTypeOTPSMS, checkedAt, true)
+	wm.refreshIdleExpiration(checkedAt)
+}
+
+func (wm *SessionWriteModel) reduceOTPEmailChecked(e *session.OTPEmailCheckedEvent) {
+	checkedAt := checkedAtOrCreationDate(e.CheckedAt, e)
+	wm.OTPEmailCheckedAt = checkedAt
+	wm.appendCheckHistory(domain.UserAuthMethodTypeOTPEmail, checkedAt, true)
+	wm.refreshIdleExpiration(checkedAt)
+}
+
+// reduceTokenSet sets the new token of the session and records the previous one as revoked.
+// An empty token ID (see [Commands.RevokeSessionToken]) only revokes the current token.
 func (wm *SessionWriteModel) reduceTokenSet(e *session.TokenSetEvent) {
+	if wm.TokenID != "" {
+		wm.PreviousTokenID = wm.TokenID
+		wm.RevokedTokenIDs = append(wm.RevokedTokenIDs, wm.TokenID)
+	}
 	wm.TokenID = e.TokenID
 }
 
-func (wm *SessionWriteModel) reduceTerminate() {
+// reduceMetadataSet replaces the metadata by the one of the event.
+// The [SessionMetadataLimits] are validated by the commands before the event is pushed,
+// stored events must always be reduced, even if they exceed the (current) limits.
+func (wm *SessionWriteModel) reduceMetadataSet(e *session.MetadataSetEvent) {
+	wm.Metadata = make(map[string][]byte, len(e.Metadata))
+	for key, value := range e.Metadata {
+		wm.Metadata[key] = value
+	}
+	wm.MetadataExpirations = nil
+	for key, expiration := range e.Expirations {
+		if _, ok := wm.Metadata[key]; ok {
+			wm.setMetadataExpiration(key, expiration)
+		}
+	}
+}
+
+// reduceMetadataBulkSet merges the entries of the event into the metadata.
+// Like [SessionWriteModel.reduceMetadataSet], the limits are only validated by the commands creating the event.
+func (wm *SessionWriteModel) reduceMetadataBulkSet(e *session.MetadataBulkSetEvent) {
+	if wm.Metadata == nil {
+		wm.Metadata = make(map[string][]byte, len(e.Metadata))
+	}
+	for key, value := range e.Metadata {
+		wm.Metadata[key] = value
+		delete(wm.MetadataExpirations, key)
+	}
+}
+
+func (wm *SessionWriteModel) reduceMetadataRemoved(e *session.MetadataRemovedEvent) {
+	for _, key := range e.Keys {
+		wm.RemoveMetadata(key)
+	}
+}
+
+func (wm *SessionWriteModel) reduceLifetimeSet(e *session.LifetimeSetEvent) {
+	if e.Lifetime > 0 {
+		wm.Expiration = e.CreationDate().Add(e.Lifetime)
+	}
+	wm.IdleTimeout = e.IdleTimeout
+	wm.IdleExpiration = time.Time{}
+	wm.refreshIdleExpiration(e.CreationDate())
+}
+
+// refreshIdleExpiration moves the idle expiration based on the provided time of the last activity (e.g. a check)
+func (wm *SessionWriteModel) refreshIdleExpiration(lastActivity time.Time) {
+	if wm.IdleTimeout <= 0 {
+		return
+	}
+	if expiration := lastActivity.Add(wm.IdleTimeout); expiration.After(wm.IdleExpiration) {
+		wm.IdleExpiration = expiration
+	}
+}
+
+func (wm *SessionWriteModel) reduceForked(e *session.ForkedEvent) {
+	wm.ParentSessionID = e.ParentSessionID
+	wm.DelegatorUserID = e.DelegatorUserID
+	wm.InheritedAuthMethodTypes = e.InheritedAuthMethodTypes
+}
+
+func (wm *SessionWriteModel) reduceDeviceTrusted(e *session.DeviceTrustedEvent) {
+	wm.TrustedDevice = true
+	wm.TrustedDeviceExpiration = e.Expiration
+}
+
+// reduceFactorInvalidated removes the check of the factor, so it's no longer part of the [SessionWriteModel.AuthMethodTypes]
+func (wm *SessionWriteModel) reduceFactorInvalidated(e *session.FactorInvalidatedEvent) {
+	switch e.Factor {
+	case domain.UserAuthMethodTypePassword:
+		wm.PasswordCheckedAt = time.Time{}
+	case domain.UserAuthMethodTypeIDP:
+		wm.IntentCheckedAt = time.Time{}
+		wm.IntentIDPID = ""
+		wm.IntentProtocol = domain.IDPIntentProtocolUnspecified
+	case domain.UserAuthMethodTypeTOTP:
+		wm.TOTPCheckedAt = time.Time{}
+		wm.TOTPDeviceID = ""
+	case domain.UserAuthMethodTypeOTPSMS:
+		wm.OTPSMSCheckedAt = time.Time{}
+		wm.OTPSMSPhoneSequence = 0
+	case domain.UserAuthMethodTypeOTPEmail:
+		wm.OTPEmailCheckedAt = time.Time{}
+	case domain.UserAuthMethodTypeRecoveryCode:
+		wm.RecoveryCodeCheckedAt = time.Time{}
+	case domain.UserAuthMethodTypeClientCert:
+		wm.ClientCertCheckedAt = time.Time{}
+		wm.ClientCertFingerprint = ""
+	case domain.UserAuthMethodTypeU2F, domain.UserAuthMethodTypePasswordless:
+		// the WebAuthN check is either passwordless or U2F, only invalidate it if it's the one of the event
+		if wm.factorCheckedAt(e.Factor).IsZero() {
+			return
+		}
+		wm.WebAuthNCheckedAt = time.Time{}
+		wm.WebAuthNUserVerified = false
+		wm.WebAuthNUserPresent = false
+		wm.WebAuthNIsPasswordless = false
+	default:
+		return
+	}
+	wm.SecurityLevelDegraded = true
+}
+
+func (wm *SessionWriteModel) reduceLocked() {
+	if !wm.State.IsOpen() {
+		return
+	}
+	wm.State = domain.SessionStateLocked
+}
+
+// reduceUnlocked restores the state of the session before it was locked:
+// active if the user was already checked, pending otherwise
+func (wm *SessionWriteModel) reduceUnlocked() {
+	if wm.State != domain.SessionStateLocked {
+		return
+	}
+	wm.State = domain.SessionStatePending
+	if wm.UserID != "" {
+		wm.State = domain.SessionStateActive
+	}
+}
+
+// reduceActivity only moves the idle expiration, the [SessionWriteModel.AuthMethodTypes] stay untouched
+func (wm *SessionWriteModel) reduceActivity(e *session.ActivityEvent) {
+	wm.LastActivityAt = e.CreationDate()
+	wm.refreshIdleExpiration(e.CreationDate())
+}
+
+func (wm *SessionWriteModel) reduceTerminate(e *session.TerminateEvent) {
 	wm.State = domain.SessionStateTerminated
+	wm.TerminationReason = e.Reason
+}
+
+// SetMetadata sets the value for the key, if neither the key, the value,
+// nor the resulting metadata as a whole exceed the [SessionMetadataLimits].
+// Existing keys can always be updated, even if the maximum amount of entries is reached.
+func (wm *SessionWriteModel) SetMetadata(key string, value []byte) error {
+	if err := wm.MetadataLimits.validateEntry(key, value); err != nil {
+		return err
+	}
+	if _, exists := wm.Metadata[key]; !exists && len(wm.Metadata) >= wm.MetadataLimits.maxEntries() {
+		return caos_errs.ThrowInvalidArgument(nil, "COMMAND-Ohb3e", "Errors.Session.Metadata.TooManyEntries")
+	}
+	size := len(key) + len(value)
+	for k, v := range wm.Metadata {
+		if k != key {
+			size += len(k) + len(v)
+		}
+	}
+	if size > wm.MetadataLimits.maxTotalSize() {
+		return caos_errs.ThrowInvalidArgument(nil, "COMMAND-Gae4u", "Errors.Session.Metadata.TooLarge")
+	}
+	if wm.Metadata == nil {
+		wm.Metadata = make(map[string][]byte)
+	}
+	wm.Metadata[key] = value
+	// a value set without TTL does not expire
+	delete(wm.MetadataExpirations, key)
+	return nil
+}
+
+// SetMetadataWithExpiration sets the value for the key like [SessionWriteModel.SetMetadata],
+// but the key is treated as absent by [SessionWriteModel.GetMetadata] after the expiration.
+func (wm *SessionWriteModel) SetMetadataWithExpiration(key string, value []byte, expiration time.Time) error {
+	if err := wm.SetMetadata(key, value); err != nil {
+		return err
+	}
+	wm.setMetadataExpiration(key, expiration)
+	return nil
+}
+
+func (wm *SessionWriteModel) setMetadataExpiration(key string, expiration time.Time) {
+	if wm.MetadataExpirations == nil {
+		wm.MetadataExpirations = make(map[string]time.Time)
+	}
+	wm.MetadataExpirations[key] = expiration
+}
+
+// GetMetadata returns the value of the key and whether it's set.
+// Keys set with a TTL (see [SessionWriteModel.SetMetadataWithExpiration]) are treated as absent after their expiration.
+func (wm *SessionWriteModel) GetMetadata(key string, now time.Time) ([]byte, bool) {
+	value, ok := wm.Metadata[key]
+	if !ok {
+		return nil, false
+	}
+	if expiration, ok := wm.MetadataExpirations[key]; ok && now.After(expiration) {
+		return nil, false
+	}
+	return value, true
+}
+
+// SetMetadataBulk merges all entries into the metadata.
+// The metadata is only changed, if neither any entry, nor the resulting metadata as a whole exceed the [SessionMetadataLimits]
+func (wm *SessionWriteModel) SetMetadataBulk(metadata map[string][]byte) error {
+	merged := make(map[string][]byte, len(wm.Metadata)+len(metadata))
+	for key, value := range wm.Metadata {
+		merged[key] = value
+	}
+	for key, value := range metadata {
+		merged[key] = value
+	}
+	if err := wm.MetadataLimits.validate(merged); err != nil {
+		return err
+	}
+	wm.Metadata = merged
+	for key := range metadata {
+		delete(wm.MetadataExpirations, key)
+	}
+	return nil
+}
+
+// RemoveMetadata removes the key from the metadata, if present
+func (wm *SessionWriteModel) RemoveMetadata(key string) {
+	delete(wm.Metadata, key)
+	delete(wm.MetadataExpirations, key)
+}
+
+// ActiveWebAuthNChallenge returns the WebAuthN challenge of the session
+// and whether there is one (not checked yet)
+func (wm *SessionWriteModel) ActiveWebAuthNChallenge() (*WebAuthNChallengeModel, bool) {
+	return wm.WebAuthNChallenge, wm.WebAuthNChallenge != nil
+}
+
+// WebAuthNChallengeByID returns the not yet checked WebAuthN challenge of the session by its challenge
+func (wm *SessionWriteModel) WebAuthNChallengeByID(challenge string) (*WebAuthNChallengeModel, bool) {
+	webAuthNChallenge, ok := wm.WebAuthNChallenges[challenge]
+	return webAuthNChallenge, ok
+}
+
+// PasswordLocked returns true if the amount of failed password checks reached the provided maximum.
+// A maximum of zero (or less) means no limit.
+func (wm *SessionWriteModel) PasswordLocked(max int) bool {
+	return max > 0 && wm.PasswordCheckFailures >= max
+}
+
+// IntentIDP returns the id of the identity provider the user authenticated with (using an intent)
+func (wm *SessionWriteModel) IntentIDP() (idpID string, ok bool) {
+	if wm.IntentCheckedAt.IsZero() {
+		return "", false
+	}
+	return wm.IntentIDPID, true
+}
+
+// TokenRevoked returns true if the token was replaced by a newer one,
+// e.g. to detect the reuse of a rotated token.
+func (wm *SessionWriteModel) TokenRevoked(tokenID string) bool {
+	for _, revoked := range wm.RevokedTokenIDs {
+		if revoked == tokenID {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	// ErrSessionNotExisting is returned by [SessionWriteModel.CheckActive] if the session was never created
+	ErrSessionNotExisting = caos_errs.ThrowNotFound(nil, "COMMAND-Aezi1", "Errors.Session.NotExisting")
+	// ErrSessionTerminated is returned by [SessionWriteModel.CheckActive] if the session was terminated
+	ErrSessionTerminated = caos_errs.ThrowPreconditionFailed(nil, "COMMAND-ieZ3o", "Errors.Session.Terminated")
+	// ErrSessionExpired is returned by [SessionWriteModel.CheckActive] if the lifetime of the session has passed
+	ErrSessionExpired = caos_errs.ThrowPreconditionFailed(nil, "COMMAND-Pah7e", "Errors.Session.Expired")
+	// ErrSessionIdleExpired is returned by [SessionWriteModel.CheckActive] if the session was idle for longer than its idle timeout
+	ErrSessionIdleExpired = caos_errs.ThrowPreconditionFailed(nil, "COMMAND-Thu8a", "Errors.Session.IdleExpired")
+	// ErrSessionLocked is returned by [SessionWriteModel.CheckActive] if the session is locked
+	ErrSessionLocked = caos_errs.ThrowPreconditionFailed(nil, "COMMAND-Quo3a", "Errors.Session.Locked")
+	// ErrSessionPasswordLocked is returned by password checks if the maximum of failed password checks on the session is reached (see [SessionWriteModel.PasswordLocked])
+	ErrSessionPasswordLocked = caos_errs.ThrowPreconditionFailed(nil, "COMMAND-ohB4u", "Errors.Session.PasswordLocked")
+	// ErrUserPasswordLocked is returned by password checks if the maximum of failed password checks of the user
+	// across all sessions is reached (see [Commands.UserPasswordChecks])
+	ErrUserPasswordLocked = caos_errs.ThrowPreconditionFailed(nil, "COMMAND-Eiph7", "Errors.Session.UserPasswordLocked")
+	// ErrSessionAuthMethodNotAllowed is returned by [SessionWriteModel.CheckAuthMethodAllowed] if the factor must not be checked on the session
+	ErrSessionAuthMethodNotAllowed = caos_errs.ThrowPreconditionFailed(nil, "COMMAND-Ahd5o", "Errors.Session.AuthMethodNotAllowed")
+)
+
+// CheckAuthMethodAllowed returns [ErrSessionAuthMethodNotAllowed] if the session restricts the [SessionWriteModel.AllowedAuthMethods]
+// and the factor is not part of them.
+func (wm *SessionWriteModel) CheckAuthMethodAllowed(factor domain.UserAuthMethodType) error {
+	if len(wm.AllowedAuthMethods) == 0 {
+		return nil
+	}
+	for _, allowed := range wm.AllowedAuthMethods {
+		if allowed == factor {
+			return nil
+		}
+	}
+	return ErrSessionAuthMethodNotAllowed
+}
+
+// CheckActive returns an error if the session cannot be used at the provided time.
+// The returned errors can be distinguished using [errors.Is] with [ErrSessionNotExisting], [ErrSessionTerminated],
+// [ErrSessionLocked], [ErrSessionExpired] and [ErrSessionIdleExpired].
+// A pending session (without a checked user) can be used as well.
+func (wm *SessionWriteModel) CheckActive(now time.Time) error {
+	switch wm.State {
+	case domain.SessionStateActive, domain.SessionStatePending:
+	case domain.SessionStateTerminated:
+		return ErrSessionTerminated
+	case domain.SessionStateLocked:
+		return ErrSessionLocked
+	default:
+		return ErrSessionNotExisting
+	}
+	return wm.checkNotExpired(now)
+}
+
+// checkNotExpired returns [ErrSessionExpired] or [ErrSessionIdleExpired], if the session expired at the provided time,
+// e.g. to prevent issuing a token for it
+func (wm *SessionWriteModel) checkNotExpired(now time.Time) error {
+	if wm.IsExpired(now) {
+		return ErrSessionExpired
+	}
+	if wm.IsIdleExpired(now) {
+		return ErrSessionIdleExpired
+	}
+	return nil
+}
+
+// IsExpired returns true if the session was created with a lifetime, which has passed at the provided time.
+// An expired session is not terminated, so the [domain.SessionState] is not changed.
+func (wm *SessionWriteModel) IsExpired(now time.Time) bool {
+	return !wm.Expiration.IsZero() && now.After(wm.Expiration)
+}
+
+// RemainingLifetime returns the time left at the provided time until the session expires,
+// which is the earlier of the absolute expiration and the idle expiration.
+// Zero is returned if the session cannot be used (anymore), e.g. because it's terminated or expired (see [SessionWriteModel.CheckActive]).
+// If neither a lifetime nor an idle timeout is set, the session never expires and the maximum [time.Duration] is returned.
+func (wm *SessionWriteModel) RemainingLifetime(now time.Time) time.Duration {
+	if wm.CheckActive(now) != nil {
+		return 0
+	}
+	remaining := time.Duration(math.MaxInt64)
+	for _, expiration := range []time.Time{wm.Expiration, wm.IdleExpiration} {
+		if expiration.IsZero() {
+			continue
+		}
+		if left := expiration.Sub(now); left < remaining {
+			remaining = left
+		}
+	}
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// IsDeviceTrusted returns true if the device of the session was marked as trusted
+// and the trust has not expired at the provided time
+func (wm *SessionWriteModel) IsDeviceTrusted(now time.Time) bool {
+	return wm.TrustedDevice && (wm.TrustedDeviceExpiration.IsZero() || now.Before(wm.TrustedDeviceExpiration))
+}
+
+// IsIdleExpired returns true if an idle timeout is set for the session
+// and neither a check nor any other activity (see [session.ActivityEvent]) occurred within it up to the provided time
+func (wm *SessionWriteModel) IsIdleExpired(now time.Time) bool {
+	return !wm.IdleExpiration.IsZero() && now.After(wm.IdleExpiration)
 }
 
 // AuthenticationTime returns the time the user authenticated using the latest time of all checks
 func (wm *SessionWriteModel) AuthenticationTime() time.Time {
+	_, authTime := wm.LatestAuthentication()
+	return authTime
+}
+
+// AuthenticationTimeFor returns the latest time any of the provided factors was checked,
+// e.g. to compute the auth_time only over the factors relevant for the requested scope.
+// Without any factors, it's the same as [SessionWriteModel.AuthenticationTime].
+func (wm *SessionWriteModel) AuthenticationTimeFor(factors ...domain.UserAuthMethodType) time.Time {
+	if len(factors) == 0 {
+		return wm.AuthenticationTime()
+	}
 	var authTime time.Time
-	for _, check := range []time.Time{
-		wm.PasswordCheckedAt,
-		wm.WebAuthNCheckedAt,
-		wm.TOTPCheckedAt,
-		wm.IntentCheckedAt,
-		// TODO: add OTP (sms and email) check https://github.com/zitadel/zitadel/issues/6224
-	} {
-		if check.After(authTime) {
-			authTime = check
+	for _, factor := range factors {
+		if checkedAt := wm.factorCheckedAt(factor); checkedAt.After(authTime) {
+			authTime = checkedAt
+		}
+	}
+	return authTime
+}
+
+// AuthenticationTimeByEventOrder returns the time of the check, which was recorded last (by the order of the events),
+// instead of the latest time of all checks as [SessionWriteModel.AuthenticationTime] does.
+// This prevents a skewed clock of the node recording a check from shifting the authentication time.
+// Invalidated checks are not considered. If the order is not known (no CheckHistory), the [SessionWriteModel.AuthenticationTime] is returned.
+func (wm *SessionWriteModel) AuthenticationTimeByEventOrder() time.Time {
+	for i := len(wm.CheckHistory) - 1; i >= 0; i-- {
+		check := wm.CheckHistory[i]
+		if !check.Succeeded {
+			continue
+		}
+		if checkedAt := wm.factorCheckedAt(check.Factor); !checkedAt.IsZero() {
+			return checkedAt
+		}
+	}
+	return wm.AuthenticationTime()
+}
+
+// authMethodTypesByStrength lists the factors from the strongest to the weakest
+var authMethodTypesByStrength = []domain.UserAuthMethodType{
+	domain.UserAuthMethodTypePasswordless,
+	domain.UserAuthMethodTypeU2F,
+	domain.UserAuthMethodTypeClientCert,
+	domain.UserAuthMethodTypeTOTP,
+	domain.UserAuthMethodTypeOTPEmail,
+	domain.UserAuthMethodTypeOTPSMS,
+	domain.UserAuthMethodTypeRecoveryCode,
+	domain.UserAuthMethodTypeIDP,
+	domain.UserAuthMethodTypePassword,
+}
+
+// LatestAuthentication returns the factor of the latest check and its time.
+// If multiple factors were checked at the same time, the stronger one will be returned.
+func (wm *SessionWriteModel) LatestAuthentication() (domain.UserAuthMethodType, time.Time) {
+	var (
+		factor   domain.UserAuthMethodType
+		authTime time.Time
+	)
+	for _, authMethod := range authMethodTypesByStrength {
+		if checkedAt := wm.factorCheckedAt(authMethod); checkedAt.After(authTime) {
+			factor = authMethod
+			authTime = checkedAt
+		}
+	}
+	return factor, authTime
+}
+
+// FirstAuthentication returns the time of the earliest check of any factor
+// or the zero time if no factor was checked.
+// Together with [SessionWriteModel.AuthenticationTime] it spans the authentication of the session.
+func (wm *SessionWriteModel) FirstAuthentication() time.Time {
+	var authTime time.Time
+	for _, authMethod := range authMethodTypesByStrength {
+		checkedAt := wm.factorCheckedAt(authMethod)
+		if !checkedAt.IsZero() && (authTime.IsZero() || checkedAt.Before(authTime)) {
+			authTime = checkedAt
 		}
 	}
 	return authTime
 }
 
 // AuthMethodTypes returns a list of UserAuthMethodTypes based on succeeded checks
+// and the types inherited from the parent session (if forked).
+// The list is sorted by the numeric value of the types and free of duplicates,
+// so sessions with the same checks will always return an identical list.
 func (wm *SessionWriteModel) AuthMethodTypes() []domain.UserAuthMethodType {
-	types := make([]domain.UserAuthMethodType, 0, domain.UserAuthMethodTypeIDP)
+	return wm.appendAuthMethodTypes(make([]domain.UserAuthMethodType, 0, maxSessionAuthMethodTypes+len(wm.InheritedAuthMethodTypes)))
+}
+
+// maxSessionAuthMethodTypes is the number of auth method types a session can be checked with itself
+const maxSessionAuthMethodTypes = int(domain.UserAuthMethodTypeClientCert)
+
+// appendAuthMethodTypes appends the sorted [SessionWriteModel.AuthMethodTypes] to the provided slice
+// and therefore allows to reuse its memory
+func (wm *SessionWriteModel) appendAuthMethodTypes(types []domain.UserAuthMethodType) []domain.UserAuthMethodType {
+	start := len(types)
 	if !wm.PasswordCheckedAt.IsZero() {
 		types = append(types, domain.UserAuthMethodTypePassword)
 	}
 	if !wm.WebAuthNCheckedAt.IsZero() {
-		if wm.WebAuthNUserVerified {
+		// a user verified assertion (e.g. security key with PIN) made as second factor still counts as U2F
+		if wm.WebAuthNIsPasswordless && wm.WebAuthNUserVerified {
 			types = append(types, domain.UserAuthMethodTypePasswordless)
 		} else {
 			types = append(types, domain.UserAuthMethodTypeU2F)
@@ -187,14 +1543,603 @@ func (wm *SessionWriteModel) AuthMethodTypes() []domain.UserAuthMethodType {
 	if !wm.TOTPCheckedAt.IsZero() {
 		types = append(types, domain.UserAuthMethodTypeTOTP)
 	}
-	// TODO: add checks with https://github.com/zitadel/zitadel/issues/6224
-	/*
-		if !wm.TOTPFactor.OTPSMSCheckedAt.IsZero() {
-			types = append(types, domain.UserAuthMethodTypeOTPSMS)
+	if !wm.OTPSMSCheckedAt.IsZero() {
+		types = append(types, domain.UserAuthMethodTypeOTPSMS)
+	}
+	if !wm.OTPEmailCheckedAt.IsZero() {
+		types = append(types, domain.UserAuthMethodTypeOTPEmail)
+	}
+	if !wm.RecoveryCodeCheckedAt.IsZero() {
+		types = append(types, domain.UserAuthMethodTypeRecoveryCode)
+	}
+	if !wm.ClientCertCheckedAt.IsZero() {
+		types = append(types, domain.UserAuthMethodTypeClientCert)
+	}
+	types = append(types, wm.InheritedAuthMethodTypes...)
+	sorted := sortAuthMethodTypes(types[start:])
+	return types[:start+len(sorted)]
+}
+
+// SessionsAuthMethodTypes returns the [SessionWriteModel.AuthMethodTypes] of each of the (reduced) sessions,
+// e.g. for reports over many sessions.
+// All lists share a single allocation, instead of allocating one per session.
+func SessionsAuthMethodTypes(sessions []*SessionWriteModel) [][]domain.UserAuthMethodType {
+	size := 0
+	for _, wm := range sessions {
+		size += maxSessionAuthMethodTypes + len(wm.InheritedAuthMethodTypes)
+	}
+	buf := make([]domain.UserAuthMethodType, 0, size)
+	result := make([][]domain.UserAuthMethodType, len(sessions))
+	for i, wm := range sessions {
+		start := len(buf)
+		buf = wm.appendAuthMethodTypes(buf)
+		// limit the capacity, so appending to one of the lists does not overwrite the next one
+		result[i] = buf[start:len(buf):len(buf)]
+	}
+	return result
+}
+
+// AuthMethodChecks returns the time of the check for each satisfied [domain.UserAuthMethodType].
+// As in [SessionWriteModel.AuthMethodTypes], a WebAuthN check is either passwordless or U2F.
+// Auth methods inherited from a parent session are not contained, since they were not checked on this session.
+func (wm *SessionWriteModel) AuthMethodChecks() map[domain.UserAuthMethodType]time.Time {
+	checks := make(map[domain.UserAuthMethodType]time.Time)
+	for _, factor := range wm.AuthMethodTypes() {
+		if checkedAt := wm.factorCheckedAt(factor); !checkedAt.IsZero() {
+			checks[factor] = checkedAt
 		}
-		if !wm.TOTPFactor.OTPEmailCheckedAt.IsZero() {
-			types = append(types, domain.UserAuthMethodTypeOTPEmail)
+	}
+	return checks
+}
+
+// ActiveAuthMethodTypes returns the [domain.UserAuthMethodType]s of [SessionWriteModel.AuthMethodTypes],
+// which were checked within the maxAge before now.
+// Inherited auth methods are not contained, since the time of their check is not known.
+func (wm *SessionWriteModel) ActiveAuthMethodTypes(maxAge time.Duration, now time.Time) []domain.UserAuthMethodType {
+	types := make([]domain.UserAuthMethodType, 0, len(wm.AuthMethodTypes()))
+	for _, factor := range wm.AuthMethodTypes() {
+		if wm.FactorFreshWithin(factor, maxAge, now) {
+			types = append(types, factor)
 		}
-	*/
+	}
 	return types
 }
+
+// CompletedFactorCount returns the amount of distinct [domain.UserAuthMethodType]s reported by [SessionWriteModel.AuthMethodTypes],
+// without allocating the list.
+func (wm *SessionWriteModel) CompletedFactorCount() int {
+	var count int
+	// a WebAuthN check is counted once, either as U2F or as passwordless
+	for _, checkedAt := range [...]time.Time{
+		wm.PasswordCheckedAt,
+		wm.WebAuthNCheckedAt,
+		wm.IntentCheckedAt,
+		wm.TOTPCheckedAt,
+		wm.OTPSMSCheckedAt,
+		wm.OTPEmailCheckedAt,
+		wm.RecoveryCodeCheckedAt,
+		wm.ClientCertCheckedAt,
+	} {
+		if !checkedAt.IsZero() {
+			count++
+		}
+	}
+	for i, inherited := range wm.InheritedAuthMethodTypes {
+		if !wm.factorCheckedAt(inherited).IsZero() || containsAuthMethodType(wm.InheritedAuthMethodTypes[:i], inherited) {
+			continue
+		}
+		count++
+	}
+	return count
+}
+
+func containsAuthMethodType(types []domain.UserAuthMethodType, authMethodType domain.UserAuthMethodType) bool {
+	for _, t := range types {
+		if t == authMethodType {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateSignCount checks the signature counter of the latest WebAuthN check against the previously known counter
+// of the authenticator. A counter, which did not increase, indicates a cloned authenticator.
+// Authenticators not supporting the counter always report zero, which is therefore allowed if both are zero.
+func (wm *SessionWriteModel) ValidateSignCount(previous uint32) error {
+	if wm.WebAuthNSignCount == 0 && previous == 0 {
+		return nil
+	}
+	if wm.WebAuthNSignCount <= previous {
+		return caos_errs.ThrowPreconditionFailed(nil, "COMMAND-Eeth4", "Errors.Session.WebAuthN.SignCountInvalid")
+	}
+	return nil
+}
+
+// OTPSMSCheckedFor returns true if an OTP SMS was checked, which was sent to the current phone number of the user,
+// identified by the provided [HumanPhoneWriteModel.PhoneChangedSequence].
+// Checks stored without the sequence (made before it was recorded) are considered valid for any phone.
+func (wm *SessionWriteModel) OTPSMSCheckedFor(phoneChangedSequence uint64) bool {
+	if wm.OTPSMSCheckedAt.IsZero() {
+		return false
+	}
+	return wm.OTPSMSPhoneSequence == 0 || wm.OTPSMSPhoneSequence == phoneChangedSequence
+}
+
+// AuthStateEqual returns true if the other session has the same authentication state and would therefore result
+// in the same token (e.g. to reuse a cached token response): the same user, auth method types
+// and authentication time (at second granularity, as used in the tokens).
+func (wm *SessionWriteModel) AuthStateEqual(other *SessionWriteModel) bool {
+	if other == nil || wm.UserID != other.UserID {
+		return false
+	}
+	if !wm.AuthenticationTime().Truncate(time.Second).Equal(other.AuthenticationTime().Truncate(time.Second)) {
+		return false
+	}
+	types, otherTypes := wm.AuthMethodTypes(), other.AuthMethodTypes()
+	if len(types) != len(otherTypes) {
+		return false
+	}
+	// both lists are sorted and free of duplicates
+	for i, t := range types {
+		if t != otherTypes[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// AuthMethodTypesForPhone returns the [SessionWriteModel.AuthMethodTypes],
+// but without the OTP SMS, if it was sent to another phone number than the current one (see [SessionWriteModel.OTPSMSCheckedFor]),
+// e.g. because the user changed the phone number since the check.
+func (wm *SessionWriteModel) AuthMethodTypesForPhone(phoneChangedSequence uint64) []domain.UserAuthMethodType {
+	types := wm.AuthMethodTypes()
+	if wm.OTPSMSCheckedAt.IsZero() || wm.OTPSMSCheckedFor(phoneChangedSequence) {
+		return types
+	}
+	valid := types[:0]
+	for _, t := range types {
+		if t != domain.UserAuthMethodTypeOTPSMS {
+			valid = append(valid, t)
+		}
+	}
+	return valid
+}
+
+// AuthenticationAssuranceLevel returns the [domain.AuthLevel] reached by the succeeded checks.
+func (wm *SessionWriteModel) AuthenticationAssuranceLevel() domain.AuthLevel {
+	if !wm.WebAuthNCheckedAt.IsZero() && wm.WebAuthNIsPasswordless && wm.WebAuthNUserVerified {
+		return domain.AuthLevel3
+	}
+	knowledge := !wm.PasswordCheckedAt.IsZero()
+	possession := wm.HasPossessionFactor()
+	switch {
+	case knowledge && possession:
+		return domain.AuthLevel2
+	case knowledge, possession, !wm.IntentCheckedAt.IsZero():
+		return domain.AuthLevel1
+	default:
+		return domain.AuthLevelUnspecified
+	}
+}
+
+// TokenContext returns the user, the [SessionWriteModel.AuthenticationTime], the [SessionWriteModel.AuthMethodTypes]
+// and the [SessionWriteModel.AuthenticationAssuranceLevel] of the session at once, as required to issue tokens.
+func (wm *SessionWriteModel) TokenContext() domain.SessionTokenContext {
+	return domain.SessionTokenContext{
+		UserID:          wm.UserID,
+		AuthTime:        wm.AuthenticationTime(),
+		AuthMethodTypes: wm.AuthMethodTypes(),
+		AuthLevel:       wm.AuthenticationAssuranceLevel(),
+	}
+}
+
+// ValidateTokenClaims returns an error if the claimed [domain.AuthLevel] (e.g. of an issued token)
+// exceeds the [SessionWriteModel.AuthenticationAssuranceLevel]. Claiming a lower level is allowed.
+func (wm *SessionWriteModel) ValidateTokenClaims(claimedLevel domain.AuthLevel) error {
+	if claimedLevel > wm.AuthenticationAssuranceLevel() {
+		return caos_errs.ThrowPreconditionFailed(nil, "COMMAND-Ki8ah", "Errors.Session.Token.AuthLevelExceeded")
+	}
+	return nil
+}
+
+// CanIssueRefreshToken returns true if the session is active and reached the minLevel
+// with an authentication made within the maxAge before now (a maxAge of zero disables the freshness check).
+// Otherwise, the returned error describes the reason.
+func (wm *SessionWriteModel) CanIssueRefreshToken(minLevel domain.AuthLevel, maxAge time.Duration, now time.Time) (bool, error) {
+	if err := wm.CheckActive(now); err != nil {
+		return false, err
+	}
+	if wm.AuthenticationAssuranceLevel() < minLevel {
+		return false, caos_errs.ThrowPreconditionFailed(nil, "COMMAND-Iek4a", "Errors.Session.Token.AuthLevelInsufficient")
+	}
+	if maxAge > 0 {
+		authTime := wm.AuthenticationTime()
+		if authTime.IsZero() || authTime.Before(now.Add(-maxAge)) {
+			return false, caos_errs.ThrowPreconditionFailed(nil, "COMMAND-Va4ae", "Errors.Session.Token.AuthenticationTooOld")
+		}
+	}
+	return true, nil
+}
+
+// HasKnowledgeFactor returns true if the user had to enter a secret:
+// the password or a one-time code (TOTP, OTP SMS or OTP Email).
+// A session authenticated only by passwordless (hardware) authenticators has no knowledge factor,
+// since the user verification (e.g. PIN or biometrics) is done on the authenticator itself.
+func (wm *SessionWriteModel) HasKnowledgeFactor() bool {
+	return !wm.PasswordCheckedAt.IsZero() ||
+		!wm.TOTPCheckedAt.IsZero() ||
+		!wm.OTPSMSCheckedAt.IsZero() ||
+		!wm.OTPEmailCheckedAt.IsZero()
+}
+
+// FactorEverChecked returns true if the factor was successfully checked on the session,
+// even if the check was invalidated since (as long as it's still part of the CheckHistory).
+// A WebAuthN check is either passwordless or U2F, depending on the challenge and the user verification.
+// Auth methods inherited from a parent session are not considered, since they were not checked on this session.
+func (wm *SessionWriteModel) FactorEverChecked(factor domain.UserAuthMethodType) bool {
+	if !wm.factorCheckedAt(factor).IsZero() {
+		return true
+	}
+	for _, check := range wm.CheckHistory {
+		if check.Succeeded && check.Factor == factor {
+			return true
+		}
+	}
+	return false
+}
+
+// WebAuthNResidentKey returns the resident key (client-side discoverable credential) property
+// reported by the credProps extension on the latest WebAuthN check.
+// The second return value is false, if the property was not reported.
+func (wm *SessionWriteModel) WebAuthNResidentKey() (residentKey bool, ok bool) {
+	credProps, ok := wm.WebAuthNExtensionResults["credProps"].(map[string]interface{})
+	if !ok {
+		return false, false
+	}
+	residentKey, ok = credProps["rk"].(bool)
+	return residentKey, ok
+}
+
+// HasPossessionFactor returns true if the possession of an authenticator or device was proven
+// by a WebAuthN check (U2F or passwordless), a one-time code (TOTP, OTP SMS, OTP Email or recovery code)
+// or a client certificate.
+func (wm *SessionWriteModel) HasPossessionFactor() bool {
+	return !wm.WebAuthNCheckedAt.IsZero() ||
+		!wm.TOTPCheckedAt.IsZero() ||
+		!wm.OTPSMSCheckedAt.IsZero() ||
+		!wm.OTPEmailCheckedAt.IsZero() ||
+		!wm.RecoveryCodeCheckedAt.IsZero() ||
+		!wm.ClientCertCheckedAt.IsZero()
+}
+
+// AuthFactorCategories returns the distinct [domain.AuthFactorCategory]s of the [SessionWriteModel.AuthMethodTypes] (in ascending order).
+func (wm *SessionWriteModel) AuthFactorCategories() []domain.AuthFactorCategory {
+	var categories []domain.AuthFactorCategory
+	for _, authMethod := range wm.AuthMethodTypes() {
+		for _, category := range authMethod.FactorCategories() {
+			if !containsAuthFactorCategory(categories, category) {
+				categories = append(categories, category)
+			}
+		}
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		return categories[i] < categories[j]
+	})
+	return categories
+}
+
+func containsAuthFactorCategory(categories []domain.AuthFactorCategory, category domain.AuthFactorCategory) bool {
+	for _, c := range categories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+// IsMFACompleted returns true if the session was authenticated with multiple factors,
+// meaning a knowledge (password) and a possession factor (e.g. TOTP or U2F) were checked
+// or a passwordless check was made, which is multi-factor by itself.
+// Multiple factors of the same category (e.g. TOTP and OTP SMS) are not sufficient.
+func (wm *SessionWriteModel) IsMFACompleted() bool {
+	return wm.AuthenticationAssuranceLevel() >= domain.AuthLevel2
+}
+
+// SatisfiesACR returns true if the succeeded checks of the session fulfill the requirements of the provided acr value.
+// Unknown acr values can never be satisfied, whereas no (empty) acr value is always satisfied.
+func (wm *SessionWriteModel) SatisfiesACR(acr string) bool {
+	if acr == "" {
+		return true
+	}
+	level, ok := domain.ACRAuthLevel(acr)
+	if !ok {
+		return false
+	}
+	return wm.AuthenticationAssuranceLevel() >= level
+}
+
+// ScopeUpgradeRequiresReauth returns true if any of the requested scopes, which are not already part of the current ones,
+// requires a higher [domain.AuthLevel] (see [SessionWriteModel.ScopeAuthLevels]) than the session currently reaches.
+func (wm *SessionWriteModel) ScopeUpgradeRequiresReauth(current, requested []string) bool {
+	level := wm.AuthenticationAssuranceLevel()
+requested:
+	for _, scope := range requested {
+		for _, granted := range current {
+			if scope == granted {
+				continue requested
+			}
+		}
+		if wm.ScopeAuthLevels[scope] > level {
+			return true
+		}
+	}
+	return false
+}
+
+// sortAuthMethodTypes sorts the types by their numeric value and removes duplicates in place.
+// The lists are short, so an insertion sort is used, which (unlike [sort.Slice]) doesn't allocate.
+func sortAuthMethodTypes(types []domain.UserAuthMethodType) []domain.UserAuthMethodType {
+	for i := 1; i < len(types); i++ {
+		for j := i; j > 0 && types[j] < types[j-1]; j-- {
+			types[j], types[j-1] = types[j-1], types[j]
+		}
+	}
+	unique := types[:0]
+	for i, t := range types {
+		if i > 0 && t == types[i-1] {
+			continue
+		}
+		unique = append(unique, t)
+	}
+	return unique
+}
+
+// MissingFactors returns the subset of the required factors, which have not been checked (yet).
+// A passwordless check satisfies a required password as well as any required second factor,
+// since it's considered multi-factor by itself.
+func (wm *SessionWriteModel) MissingFactors(required []domain.UserAuthMethodType) []domain.UserAuthMethodType {
+	checked := make(map[domain.UserAuthMethodType]bool, len(required))
+	for _, authMethod := range wm.AuthMethodTypes() {
+		checked[authMethod] = true
+	}
+	var missing []domain.UserAuthMethodType
+	for _, factor := range required {
+		if checked[factor] || (checked[domain.UserAuthMethodTypePasswordless] && satisfiedByPasswordless(factor)) {
+			continue
+		}
+		missing = append(missing, factor)
+	}
+	return missing
+}
+
+func satisfiedByPasswordless(factor domain.UserAuthMethodType) bool {
+	switch factor {
+	case domain.UserAuthMethodTypePassword,
+		domain.UserAuthMethodTypeU2F,
+		domain.UserAuthMethodTypeTOTP,
+		domain.UserAuthMethodTypeOTPSMS,
+		domain.UserAuthMethodTypeOTPEmail:
+		return true
+	default:
+		return false
+	}
+}
+
+// FactorFreshWithin returns true if the factor was checked within the maxAge before now,
+// e.g. to require a recent second factor check for a sensitive action (step-up).
+// Unlike [SessionWriteModel.MissingFactors], only the check of the specific factor is considered.
+func (wm *SessionWriteModel) FactorFreshWithin(factor domain.UserAuthMethodType, maxAge time.Duration, now time.Time) bool {
+	checkedAt := wm.factorCheckedAt(factor)
+	if checkedAt.IsZero() {
+		return false
+	}
+	return !checkedAt.Before(now.Add(-maxAge))
+}
+
+// PasswordReentryRequired returns true if the password was never checked or the check is older than maxAge,
+// regardless of any other (fresh) factor.
+func (wm *SessionWriteModel) PasswordReentryRequired(maxAge time.Duration, now time.Time) bool {
+	return !wm.FactorFreshWithin(domain.UserAuthMethodTypePassword, maxAge, now)
+}
+
+// UserCheckStale returns true if the user was never checked or the check is older than maxAge.
+// Unlike the freshness of a factor, it's about the identification of the user (e.g. the entered username),
+// which must be confirmed again on shared devices, even if the factors are still fresh.
+func (wm *SessionWriteModel) UserCheckStale(maxAge time.Duration, now time.Time) bool {
+	if wm.UserCheckedAt.IsZero() {
+		return true
+	}
+	return wm.UserCheckedAt.Before(now.Add(-maxAge))
+}
+
+// factorCheckedAt returns the time of the (latest) check of the factor
+// or the zero time if the factor was not checked.
+func (wm *SessionWriteModel) factorCheckedAt(factor domain.UserAuthMethodType) time.Time {
+	switch factor {
+	case domain.UserAuthMethodTypePassword:
+		return wm.PasswordCheckedAt
+	case domain.UserAuthMethodTypeIDP:
+		return wm.IntentCheckedAt
+	case domain.UserAuthMethodTypeTOTP:
+		return wm.TOTPCheckedAt
+	case domain.UserAuthMethodTypeOTPSMS:
+		return wm.OTPSMSCheckedAt
+	case domain.UserAuthMethodTypeOTPEmail:
+		return wm.OTPEmailCheckedAt
+	case domain.UserAuthMethodTypeRecoveryCode:
+		return wm.RecoveryCodeCheckedAt
+	case domain.UserAuthMethodTypeClientCert:
+		return wm.ClientCertCheckedAt
+	case domain.UserAuthMethodTypePasswordless:
+		if wm.WebAuthNIsPasswordless && wm.WebAuthNUserVerified {
+			return wm.WebAuthNCheckedAt
+		}
+	case domain.UserAuthMethodTypeU2F:
+		if !wm.WebAuthNIsPasswordless || !wm.WebAuthNUserVerified {
+			return wm.WebAuthNCheckedAt
+		}
+	}
+	return time.Time{}
+}
+
+// SessionSnapshot is a plain representation of the state of a [SessionWriteModel],
+// which can be stored (e.g. in projections) independent of the internal field layout of the write model.
+type SessionSnapshot struct {
+	ID                     string                      `json:"id"`
+	ResourceOwner          string                      `json:"resourceOwner"`
+	Sequence               uint64                      `json:"sequence"`
+	ChangeDate             time.Time                   `json:"changeDate"`
+	State                  domain.SessionState         `json:"state"`
+	UserID                 string                      `json:"userID,omitempty"`
+	UserAgentFingerprintID string                      `json:"userAgentFingerprintID,omitempty"`
+	CreatedFromRequestID   string                      `json:"createdFromRequestID,omitempty"`
+	ClientID               string                      `json:"clientID,omitempty"`
+	UserCheckedAt          time.Time                   `json:"userCheckedAt,omitempty"`
+	PasswordCheckedAt      time.Time                   `json:"passwordCheckedAt,omitempty"`
+	IntentCheckedAt        time.Time                   `json:"intentCheckedAt,omitempty"`
+	IntentIDPID            string                      `json:"intentIDPID,omitempty"`
+	WebAuthNCheckedAt      time.Time                   `json:"webAuthNCheckedAt,omitempty"`
+	WebAuthNUserVerified   bool                        `json:"webAuthNUserVerified,omitempty"`
+	WebAuthNIsPasswordless bool                        `json:"webAuthNIsPasswordless,omitempty"`
+	TOTPCheckedAt          time.Time                   `json:"totpCheckedAt,omitempty"`
+	RecoveryCodeCheckedAt  time.Time                   `json:"recoveryCodeCheckedAt,omitempty"`
+	ClientCertCheckedAt    time.Time                   `json:"clientCertCheckedAt,omitempty"`
+	OTPSMSCheckedAt        time.Time                   `json:"otpSMSCheckedAt,omitempty"`
+	OTPEmailCheckedAt      time.Time                   `json:"otpEmailCheckedAt,omitempty"`
+	AuthMethodTypes        []domain.UserAuthMethodType `json:"authMethodTypes,omitempty"`
+}
+
+// Snapshot returns the current state of the write model as [SessionSnapshot].
+func (wm *SessionWriteModel) Snapshot() *SessionSnapshot {
+	return &SessionSnapshot{
+		ID:                     wm.AggregateID,
+		ResourceOwner:          wm.ResourceOwner,
+		Sequence:               wm.ProcessedSequence,
+		ChangeDate:             wm.ChangeDate,
+		State:                  wm.State,
+		UserID:                 wm.UserID,
+		UserAgentFingerprintID: wm.UserAgentFingerprintID,
+		CreatedFromRequestID:   wm.CreatedFromRequestID,
+		ClientID:               wm.ClientID,
+		UserCheckedAt:          wm.UserCheckedAt,
+		PasswordCheckedAt:      wm.PasswordCheckedAt,
+		IntentCheckedAt:        wm.IntentCheckedAt,
+		IntentIDPID:            wm.IntentIDPID,
+		WebAuthNCheckedAt:      wm.WebAuthNCheckedAt,
+		WebAuthNUserVerified:   wm.WebAuthNUserVerified,
+		WebAuthNIsPasswordless: wm.WebAuthNIsPasswordless,
+		TOTPCheckedAt:          wm.TOTPCheckedAt,
+		RecoveryCodeCheckedAt:  wm.RecoveryCodeCheckedAt,
+		ClientCertCheckedAt:    wm.ClientCertCheckedAt,
+		OTPSMSCheckedAt:        wm.OTPSMSCheckedAt,
+		OTPEmailCheckedAt:      wm.OTPEmailCheckedAt,
+		AuthMethodTypes:        wm.AuthMethodTypes(),
+	}
+}
+
+// LoadSnapshot creates a [SessionWriteModel] from a [SessionSnapshot].
+// The auth method types of the snapshot are not loaded, since they're derived from the checks.
+func LoadSnapshot(snapshot *SessionSnapshot) *SessionWriteModel {
+	wm := NewSessionWriteModel(snapshot.ID, snapshot.ResourceOwner)
+	wm.ProcessedSequence = snapshot.Sequence
+	wm.ChangeDate = snapshot.ChangeDate
+	wm.State = snapshot.State
+	wm.UserID = snapshot.UserID
+	wm.UserAgentFingerprintID = snapshot.UserAgentFingerprintID
+	wm.CreatedFromRequestID = snapshot.CreatedFromRequestID
+	wm.ClientID = snapshot.ClientID
+	wm.UserCheckedAt = snapshot.UserCheckedAt
+	wm.PasswordCheckedAt = snapshot.PasswordCheckedAt
+	wm.IntentCheckedAt = snapshot.IntentCheckedAt
+	wm.IntentIDPID = snapshot.IntentIDPID
+	wm.WebAuthNCheckedAt = snapshot.WebAuthNCheckedAt
+	wm.WebAuthNUserVerified = snapshot.WebAuthNUserVerified
+	wm.WebAuthNIsPasswordless = snapshot.WebAuthNIsPasswordless
+	wm.TOTPCheckedAt = snapshot.TOTPCheckedAt
+	wm.RecoveryCodeCheckedAt = snapshot.RecoveryCodeCheckedAt
+	wm.ClientCertCheckedAt = snapshot.ClientCertCheckedAt
+	wm.OTPSMSCheckedAt = snapshot.OTPSMSCheckedAt
+	wm.OTPEmailCheckedAt = snapshot.OTPEmailCheckedAt
+	return wm
+}
+
+// sessionWriteModelJSON is the stable JSON representation of a [SessionWriteModel]
+type sessionWriteModelJSON struct {
+	ID                     string                        `json:"id"`
+	ResourceOwner          string                        `json:"resourceOwner"`
+	Sequence               uint64                        `json:"sequence"`
+	ChangeDate             string                        `json:"changeDate,omitempty"`
+	State                  domain.SessionState           `json:"state"`
+	TerminationReason      domain.SessionTerminationType `json:"terminationReason,omitempty"`
+	TokenID                string                        `json:"tokenID,omitempty"`
+	UserID                 string                        `json:"userID,omitempty"`
+	UserAgentFingerprintID string                        `json:"userAgentFingerprintID,omitempty"`
+	CreatedFromRequestID   string                        `json:"createdFromRequestID,omitempty"`
+	ClientID               string                        `json:"clientID,omitempty"`
+	UserCheckedAt          string                        `json:"userCheckedAt,omitempty"`
+	PasswordCheckedAt      string                        `json:"passwordCheckedAt,omitempty"`
+	PasswordCheckFailures  int                           `json:"passwordCheckFailures,omitempty"`
+	IntentCheckedAt        string                        `json:"intentCheckedAt,omitempty"`
+	IntentIDPID            string                        `json:"intentIDPID,omitempty"`
+	WebAuthNCheckedAt      string                        `json:"webAuthNCheckedAt,omitempty"`
+	WebAuthNUserVerified   bool                          `json:"webAuthNUserVerified,omitempty"`
+	WebAuthNIsPasswordless bool                          `json:"webAuthNIsPasswordless,omitempty"`
+	TOTPCheckedAt          string                        `json:"totpCheckedAt,omitempty"`
+	RecoveryCodeCheckedAt  string                        `json:"recoveryCodeCheckedAt,omitempty"`
+	ClientCertCheckedAt    string                        `json:"clientCertCheckedAt,omitempty"`
+	OTPSMSCheckedAt        string                        `json:"otpSMSCheckedAt,omitempty"`
+	OTPEmailCheckedAt      string                        `json:"otpEmailCheckedAt,omitempty"`
+	Expiration             string                        `json:"expiration,omitempty"`
+	IdleTimeout            string                        `json:"idleTimeout,omitempty"`
+	IdleExpiration         string                        `json:"idleExpiration,omitempty"`
+	LastActivityAt         string                        `json:"lastActivityAt,omitempty"`
+	Metadata               map[string][]byte             `json:"metadata,omitempty"`
+	AuthMethodTypes        []domain.UserAuthMethodType   `json:"authMethodTypes"`
+}
+
+// MarshalJSON implements [json.Marshaler].
+// Timestamps are formatted as RFC3339 (and omitted if not set), the internal state (e.g. events) is left out.
+func (wm *SessionWriteModel) MarshalJSON() ([]byte, error) {
+	state := sessionWriteModelJSON{
+		ID:                     wm.AggregateID,
+		ResourceOwner:          wm.ResourceOwner,
+		Sequence:               wm.ProcessedSequence,
+		ChangeDate:             formatRFC3339(wm.ChangeDate),
+		State:                  wm.State,
+		TerminationReason:      wm.TerminationReason,
+		TokenID:                wm.TokenID,
+		UserID:                 wm.UserID,
+		UserAgentFingerprintID: wm.UserAgentFingerprintID,
+		CreatedFromRequestID:   wm.CreatedFromRequestID,
+		ClientID:               wm.ClientID,
+		UserCheckedAt:          formatRFC3339(wm.UserCheckedAt),
+		PasswordCheckedAt:      formatRFC3339(wm.PasswordCheckedAt),
+		PasswordCheckFailures:  wm.PasswordCheckFailures,
+		IntentCheckedAt:        formatRFC3339(wm.IntentCheckedAt),
+		IntentIDPID:            wm.IntentIDPID,
+		WebAuthNCheckedAt:      formatRFC3339(wm.WebAuthNCheckedAt),
+		WebAuthNUserVerified:   wm.WebAuthNUserVerified,
+		WebAuthNIsPasswordless: wm.WebAuthNIsPasswordless,
+		TOTPCheckedAt:          formatRFC3339(wm.TOTPCheckedAt),
+		RecoveryCodeCheckedAt:  formatRFC3339(wm.RecoveryCodeCheckedAt),
+		ClientCertCheckedAt:    formatRFC3339(wm.ClientCertCheckedAt),
+		OTPSMSCheckedAt:        formatRFC3339(wm.OTPSMSCheckedAt),
+		OTPEmailCheckedAt:      formatRFC3339(wm.OTPEmailCheckedAt),
+		Expiration:             formatRFC3339(wm.Expiration),
+		IdleExpiration:         formatRFC3339(wm.IdleExpiration),
+		LastActivityAt:         formatRFC3339(wm.LastActivityAt),
+		Metadata:               wm.Metadata,
+		AuthMethodTypes:        wm.AuthMethodTypes(),
+	}
+	if wm.IdleTimeout > 0 {
+		state.IdleTimeout = wm.IdleTimeout.String()
+	}
+	return json.Marshal(state)
+}
+
+func formatRFC3339(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
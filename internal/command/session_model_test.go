@@ -1,20 +1,37 @@
 package command
 
 import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/zitadel/zitadel/internal/domain"
+	caos_errs "github.com/zitadel/zitadel/internal/errors"
+	"github.com/zitadel/zitadel/internal/eventstore"
+	"github.com/zitadel/zitadel/internal/eventstore/repository"
+	"github.com/zitadel/zitadel/internal/repository/session"
+	"github.com/zitadel/zitadel/internal/repository/user"
 )
 
 func TestSessionWriteModel_AuthMethodTypes(t *testing.T) {
 	type fields struct {
-		PasswordCheckedAt    time.Time
-		IntentCheckedAt      time.Time
-		WebAuthNCheckedAt    time.Time
-		WebAuthNUserVerified bool
+		PasswordCheckedAt      time.Time
+		IntentCheckedAt        time.Time
+		WebAuthNCheckedAt      time.Time
+		WebAuthNUserVerified   bool
+		WebAuthNIsPasswordless bool
+		OTPSMSCheckedAt        time.Time
+		OTPEmailCheckedAt      time.Time
+		TOTPCheckedAt          time.Time
+		RecoveryCodeCheckedAt  time.Time
 	}
 	tests := []struct {
 		name   string
@@ -33,13 +50,36 @@ func TestSessionWriteModel_AuthMethodTypes(t *testing.T) {
 		{
 			name: "passwordless",
 			fields: fields{
-				WebAuthNCheckedAt:    testNow,
-				WebAuthNUserVerified: true,
+				WebAuthNCheckedAt:      testNow,
+				WebAuthNUserVerified:   true,
+				WebAuthNIsPasswordless: true,
 			},
 			want: []domain.UserAuthMethodType{
 				domain.UserAuthMethodTypePasswordless,
 			},
 		},
+		{
+			name: "passwordless challenge, not user verified",
+			fields: fields{
+				WebAuthNCheckedAt:      testNow,
+				WebAuthNUserVerified:   false,
+				WebAuthNIsPasswordless: true,
+			},
+			want: []domain.UserAuthMethodType{
+				domain.UserAuthMethodTypeU2F,
+			},
+		},
+		{
+			name: "u2f, user verified",
+			fields: fields{
+				WebAuthNCheckedAt:      testNow,
+				WebAuthNUserVerified:   true,
+				WebAuthNIsPasswordless: false,
+			},
+			want: []domain.UserAuthMethodType{
+				domain.UserAuthMethodTypeU2F,
+			},
+		},
 		{
 			name: "u2f",
 			fields: fields{
@@ -59,17 +99,3384 @@ func TestSessionWriteModel_AuthMethodTypes(t *testing.T) {
 				domain.UserAuthMethodTypeIDP,
 			},
 		},
+		{
+			name: "otp sms",
+			fields: fields{
+				OTPSMSCheckedAt: testNow,
+			},
+			want: []domain.UserAuthMethodType{
+				domain.UserAuthMethodTypeOTPSMS,
+			},
+		},
+		{
+			name: "otp email",
+			fields: fields{
+				OTPEmailCheckedAt: testNow,
+			},
+			want: []domain.UserAuthMethodType{
+				domain.UserAuthMethodTypeOTPEmail,
+			},
+		},
+		{
+			name: "recovery code",
+			fields: fields{
+				RecoveryCodeCheckedAt: testNow,
+			},
+			want: []domain.UserAuthMethodType{
+				domain.UserAuthMethodTypeRecoveryCode,
+			},
+		},
+		{
+			name: "password and totp, sorted",
+			fields: fields{
+				PasswordCheckedAt: testNow,
+				TOTPCheckedAt:     testNow,
+			},
+			want: []domain.UserAuthMethodType{
+				domain.UserAuthMethodTypeTOTP,
+				domain.UserAuthMethodTypePassword,
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			wm := &SessionWriteModel{
-				PasswordCheckedAt:    tt.fields.PasswordCheckedAt,
-				IntentCheckedAt:      tt.fields.IntentCheckedAt,
-				WebAuthNCheckedAt:    tt.fields.WebAuthNCheckedAt,
-				WebAuthNUserVerified: tt.fields.WebAuthNUserVerified,
+				PasswordCheckedAt:      tt.fields.PasswordCheckedAt,
+				IntentCheckedAt:        tt.fields.IntentCheckedAt,
+				WebAuthNCheckedAt:      tt.fields.WebAuthNCheckedAt,
+				WebAuthNUserVerified:   tt.fields.WebAuthNUserVerified,
+				WebAuthNIsPasswordless: tt.fields.WebAuthNIsPasswordless,
+				OTPSMSCheckedAt:        tt.fields.OTPSMSCheckedAt,
+				OTPEmailCheckedAt:      tt.fields.OTPEmailCheckedAt,
+				TOTPCheckedAt:          tt.fields.TOTPCheckedAt,
+				RecoveryCodeCheckedAt:  tt.fields.RecoveryCodeCheckedAt,
 			}
 			got := wm.AuthMethodTypes()
 			assert.Equal(t, got, tt.want)
 		})
 	}
 }
+
+func Test_sortAuthMethodTypes(t *testing.T) {
+	got := sortAuthMethodTypes([]domain.UserAuthMethodType{
+		domain.UserAuthMethodTypePassword,
+		domain.UserAuthMethodTypeTOTP,
+		domain.UserAuthMethodTypePassword,
+		domain.UserAuthMethodTypeIDP,
+		domain.UserAuthMethodTypeTOTP,
+	})
+	assert.Equal(t, []domain.UserAuthMethodType{
+		domain.UserAuthMethodTypeTOTP,
+		domain.UserAuthMethodTypePassword,
+		domain.UserAuthMethodTypeIDP,
+	}, got)
+}
+
+func TestSessionWriteModel_IsIdleExpired(t *testing.T) {
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	start := time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		events []*repository.Event
+		now    time.Time
+		want   bool
+	}{
+		{
+			name: "no idle timeout",
+			events: []*repository.Event{
+				eventFromEventPusherWithCreationDate(session.NewAddedEvent(context.Background(), sessionAggregate, 0, "", "", "", nil), start),
+			},
+			now:  start.Add(time.Hour),
+			want: false,
+		},
+		{
+			name: "idle past timeout",
+			events: []*repository.Event{
+				eventFromEventPusherWithCreationDate(session.NewAddedEvent(context.Background(), sessionAggregate, 0, "", "", "", nil), start),
+				eventFromEventPusherWithCreationDate(session.NewLifetimeSetEvent(context.Background(), sessionAggregate, 0, 10*time.Minute), start),
+				eventFromEventPusherWithCreationDate(session.NewPasswordCheckedEvent(context.Background(), sessionAggregate, start.Add(time.Minute), ""), start.Add(time.Minute)),
+			},
+			now:  start.Add(15 * time.Minute),
+			want: true,
+		},
+		{
+			name: "refreshed by password check",
+			events: []*repository.Event{
+				eventFromEventPusherWithCreationDate(session.NewAddedEvent(context.Background(), sessionAggregate, 0, "", "", "", nil), start),
+				eventFromEventPusherWithCreationDate(session.NewLifetimeSetEvent(context.Background(), sessionAggregate, 0, 10*time.Minute), start),
+				eventFromEventPusherWithCreationDate(session.NewPasswordCheckedEvent(context.Background(), sessionAggregate, start.Add(time.Minute), ""), start.Add(time.Minute)),
+				eventFromEventPusherWithCreationDate(session.NewPasswordCheckedEvent(context.Background(), sessionAggregate, start.Add(10*time.Minute), ""), start.Add(10*time.Minute)),
+			},
+			now:  start.Add(15 * time.Minute),
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wm := NewSessionWriteModel("sessionID", "org1")
+			err := eventstoreExpect(t, expectFilter(tt.events...)).FilterToQueryReducer(context.Background(), wm)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, wm.IsIdleExpired(tt.now))
+		})
+	}
+}
+
+func TestSessionWriteModel_reduceActivity(t *testing.T) {
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	start := time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC)
+	wm := NewSessionWriteModel("sessionID", "org1")
+	err := eventstoreExpect(t, expectFilter(
+		eventFromEventPusherWithCreationDate(session.NewAddedEvent(context.Background(), sessionAggregate, 0, "", "", "", nil), start),
+		eventFromEventPusherWithCreationDate(session.NewLifetimeSetEvent(context.Background(), sessionAggregate, 0, 10*time.Minute), start),
+		eventFromEventPusherWithCreationDate(session.NewActivityEvent(context.Background(), sessionAggregate), start.Add(8*time.Minute)),
+	)).FilterToQueryReducer(context.Background(), wm)
+	require.NoError(t, err)
+
+	assert.Equal(t, start.Add(8*time.Minute), wm.LastActivityAt)
+	assert.Equal(t, start.Add(18*time.Minute), wm.IdleExpiration)
+	assert.False(t, wm.IsIdleExpired(start.Add(15*time.Minute)))
+	assert.True(t, wm.IsIdleExpired(start.Add(20*time.Minute)))
+	assert.Empty(t, wm.AuthMethodTypes())
+}
+
+func TestSessionWriteModel_RemainingLifetime(t *testing.T) {
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	start := time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		events []*repository.Event
+		now    time.Time
+		want   time.Duration
+	}{
+		{
+			name: "not existing",
+			now:  start,
+			want: 0,
+		},
+		{
+			name: "no expiration",
+			events: []*repository.Event{
+				eventFromEventPusherWithCreationDate(session.NewAddedEvent(context.Background(), sessionAggregate, 0, "", "", "", nil), start),
+			},
+			now:  start.Add(time.Hour),
+			want: time.Duration(math.MaxInt64),
+		},
+		{
+			name: "idle dominant",
+			events: []*repository.Event{
+				eventFromEventPusherWithCreationDate(session.NewAddedEvent(context.Background(), sessionAggregate, time.Hour, "", "", "", nil), start),
+				eventFromEventPusherWithCreationDate(session.NewLifetimeSetEvent(context.Background(), sessionAggregate, time.Hour, 10*time.Minute), start),
+				eventFromEventPusherWithCreationDate(session.NewPasswordCheckedEvent(context.Background(), sessionAggregate, start.Add(5*time.Minute), ""), start.Add(5*time.Minute)),
+			},
+			now:  start.Add(10 * time.Minute),
+			want: 5 * time.Minute,
+		},
+		{
+			name: "absolute dominant",
+			events: []*repository.Event{
+				eventFromEventPusherWithCreationDate(session.NewAddedEvent(context.Background(), sessionAggregate, time.Hour, "", "", "", nil), start),
+				eventFromEventPusherWithCreationDate(session.NewLifetimeSetEvent(context.Background(), sessionAggregate, time.Hour, 10*time.Minute), start),
+				eventFromEventPusherWithCreationDate(session.NewPasswordCheckedEvent(context.Background(), sessionAggregate, start.Add(55*time.Minute), ""), start.Add(55*time.Minute)),
+			},
+			now:  start.Add(57 * time.Minute),
+			want: 3 * time.Minute,
+		},
+		{
+			name: "expired",
+			events: []*repository.Event{
+				eventFromEventPusherWithCreationDate(session.NewAddedEvent(context.Background(), sessionAggregate, time.Hour, "", "", "", nil), start),
+				eventFromEventPusherWithCreationDate(session.NewLifetimeSetEvent(context.Background(), sessionAggregate, time.Hour, 0), start),
+			},
+			now:  start.Add(2 * time.Hour),
+			want: 0,
+		},
+		{
+			name: "terminated",
+			events: []*repository.Event{
+				eventFromEventPusherWithCreationDate(session.NewAddedEvent(context.Background(), sessionAggregate, time.Hour, "", "", "", nil), start),
+				eventFromEventPusherWithCreationDate(session.NewLifetimeSetEvent(context.Background(), sessionAggregate, time.Hour, 0), start),
+				eventFromEventPusherWithCreationDate(session.NewTerminateEvent(context.Background(), sessionAggregate, domain.SessionTerminationTypeLogout), start),
+			},
+			now:  start.Add(time.Minute),
+			want: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wm := NewSessionWriteModel("sessionID", "org1")
+			err := eventstoreExpect(t, expectFilter(tt.events...)).FilterToQueryReducer(context.Background(), wm)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, wm.RemainingLifetime(tt.now))
+		})
+	}
+}
+
+func TestSessionWriteModel_IsExpired(t *testing.T) {
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	start := time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC)
+	type res struct {
+		expired bool
+		state   domain.SessionState
+	}
+	tests := []struct {
+		name   string
+		events []*repository.Event
+		now    time.Time
+		res    res
+	}{
+		{
+			name: "no lifetime",
+			events: []*repository.Event{
+				eventFromEventPusherWithCreationDate(session.NewAddedEvent(context.Background(), sessionAggregate, 0, "", "", "", nil), start),
+			},
+			now: start.Add(24 * time.Hour),
+			res: res{
+				expired: false,
+				state:   domain.SessionStatePending,
+			},
+		},
+		{
+			name: "within lifetime",
+			events: []*repository.Event{
+				eventFromEventPusherWithCreationDate(session.NewAddedEvent(context.Background(), sessionAggregate, time.Hour, "", "", "", nil), start),
+			},
+			now: start.Add(30 * time.Minute),
+			res: res{
+				expired: false,
+				state:   domain.SessionStatePending,
+			},
+		},
+		{
+			name: "expired, not terminated",
+			events: []*repository.Event{
+				eventFromEventPusherWithCreationDate(session.NewAddedEvent(context.Background(), sessionAggregate, time.Hour, "", "", "", nil), start),
+			},
+			now: start.Add(2 * time.Hour),
+			res: res{
+				expired: true,
+				state:   domain.SessionStatePending,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wm := NewSessionWriteModel("sessionID", "org1")
+			err := eventstoreExpect(t, expectFilter(tt.events...)).FilterToQueryReducer(context.Background(), wm)
+			require.NoError(t, err)
+			assert.Equal(t, tt.res.expired, wm.IsExpired(tt.now))
+			assert.Equal(t, tt.res.state, wm.State)
+		})
+	}
+}
+
+func TestSessionWriteModel_TerminationReason(t *testing.T) {
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	tests := []struct {
+		name   string
+		reason domain.SessionTerminationType
+	}{
+		{
+			name:   "unspecified",
+			reason: domain.SessionTerminationTypeUnspecified,
+		},
+		{
+			name:   "logout",
+			reason: domain.SessionTerminationTypeLogout,
+		},
+		{
+			name:   "revoked",
+			reason: domain.SessionTerminationTypeRevoked,
+		},
+		{
+			name:   "expired",
+			reason: domain.SessionTerminationTypeExpired,
+		},
+		{
+			name:   "replaced",
+			reason: domain.SessionTerminationTypeReplaced,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wm := NewSessionWriteModel("sessionID", "org1")
+			err := eventstoreExpect(t,
+				expectFilter(
+					eventFromEventPusher(session.NewAddedEvent(context.Background(), sessionAggregate, 0, "", "", "", nil)),
+					eventFromEventPusher(session.NewTerminateEvent(context.Background(), sessionAggregate, tt.reason)),
+				),
+			).FilterToQueryReducer(context.Background(), wm)
+			require.NoError(t, err)
+			assert.Equal(t, domain.SessionStateTerminated, wm.State)
+			assert.Equal(t, tt.reason, wm.TerminationReason)
+		})
+	}
+}
+
+func TestSessionWriteModel_Clone(t *testing.T) {
+	wm := NewSessionWriteModel("sessionID", "org1")
+	wm.UserID = "userID"
+	wm.PasswordCheckedAt = testNow
+	wm.Metadata["key"] = []byte("value")
+	wm.ScopeAuthLevels = map[string]domain.AuthLevel{"admin": domain.AuthLevel2}
+	wm.WebAuthNChallenge = &WebAuthNChallengeModel{
+		Challenge:            "challenge",
+		AllowedCredentialIDs: [][]byte{[]byte("credentialID")},
+		UserVerification:     domain.UserVerificationRequirementRequired,
+		RPID:                 "example.com",
+	}
+
+	clone := wm.Clone()
+	assert.Equal(t, wm, clone)
+
+	clone.UserID = "otherUserID"
+	clone.Metadata["key"][0] = 'V'
+	clone.Metadata["other"] = []byte("other")
+	clone.ScopeAuthLevels["admin"] = domain.AuthLevel1
+	clone.ScopeAuthLevels["other"] = domain.AuthLevel2
+	clone.WebAuthNChallenge.Challenge = "otherChallenge"
+	clone.WebAuthNChallenge.AllowedCredentialIDs[0][0] = 'C'
+	clone.WebAuthNChallenge.AllowedCredentialIDs = append(clone.WebAuthNChallenge.AllowedCredentialIDs, []byte("otherCredentialID"))
+
+	assert.Equal(t, "userID", wm.UserID)
+	assert.Equal(t, map[string][]byte{"key": []byte("value")}, wm.Metadata)
+	assert.Equal(t, map[string]domain.AuthLevel{"admin": domain.AuthLevel2}, wm.ScopeAuthLevels)
+	assert.Equal(t, &WebAuthNChallengeModel{
+		Challenge:            "challenge",
+		AllowedCredentialIDs: [][]byte{[]byte("credentialID")},
+		UserVerification:     domain.UserVerificationRequirementRequired,
+		RPID:                 "example.com",
+	}, wm.WebAuthNChallenge)
+}
+
+func TestWebAuthNChallengeModel_AllowedCrentialIDs(t *testing.T) {
+	challenge := &WebAuthNChallengeModel{
+		AllowedCredentialIDs: [][]byte{[]byte("credentialID")},
+	}
+	assert.Equal(t, challenge.AllowedCredentialIDs, challenge.AllowedCrentialIDs())
+
+	challenge.SetAllowedCrentialIDs([][]byte{[]byte("otherCredentialID")})
+	assert.Equal(t, [][]byte{[]byte("otherCredentialID")}, challenge.AllowedCredentialIDs)
+	assert.Equal(t, challenge.AllowedCredentialIDs, challenge.AllowedCrentialIDs())
+}
+
+func TestSessionWriteModel_SetMetadata(t *testing.T) {
+	type args struct {
+		key   string
+		value []byte
+	}
+	tests := []struct {
+		name     string
+		limits   SessionMetadataLimits
+		metadata map[string][]byte
+		args     args
+		want     map[string][]byte
+		err      error
+	}{
+		{
+			name:     "empty key",
+			metadata: map[string][]byte{},
+			args:     args{key: "", value: []byte("value")},
+			want:     map[string][]byte{},
+			err:      caos_errs.ThrowInvalidArgument(nil, "COMMAND-Oow8i", "Errors.Session.Metadata.KeyInvalid"),
+		},
+		{
+			name:     "key too long",
+			limits:   SessionMetadataLimits{MaxKeyLength: 3},
+			metadata: map[string][]byte{},
+			args:     args{key: "long", value: []byte("value")},
+			want:     map[string][]byte{},
+			err:      caos_errs.ThrowInvalidArgument(nil, "COMMAND-Oow8i", "Errors.Session.Metadata.KeyInvalid"),
+		},
+		{
+			name:     "value too long",
+			limits:   SessionMetadataLimits{MaxValueLength: 3},
+			metadata: map[string][]byte{},
+			args:     args{key: "key", value: []byte("value")},
+			want:     map[string][]byte{},
+			err:      caos_errs.ThrowInvalidArgument(nil, "COMMAND-ieM3a", "Errors.Session.Metadata.ValueTooLong"),
+		},
+		{
+			name:     "total size exceeded",
+			limits:   SessionMetadataLimits{MaxTotalSize: 10},
+			metadata: map[string][]byte{"key": []byte("value")},
+			args:     args{key: "key2", value: []byte("value")},
+			want:     map[string][]byte{"key": []byte("value")},
+			err:      caos_errs.ThrowInvalidArgument(nil, "COMMAND-Gae4u", "Errors.Session.Metadata.TooLarge"),
+		},
+		{
+			name:     "replace within total size",
+			limits:   SessionMetadataLimits{MaxTotalSize: 10},
+			metadata: map[string][]byte{"key": []byte("value")},
+			args:     args{key: "key", value: []byte("value2")},
+			want:     map[string][]byte{"key": []byte("value2")},
+		},
+		{
+			name:     "too many entries",
+			limits:   SessionMetadataLimits{MaxEntries: 1},
+			metadata: map[string][]byte{"key": []byte("value")},
+			args:     args{key: "key2", value: []byte("value")},
+			want:     map[string][]byte{"key": []byte("value")},
+			err:      caos_errs.ThrowInvalidArgument(nil, "COMMAND-Ohb3e", "Errors.Session.Metadata.TooManyEntries"),
+		},
+		{
+			name:     "replace with max entries",
+			limits:   SessionMetadataLimits{MaxEntries: 1},
+			metadata: map[string][]byte{"key": []byte("value")},
+			args:     args{key: "key", value: []byte("value2")},
+			want:     map[string][]byte{"key": []byte("value2")},
+		},
+		{
+			name:     "ok",
+			metadata: map[string][]byte{"key": []byte("value")},
+			args:     args{key: "key2", value: []byte("value2")},
+			want:     map[string][]byte{"key": []byte("value"), "key2": []byte("value2")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wm := &SessionWriteModel{
+				Metadata:       tt.metadata,
+				MetadataLimits: tt.limits,
+			}
+			err := wm.SetMetadata(tt.args.key, tt.args.value)
+			require.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.want, wm.Metadata)
+		})
+	}
+}
+
+func TestSessionWriteModel_SetMetadata_maxEntries(t *testing.T) {
+	const maxEntries = 5
+	wm := &SessionWriteModel{
+		MetadataLimits: SessionMetadataLimits{MaxEntries: maxEntries},
+	}
+	for i := 0; i < maxEntries; i++ {
+		require.NoError(t, wm.SetMetadata(fmt.Sprintf("key%d", i), []byte("value")))
+	}
+	err := wm.SetMetadata(fmt.Sprintf("key%d", maxEntries), []byte("value"))
+	require.ErrorIs(t, err, caos_errs.ThrowInvalidArgument(nil, "COMMAND-Ohb3e", "Errors.Session.Metadata.TooManyEntries"))
+	assert.Len(t, wm.Metadata, maxEntries)
+
+	require.NoError(t, wm.SetMetadata("key0", []byte("updated")))
+	assert.Equal(t, []byte("updated"), wm.Metadata["key0"])
+	assert.Len(t, wm.Metadata, maxEntries)
+}
+
+func TestSessionWriteModel_RemoveMetadata(t *testing.T) {
+	wm := &SessionWriteModel{
+		Metadata: map[string][]byte{"key": []byte("value")},
+	}
+	wm.RemoveMetadata("notExisting")
+	assert.Equal(t, map[string][]byte{"key": []byte("value")}, wm.Metadata)
+	wm.RemoveMetadata("key")
+	assert.Empty(t, wm.Metadata)
+}
+
+func TestSessionWriteModel_reduceMetadata(t *testing.T) {
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	t.Run("ok", func(t *testing.T) {
+		wm := NewSessionWriteModel("sessionID", "org1")
+		err := eventstoreExpect(t,
+			expectFilter(
+				eventFromEventPusher(session.NewAddedEvent(context.Background(), sessionAggregate, 0, "", "", "", nil)),
+				eventFromEventPusher(session.NewMetadataSetEvent(context.Background(), sessionAggregate, map[string][]byte{"key": []byte("value")}, nil)),
+			),
+		).FilterToQueryReducer(context.Background(), wm)
+		require.NoError(t, err)
+		assert.Equal(t, map[string][]byte{"key": []byte("value")}, wm.Metadata)
+	})
+	t.Run("set and removed", func(t *testing.T) {
+		wm := NewSessionWriteModel("sessionID", "org1")
+		err := eventstoreExpect(t,
+			expectFilter(
+				eventFromEventPusher(session.NewAddedEvent(context.Background(), sessionAggregate, 0, "", "", "", nil)),
+				eventFromEventPusher(session.NewMetadataSetEvent(context.Background(), sessionAggregate, map[string][]byte{"key": []byte("value"), "transient": []byte("value")}, nil)),
+				eventFromEventPusher(session.NewMetadataRemovedEvent(context.Background(), sessionAggregate, []string{"transient"})),
+			),
+		).FilterToQueryReducer(context.Background(), wm)
+		require.NoError(t, err)
+		assert.NotContains(t, wm.Metadata, "transient")
+		assert.Equal(t, map[string][]byte{"key": []byte("value")}, wm.Metadata)
+	})
+	t.Run("limit exceeded by stored event", func(t *testing.T) {
+		wm := NewSessionWriteModel("sessionID", "org1")
+		wm.MetadataLimits = SessionMetadataLimits{MaxValueLength: 3}
+		err := eventstoreExpect(t,
+			expectFilter(
+				eventFromEventPusher(session.NewAddedEvent(context.Background(), sessionAggregate, 0, "", "", "", nil)),
+				eventFromEventPusher(session.NewMetadataSetEvent(context.Background(), sessionAggregate, map[string][]byte{"key": []byte("value")}, nil)),
+			),
+		).FilterToQueryReducer(context.Background(), wm)
+		require.NoError(t, err)
+		assert.Equal(t, map[string][]byte{"key": []byte("value")}, wm.Metadata)
+
+		// but new values are still validated
+		err = wm.SetMetadata("other", []byte("value"))
+		require.ErrorIs(t, err, caos_errs.ThrowInvalidArgument(nil, "COMMAND-ieM3a", "Errors.Session.Metadata.ValueTooLong"))
+	})
+}
+
+func TestSessionWriteModel_MissingFactors(t *testing.T) {
+	tests := []struct {
+		name     string
+		wm       *SessionWriteModel
+		required []domain.UserAuthMethodType
+		want     []domain.UserAuthMethodType
+	}{
+		{
+			name:     "nothing required",
+			wm:       &SessionWriteModel{},
+			required: nil,
+			want:     nil,
+		},
+		{
+			name: "nothing checked",
+			wm:   &SessionWriteModel{},
+			required: []domain.UserAuthMethodType{
+				domain.UserAuthMethodTypePassword,
+				domain.UserAuthMethodTypeTOTP,
+			},
+			want: []domain.UserAuthMethodType{
+				domain.UserAuthMethodTypePassword,
+				domain.UserAuthMethodTypeTOTP,
+			},
+		},
+		{
+			name: "password checked, totp missing",
+			wm: &SessionWriteModel{
+				PasswordCheckedAt: testNow,
+			},
+			required: []domain.UserAuthMethodType{
+				domain.UserAuthMethodTypePassword,
+				domain.UserAuthMethodTypeTOTP,
+			},
+			want: []domain.UserAuthMethodType{
+				domain.UserAuthMethodTypeTOTP,
+			},
+		},
+		{
+			name: "password and totp checked",
+			wm: &SessionWriteModel{
+				PasswordCheckedAt: testNow,
+				TOTPCheckedAt:     testNow,
+			},
+			required: []domain.UserAuthMethodType{
+				domain.UserAuthMethodTypePassword,
+				domain.UserAuthMethodTypeTOTP,
+			},
+			want: nil,
+		},
+		{
+			name: "passwordless satisfies password and second factor",
+			wm: &SessionWriteModel{
+				WebAuthNCheckedAt:      testNow,
+				WebAuthNUserVerified:   true,
+				WebAuthNIsPasswordless: true,
+			},
+			required: []domain.UserAuthMethodType{
+				domain.UserAuthMethodTypePassword,
+				domain.UserAuthMethodTypeU2F,
+			},
+			want: nil,
+		},
+		{
+			name: "passwordless does not satisfy idp",
+			wm: &SessionWriteModel{
+				WebAuthNCheckedAt:      testNow,
+				WebAuthNUserVerified:   true,
+				WebAuthNIsPasswordless: true,
+			},
+			required: []domain.UserAuthMethodType{
+				domain.UserAuthMethodTypeIDP,
+			},
+			want: []domain.UserAuthMethodType{
+				domain.UserAuthMethodTypeIDP,
+			},
+		},
+		{
+			name: "u2f does not satisfy password",
+			wm: &SessionWriteModel{
+				WebAuthNCheckedAt: testNow,
+			},
+			required: []domain.UserAuthMethodType{
+				domain.UserAuthMethodTypePassword,
+				domain.UserAuthMethodTypeU2F,
+			},
+			want: []domain.UserAuthMethodType{
+				domain.UserAuthMethodTypePassword,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.wm.MissingFactors(tt.required))
+		})
+	}
+}
+
+func TestSessionWriteModel_reduceWebAuthNChecked(t *testing.T) {
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	tests := []struct {
+		name             string
+		userVerification domain.UserVerificationRequirement
+		userVerified     bool
+		userPresent      bool
+		want             []domain.UserAuthMethodType
+	}{
+		{
+			name:             "passwordless challenge",
+			userVerification: domain.UserVerificationRequirementRequired,
+			userVerified:     true,
+			userPresent:      true,
+			want:             []domain.UserAuthMethodType{domain.UserAuthMethodTypePasswordless},
+		},
+		{
+			name:             "passwordless challenge, presence without verification",
+			userVerification: domain.UserVerificationRequirementRequired,
+			userVerified:     false,
+			userPresent:      true,
+			want:             []domain.UserAuthMethodType{domain.UserAuthMethodTypeU2F},
+		},
+		{
+			name:             "second factor challenge, user verified",
+			userVerification: domain.UserVerificationRequirementDiscouraged,
+			userVerified:     true,
+			userPresent:      true,
+			want:             []domain.UserAuthMethodType{domain.UserAuthMethodTypeU2F},
+		},
+		{
+			name:             "second factor challenge",
+			userVerification: domain.UserVerificationRequirementDiscouraged,
+			userVerified:     false,
+			userPresent:      true,
+			want:             []domain.UserAuthMethodType{domain.UserAuthMethodTypeU2F},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wm := NewSessionWriteModel("sessionID", "org1")
+			err := eventstoreExpect(t,
+				expectFilter(
+					eventFromEventPusher(session.NewAddedEvent(context.Background(), sessionAggregate, 0, "", "", "", nil)),
+					eventFromEventPusher(session.NewWebAuthNChallengedEvent(context.Background(), sessionAggregate, "challenge", nil, nil, tt.userVerification, "example.com", testNow.Add(time.Minute))),
+					eventFromEventPusher(session.NewWebAuthNCheckedEvent(context.Background(), sessionAggregate, testNow, tt.userVerified, tt.userPresent, 0, "", "", nil)),
+				),
+			).FilterToQueryReducer(context.Background(), wm)
+			require.NoError(t, err)
+			challenge, ok := wm.ActiveWebAuthNChallenge()
+			assert.False(t, ok)
+			assert.Nil(t, challenge)
+			assert.Equal(t, tt.userVerified, wm.WebAuthNUserVerified)
+			assert.Equal(t, tt.userPresent, wm.WebAuthNUserPresent)
+			assert.Equal(t, tt.want, wm.AuthMethodTypes())
+		})
+	}
+}
+
+func TestSessionWriteModel_ActiveWebAuthNChallenge(t *testing.T) {
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	wm := NewSessionWriteModel("sessionID", "org1")
+	err := eventstoreExpect(t,
+		expectFilter(
+			eventFromEventPusher(session.NewAddedEvent(context.Background(), sessionAggregate, 0, "", "", "", nil)),
+			eventFromEventPusher(session.NewWebAuthNChallengedEvent(context.Background(), sessionAggregate, "challenge", nil, nil, domain.UserVerificationRequirementRequired, "example.com", time.Time{})),
+		),
+	).FilterToQueryReducer(context.Background(), wm)
+	require.NoError(t, err)
+	challenge, ok := wm.ActiveWebAuthNChallenge()
+	require.True(t, ok)
+	assert.Equal(t, "challenge", challenge.Challenge)
+
+	err = AppendAndReduce(wm, session.NewWebAuthNCheckedEvent(context.Background(), sessionAggregate, testNow, true, true, 0, "", "", nil))
+	require.NoError(t, err)
+	challenge, ok = wm.ActiveWebAuthNChallenge()
+	assert.False(t, ok)
+	assert.Nil(t, challenge)
+}
+
+func TestSessionWriteModel_reduceWebAuthNChecked_multipleChallenges(t *testing.T) {
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	wm := NewSessionWriteModel("sessionID", "org1")
+	err := eventstoreExpect(t,
+		expectFilter(
+			eventFromEventPusher(session.NewAddedEvent(context.Background(), sessionAggregate, 0, "", "", "", nil)),
+			eventFromEventPusher(session.NewWebAuthNChallengedEvent(context.Background(), sessionAggregate, "challenge1", nil, nil, domain.UserVerificationRequirementRequired, "example.com", time.Time{})),
+			eventFromEventPusher(session.NewWebAuthNChallengedEvent(context.Background(), sessionAggregate, "challenge2", nil, nil, domain.UserVerificationRequirementDiscouraged, "example.com", time.Time{})),
+			eventFromEventPusher(session.NewWebAuthNCheckedEvent(context.Background(), sessionAggregate, testNow, true, true, 0, "", "challenge1", nil)),
+		),
+	).FilterToQueryReducer(context.Background(), wm)
+	require.NoError(t, err)
+
+	_, ok := wm.WebAuthNChallengeByID("challenge1")
+	assert.False(t, ok)
+	challenge, ok := wm.WebAuthNChallengeByID("challenge2")
+	require.True(t, ok)
+	assert.Equal(t, domain.UserVerificationRequirementDiscouraged, challenge.UserVerification)
+	challenge, ok = wm.ActiveWebAuthNChallenge()
+	require.True(t, ok)
+	assert.Equal(t, "challenge2", challenge.Challenge)
+	assert.Equal(t, []domain.UserAuthMethodType{domain.UserAuthMethodTypePasswordless}, wm.AuthMethodTypes())
+
+	err = AppendAndReduce(wm, session.NewWebAuthNCheckedEvent(context.Background(), sessionAggregate, testNow, true, true, 0, "", "challenge2", nil))
+	require.NoError(t, err)
+	_, ok = wm.WebAuthNChallengeByID("challenge2")
+	assert.False(t, ok)
+	_, ok = wm.ActiveWebAuthNChallenge()
+	assert.False(t, ok)
+}
+
+func TestSessionWriteModel_AuthenticationAssuranceLevel(t *testing.T) {
+	tests := []struct {
+		name string
+		wm   *SessionWriteModel
+		want domain.AuthLevel
+	}{
+		{
+			name: "no checks",
+			wm:   &SessionWriteModel{},
+			want: domain.AuthLevelUnspecified,
+		},
+		{
+			name: "password only",
+			wm: &SessionWriteModel{
+				PasswordCheckedAt: testNow,
+			},
+			want: domain.AuthLevel1,
+		},
+		{
+			name: "intent only",
+			wm: &SessionWriteModel{
+				IntentCheckedAt: testNow,
+			},
+			want: domain.AuthLevel1,
+		},
+		{
+			name: "totp only",
+			wm: &SessionWriteModel{
+				TOTPCheckedAt: testNow,
+			},
+			want: domain.AuthLevel1,
+		},
+		{
+			name: "password and totp",
+			wm: &SessionWriteModel{
+				PasswordCheckedAt: testNow,
+				TOTPCheckedAt:     testNow,
+			},
+			want: domain.AuthLevel2,
+		},
+		{
+			name: "password and otp sms",
+			wm: &SessionWriteModel{
+				PasswordCheckedAt: testNow,
+				OTPSMSCheckedAt:   testNow,
+			},
+			want: domain.AuthLevel2,
+		},
+		{
+			name: "password and u2f",
+			wm: &SessionWriteModel{
+				PasswordCheckedAt: testNow,
+				WebAuthNCheckedAt: testNow,
+			},
+			want: domain.AuthLevel2,
+		},
+		{
+			name: "passwordless only",
+			wm: &SessionWriteModel{
+				WebAuthNCheckedAt:      testNow,
+				WebAuthNUserVerified:   true,
+				WebAuthNIsPasswordless: true,
+			},
+			want: domain.AuthLevel3,
+		},
+		{
+			name: "passwordless challenge without user verification",
+			wm: &SessionWriteModel{
+				WebAuthNCheckedAt:      testNow,
+				WebAuthNIsPasswordless: true,
+			},
+			want: domain.AuthLevel1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.wm.AuthenticationAssuranceLevel())
+		})
+	}
+}
+
+func TestSessionWriteModel_ValidateTokenClaims(t *testing.T) {
+	aal1 := &SessionWriteModel{
+		PasswordCheckedAt: testNow,
+	}
+	tests := []struct {
+		name    string
+		wm      *SessionWriteModel
+		claimed domain.AuthLevel
+		wantErr error
+	}{
+		{
+			name:    "lower level",
+			wm:      &SessionWriteModel{PasswordCheckedAt: testNow, TOTPCheckedAt: testNow},
+			claimed: domain.AuthLevel1,
+		},
+		{
+			name:    "same level",
+			wm:      aal1,
+			claimed: domain.AuthLevel1,
+		},
+		{
+			name:    "unspecified level",
+			wm:      &SessionWriteModel{},
+			claimed: domain.AuthLevelUnspecified,
+		},
+		{
+			name:    "higher level",
+			wm:      aal1,
+			claimed: domain.AuthLevel3,
+			wantErr: caos_errs.ThrowPreconditionFailed(nil, "COMMAND-Ki8ah", "Errors.Session.Token.AuthLevelExceeded"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.wm.ValidateTokenClaims(tt.claimed)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestSessionWriteModel_Snapshot(t *testing.T) {
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	wm := NewSessionWriteModel("sessionID", "org1")
+	err := eventstoreExpect(t,
+		expectFilter(
+			eventFromEventPusher(session.NewAddedEvent(context.Background(), sessionAggregate, 0, "", "", "", nil)),
+			eventFromEventPusher(session.NewUserCheckedEvent(context.Background(), sessionAggregate, "userID", testNow)),
+			eventFromEventPusher(session.NewPasswordCheckedEvent(context.Background(), sessionAggregate, testNow, "")),
+			eventFromEventPusher(session.NewWebAuthNChallengedEvent(context.Background(), sessionAggregate, "challenge", nil, nil, domain.UserVerificationRequirementDiscouraged, "example.com", time.Time{})),
+			eventFromEventPusher(session.NewWebAuthNCheckedEvent(context.Background(), sessionAggregate, testNow, false, true, 0, "", "challenge", nil)),
+			eventFromEventPusher(session.NewTOTPCheckedEvent(context.Background(), sessionAggregate, testNow, "", 0)),
+		),
+	).FilterToQueryReducer(context.Background(), wm)
+	require.NoError(t, err)
+
+	snapshot := wm.Snapshot()
+	assert.Equal(t, "sessionID", snapshot.ID)
+	assert.Equal(t, "org1", snapshot.ResourceOwner)
+	assert.Equal(t, "userID", snapshot.UserID)
+	assert.Equal(t, domain.SessionStateActive, snapshot.State)
+	assert.Equal(t, wm.AuthMethodTypes(), snapshot.AuthMethodTypes)
+
+	loaded := LoadSnapshot(snapshot)
+	assert.Equal(t, wm.AuthMethodTypes(), loaded.AuthMethodTypes())
+	assert.Equal(t, wm.AuthenticationTime(), loaded.AuthenticationTime())
+	assert.Equal(t, snapshot, loaded.Snapshot())
+}
+
+func TestSessionWriteModel_reduceSnapshot(t *testing.T) {
+	ctx := context.Background()
+	// fixed UTC time, which is not changed by the marshalling of the snapshot
+	now := time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC)
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	head := []eventstore.Command{
+		session.NewAddedEvent(ctx, sessionAggregate, time.Hour, "", "", "", nil),
+		session.NewUserCheckedEvent(ctx, sessionAggregate, "userID", now),
+		session.NewPasswordCheckedEvent(ctx, sessionAggregate, now, ""),
+		session.NewWebAuthNChallengedEvent(ctx, sessionAggregate, "challenge1", [][]byte{[]byte("credentialID")}, nil, domain.UserVerificationRequirementRequired, "example.com", now.Add(time.Minute)),
+		session.NewWebAuthNChallengedEvent(ctx, sessionAggregate, "challenge2", nil, nil, domain.UserVerificationRequirementDiscouraged, "example.com", now.Add(time.Minute)),
+		session.NewTokenSetEvent(ctx, sessionAggregate, "tokenID"),
+		session.NewMetadataSetEvent(ctx, sessionAggregate, map[string][]byte{"key": []byte("value")}, nil),
+	}
+	tail := []eventstore.Command{
+		session.NewWebAuthNCheckedEvent(ctx, sessionAggregate, now.Add(time.Second), true, true, 0, "", "challenge1", nil),
+		session.NewTOTPCheckedEvent(ctx, sessionAggregate, now.Add(2*time.Second), "", 0),
+		session.NewLifetimeSetEvent(ctx, sessionAggregate, 0, time.Minute),
+		session.NewTokenSetEvent(ctx, sessionAggregate, "tokenID2"),
+	}
+	toEvents := func(cmds ...eventstore.Command) []*repository.Event {
+		events := make([]*repository.Event, len(cmds))
+		for i, cmd := range cmds {
+			events[i] = eventFromEventPusherWithCreationDate(cmd, now)
+		}
+		return events
+	}
+
+	full := NewSessionWriteModel("sessionID", "org1")
+	err := eventstoreExpect(t,
+		expectFilter(toEvents(append(head, tail...)...)...),
+	).FilterToQueryReducer(ctx, full)
+	require.NoError(t, err)
+
+	beforeSnapshot := NewSessionWriteModel("sessionID", "org1")
+	err = eventstoreExpect(t,
+		expectFilter(toEvents(head...)...),
+	).FilterToQueryReducer(ctx, beforeSnapshot)
+	require.NoError(t, err)
+	snapshot := session.NewSnapshotEvent(ctx, sessionAggregate, beforeSnapshot.snapshotState())
+
+	fromSnapshot := NewSessionWriteModel("sessionID", "org1")
+	err = eventstoreExpect(t,
+		expectFilter(toEvents(append([]eventstore.Command{snapshot}, tail...)...)...),
+	).FilterToQueryReducer(ctx, fromSnapshot)
+	require.NoError(t, err)
+
+	assert.Equal(t, full.snapshotState(), fromSnapshot.snapshotState())
+	assert.Equal(t, full.AuthMethodTypes(), fromSnapshot.AuthMethodTypes())
+	assert.Equal(t, full.AuthenticationTime(), fromSnapshot.AuthenticationTime())
+	assert.Equal(t, 4, fromSnapshot.eventsSinceSnapshot)
+	challenge, ok := fromSnapshot.ActiveWebAuthNChallenge()
+	require.True(t, ok)
+	assert.Equal(t, "challenge2", challenge.Challenge)
+}
+
+func TestCommands_sessionWriteModelFromSnapshot(t *testing.T) {
+	ctx := context.Background()
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	c := &Commands{
+		eventstore: eventstoreExpect(t,
+			expectFilter(
+				eventFromEventPusher(session.NewSnapshotEvent(ctx, sessionAggregate, session.SnapshotState{
+					UserID:            "userID",
+					UserCheckedAt:     testNow,
+					PasswordCheckedAt: testNow,
+					State:             domain.SessionStateActive,
+				})),
+			),
+			expectFilter(
+				eventFromEventPusher(session.NewTOTPCheckedEvent(ctx, sessionAggregate, testNow, "", 0)),
+			),
+		),
+	}
+	wm, err := c.sessionWriteModelFromSnapshot(ctx, "sessionID", "org1")
+	require.NoError(t, err)
+	assert.Equal(t, "userID", wm.UserID)
+	assert.Equal(t, domain.SessionStateActive, wm.State)
+	assert.Equal(t, []domain.UserAuthMethodType{domain.UserAuthMethodTypeTOTP, domain.UserAuthMethodTypePassword}, wm.AuthMethodTypes())
+}
+
+func TestSessionWriteModel_QueryMany(t *testing.T) {
+	ctx := context.Background()
+	query := NewSessionWriteModel("", "org1").QueryMany("sessionID1", "sessionID2", "sessionID3")
+
+	for _, id := range []string{"sessionID1", "sessionID2", "sessionID3"} {
+		event := session.NewAddedEvent(ctx, &session.NewAggregate(id, "org1").Aggregate, 0, "", "", "", nil)
+		assert.True(t, query.Matches(event, 0), "session %s must be part of the query", id)
+	}
+	assert.False(t, query.Matches(session.NewAddedEvent(ctx, &session.NewAggregate("sessionID4", "org1").Aggregate, 0, "", "", "", nil), 0), "other session must not be part of the query")
+	assert.False(t, query.Matches(session.NewAddedEvent(ctx, &session.NewAggregate("sessionID1", "org2").Aggregate, 0, "", "", "", nil), 0), "other resource owner must not be part of the query")
+	assert.False(t, query.Matches(user.NewHumanPasswordCheckSucceededEvent(ctx, &user.NewAggregate("sessionID1", "org1").Aggregate, nil), 0), "other aggregate type must not be part of the query")
+}
+
+func TestSessionWriteModel_PasswordCheckFailures(t *testing.T) {
+	ctx := context.Background()
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	wm := NewSessionWriteModel("sessionID", "org1")
+	err := eventstoreExpect(t,
+		expectFilter(
+			eventFromEventPusher(session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil)),
+			eventFromEventPusher(session.NewPasswordCheckFailedEvent(ctx, sessionAggregate, testNow)),
+			eventFromEventPusher(session.NewPasswordCheckFailedEvent(ctx, sessionAggregate, testNow)),
+		),
+	).FilterToQueryReducer(ctx, wm)
+	require.NoError(t, err)
+	assert.Equal(t, 2, wm.PasswordCheckFailures)
+	assert.True(t, wm.PasswordCheckedAt.IsZero())
+
+	err = AppendAndReduce(wm, session.NewPasswordCheckFailedEvent(ctx, sessionAggregate, testNow))
+	require.NoError(t, err)
+	assert.Equal(t, 3, wm.PasswordCheckFailures)
+
+	err = AppendAndReduce(wm, session.NewPasswordCheckedEvent(ctx, sessionAggregate, testNow, ""))
+	require.NoError(t, err)
+	assert.Equal(t, 0, wm.PasswordCheckFailures)
+}
+
+func TestSessionWriteModel_PasswordLocked(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int
+		max      int
+		want     bool
+	}{
+		{
+			name:     "no limit",
+			failures: 10,
+			max:      0,
+			want:     false,
+		},
+		{
+			name:     "below threshold",
+			failures: 2,
+			max:      3,
+			want:     false,
+		},
+		{
+			name:     "threshold reached",
+			failures: 3,
+			max:      3,
+			want:     true,
+		},
+		{
+			name:     "threshold exceeded",
+			failures: 4,
+			max:      3,
+			want:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wm := &SessionWriteModel{
+				PasswordCheckFailures: tt.failures,
+			}
+			assert.Equal(t, tt.want, wm.PasswordLocked(tt.max))
+		})
+	}
+}
+
+func TestSessionWriteModel_reduceAdded_origin(t *testing.T) {
+	ctx := context.Background()
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	wm := NewSessionWriteModel("sessionID", "org1")
+	err := eventstoreExpect(t,
+		expectFilter(
+			eventFromEventPusher(session.NewAddedEvent(ctx, sessionAggregate, 0, "userAgentID", "authRequestID", "", nil)),
+		),
+	).FilterToQueryReducer(ctx, wm)
+	require.NoError(t, err)
+	assert.Equal(t, "userAgentID", wm.UserAgentFingerprintID)
+	assert.Equal(t, "authRequestID", wm.CreatedFromRequestID)
+
+	snapshot := wm.Snapshot()
+	assert.Equal(t, "userAgentID", snapshot.UserAgentFingerprintID)
+	assert.Equal(t, "authRequestID", snapshot.CreatedFromRequestID)
+}
+
+func TestSessionWriteModel_reduceAdded_clientID(t *testing.T) {
+	ctx := context.Background()
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	wm := NewSessionWriteModel("sessionID", "org1")
+	err := AppendAndReduce(wm,
+		session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "clientID", nil),
+		session.NewUserCheckedEvent(ctx, sessionAggregate, "userID", testNow),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "clientID", wm.ClientID)
+	assert.Equal(t, "clientID", wm.Snapshot().ClientID)
+	assert.Equal(t, "clientID", LoadSnapshot(wm.Snapshot()).ClientID)
+
+	restored := NewSessionWriteModel("sessionID", "org1")
+	err = AppendAndReduce(restored, session.NewSnapshotEvent(ctx, sessionAggregate, wm.snapshotState()))
+	require.NoError(t, err)
+	assert.Equal(t, "clientID", restored.ClientID)
+}
+
+func TestSessionWriteModel_SatisfiesACR(t *testing.T) {
+	tests := []struct {
+		name string
+		wm   *SessionWriteModel
+		acr  string
+		want bool
+	}{
+		{
+			name: "no acr",
+			wm:   &SessionWriteModel{},
+			acr:  "",
+			want: true,
+		},
+		{
+			name: "unknown acr",
+			wm: &SessionWriteModel{
+				PasswordCheckedAt: testNow,
+				TOTPCheckedAt:     testNow,
+			},
+			acr:  "unknown",
+			want: false,
+		},
+		{
+			name: "single factor, password only",
+			wm: &SessionWriteModel{
+				PasswordCheckedAt: testNow,
+			},
+			acr:  domain.ACRInCommonIAPBronze,
+			want: true,
+		},
+		{
+			name: "single factor, no checks",
+			wm:   &SessionWriteModel{},
+			acr:  domain.ACRInCommonIAPBronze,
+			want: false,
+		},
+		{
+			name: "mfa, password only",
+			wm: &SessionWriteModel{
+				PasswordCheckedAt: testNow,
+			},
+			acr:  domain.ACRInCommonIAPSilver,
+			want: false,
+		},
+		{
+			name: "mfa, password and totp",
+			wm: &SessionWriteModel{
+				PasswordCheckedAt: testNow,
+				TOTPCheckedAt:     testNow,
+			},
+			acr:  domain.ACRInCommonIAPSilver,
+			want: true,
+		},
+		{
+			name: "mfa, passwordless",
+			wm: &SessionWriteModel{
+				WebAuthNCheckedAt:      testNow,
+				WebAuthNUserVerified:   true,
+				WebAuthNIsPasswordless: true,
+			},
+			acr:  domain.ACRPAPEMultiFactor,
+			want: true,
+		},
+		{
+			name: "mfa physical, password and totp",
+			wm: &SessionWriteModel{
+				PasswordCheckedAt: testNow,
+				TOTPCheckedAt:     testNow,
+			},
+			acr:  domain.ACRPAPEMultiFactorPhysical,
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.wm.SatisfiesACR(tt.acr))
+		})
+	}
+}
+
+func TestSessionWriteModel_FactorFreshWithin(t *testing.T) {
+	tests := []struct {
+		name   string
+		wm     *SessionWriteModel
+		factor domain.UserAuthMethodType
+		maxAge time.Duration
+		want   bool
+	}{
+		{
+			name:   "never checked",
+			wm:     &SessionWriteModel{PasswordCheckedAt: testNow},
+			factor: domain.UserAuthMethodTypeTOTP,
+			maxAge: 2 * time.Minute,
+			want:   false,
+		},
+		{
+			name:   "fresh",
+			wm:     &SessionWriteModel{TOTPCheckedAt: testNow.Add(-time.Minute)},
+			factor: domain.UserAuthMethodTypeTOTP,
+			maxAge: 2 * time.Minute,
+			want:   true,
+		},
+		{
+			name:   "exactly max age",
+			wm:     &SessionWriteModel{TOTPCheckedAt: testNow.Add(-2 * time.Minute)},
+			factor: domain.UserAuthMethodTypeTOTP,
+			maxAge: 2 * time.Minute,
+			want:   true,
+		},
+		{
+			name:   "stale",
+			wm:     &SessionWriteModel{TOTPCheckedAt: testNow.Add(-time.Hour)},
+			factor: domain.UserAuthMethodTypeTOTP,
+			maxAge: 2 * time.Minute,
+			want:   false,
+		},
+		{
+			name: "other factor fresh",
+			wm: &SessionWriteModel{
+				PasswordCheckedAt: testNow,
+				TOTPCheckedAt:     testNow.Add(-time.Hour),
+			},
+			factor: domain.UserAuthMethodTypeTOTP,
+			maxAge: 2 * time.Minute,
+			want:   false,
+		},
+		{
+			name: "u2f fresh",
+			wm: &SessionWriteModel{
+				WebAuthNCheckedAt: testNow,
+			},
+			factor: domain.UserAuthMethodTypeU2F,
+			maxAge: 2 * time.Minute,
+			want:   true,
+		},
+		{
+			name: "passwordless checked, u2f required",
+			wm: &SessionWriteModel{
+				WebAuthNCheckedAt:      testNow,
+				WebAuthNIsPasswordless: true,
+				WebAuthNUserVerified:   true,
+			},
+			factor: domain.UserAuthMethodTypeU2F,
+			maxAge: 2 * time.Minute,
+			want:   false,
+		},
+		{
+			name: "passwordless fresh",
+			wm: &SessionWriteModel{
+				WebAuthNCheckedAt:      testNow,
+				WebAuthNIsPasswordless: true,
+				WebAuthNUserVerified:   true,
+			},
+			factor: domain.UserAuthMethodTypePasswordless,
+			maxAge: 2 * time.Minute,
+			want:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.wm.FactorFreshWithin(tt.factor, tt.maxAge, testNow))
+		})
+	}
+}
+
+func TestSessionWriteModel_reduceTerminate_userRemoved(t *testing.T) {
+	ctx := context.Background()
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	wm := NewSessionWriteModel("sessionID", "org1")
+	err := AppendAndReduce(wm,
+		session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+		session.NewUserCheckedEvent(ctx, sessionAggregate, "userID", testNow),
+	)
+	require.NoError(t, err)
+	require.Equal(t, domain.SessionStateActive, wm.State)
+
+	err = AppendAndReduce(wm, session.NewTerminateEvent(ctx, sessionAggregate, domain.SessionTerminationTypeUserRemoved))
+	require.NoError(t, err)
+	assert.Equal(t, domain.SessionStateTerminated, wm.State)
+	assert.Equal(t, domain.SessionTerminationTypeUserRemoved, wm.TerminationReason)
+}
+
+func TestSessionWriteModel_LatestAuthentication(t *testing.T) {
+	tests := []struct {
+		name       string
+		wm         *SessionWriteModel
+		wantFactor domain.UserAuthMethodType
+		wantTime   time.Time
+	}{
+		{
+			name:       "no checks",
+			wm:         &SessionWriteModel{},
+			wantFactor: domain.UserAuthMethodTypeUnspecified,
+			wantTime:   time.Time{},
+		},
+		{
+			name: "latest totp",
+			wm: &SessionWriteModel{
+				PasswordCheckedAt: testNow,
+				TOTPCheckedAt:     testNow.Add(time.Second),
+			},
+			wantFactor: domain.UserAuthMethodTypeTOTP,
+			wantTime:   testNow.Add(time.Second),
+		},
+		{
+			name: "latest password",
+			wm: &SessionWriteModel{
+				PasswordCheckedAt: testNow.Add(time.Second),
+				WebAuthNCheckedAt: testNow,
+			},
+			wantFactor: domain.UserAuthMethodTypePassword,
+			wantTime:   testNow.Add(time.Second),
+		},
+		{
+			name: "tie between password and u2f",
+			wm: &SessionWriteModel{
+				PasswordCheckedAt: testNow,
+				WebAuthNCheckedAt: testNow,
+			},
+			wantFactor: domain.UserAuthMethodTypeU2F,
+			wantTime:   testNow,
+		},
+		{
+			name: "tie between password and passwordless",
+			wm: &SessionWriteModel{
+				PasswordCheckedAt:      testNow,
+				WebAuthNCheckedAt:      testNow,
+				WebAuthNUserVerified:   true,
+				WebAuthNIsPasswordless: true,
+			},
+			wantFactor: domain.UserAuthMethodTypePasswordless,
+			wantTime:   testNow,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			factor, authTime := tt.wm.LatestAuthentication()
+			assert.Equal(t, tt.wantFactor, factor)
+			assert.Equal(t, tt.wantTime, authTime)
+			assert.Equal(t, tt.wantTime, tt.wm.AuthenticationTime())
+		})
+	}
+}
+
+func TestSessionWriteModel_IntentIDP(t *testing.T) {
+	ctx := context.Background()
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	wm := NewSessionWriteModel("sessionID", "org1")
+	idpID, ok := wm.IntentIDP()
+	assert.False(t, ok)
+	assert.Empty(t, idpID)
+
+	err := eventstoreExpect(t,
+		expectFilter(
+			eventFromEventPusher(session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil)),
+			eventFromEventPusher(session.NewUserCheckedEvent(ctx, sessionAggregate, "userID", testNow)),
+			eventFromEventPusher(session.NewIntentCheckedEvent(ctx, sessionAggregate, testNow, "idpID", domain.IDPIntentProtocolOIDC)),
+		),
+	).FilterToQueryReducer(ctx, wm)
+	require.NoError(t, err)
+	idpID, ok = wm.IntentIDP()
+	assert.True(t, ok)
+	assert.Equal(t, "idpID", idpID)
+	assert.Equal(t, "idpID", wm.Snapshot().IntentIDPID)
+	assert.Equal(t, "idpID", wm.snapshotState().IntentIDPID)
+}
+
+func TestSessionWriteModel_Reduce_afterTermination(t *testing.T) {
+	ctx := context.Background()
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	wm := NewSessionWriteModel("sessionID", "org1")
+	err := eventstoreExpect(t,
+		expectFilter(
+			eventFromEventPusher(session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil)),
+			eventFromEventPusher(session.NewUserCheckedEvent(ctx, sessionAggregate, "userID", testNow)),
+			eventFromEventPusher(session.NewTokenSetEvent(ctx, sessionAggregate, "tokenID")),
+			eventFromEventPusher(session.NewTerminateEvent(ctx, sessionAggregate, domain.SessionTerminationTypeLogout)),
+			eventFromEventPusher(session.NewPasswordCheckedEvent(ctx, sessionAggregate, testNow, "")),
+			eventFromEventPusher(session.NewTokenSetEvent(ctx, sessionAggregate, "tokenID2")),
+			eventFromEventPusher(session.NewTerminateEvent(ctx, sessionAggregate, domain.SessionTerminationTypeRevoked)),
+		),
+	).FilterToQueryReducer(ctx, wm)
+	require.NoError(t, err)
+	assert.Equal(t, domain.SessionStateTerminated, wm.State)
+	assert.Equal(t, domain.SessionTerminationTypeLogout, wm.TerminationReason)
+	assert.True(t, wm.PasswordCheckedAt.IsZero())
+	assert.Equal(t, "tokenID", wm.TokenID)
+	assert.Empty(t, wm.AuthMethodTypes())
+}
+
+func TestSessionWriteModel_reduceTokenSet(t *testing.T) {
+	ctx := context.Background()
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	wm := NewSessionWriteModel("sessionID", "org1")
+	err := eventstoreExpect(t,
+		expectFilter(
+			eventFromEventPusher(session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil)),
+			eventFromEventPusher(session.NewTokenSetEvent(ctx, sessionAggregate, "tokenID1")),
+		),
+	).FilterToQueryReducer(ctx, wm)
+	require.NoError(t, err)
+	assert.Equal(t, "tokenID1", wm.TokenID)
+	assert.Empty(t, wm.PreviousTokenID)
+	assert.False(t, wm.TokenRevoked("tokenID1"))
+
+	err = AppendAndReduce(wm, session.NewTokenSetEvent(ctx, sessionAggregate, "tokenID2"))
+	require.NoError(t, err)
+	assert.Equal(t, "tokenID2", wm.TokenID)
+	assert.Equal(t, "tokenID1", wm.PreviousTokenID)
+	assert.True(t, wm.TokenRevoked("tokenID1"))
+	assert.False(t, wm.TokenRevoked("tokenID2"))
+
+	err = AppendAndReduce(wm, session.NewTokenSetEvent(ctx, sessionAggregate, "tokenID3"))
+	require.NoError(t, err)
+	assert.Equal(t, "tokenID2", wm.PreviousTokenID)
+	assert.Equal(t, []string{"tokenID1", "tokenID2"}, wm.RevokedTokenIDs)
+}
+
+func TestSessionWriteModel_MarshalJSON(t *testing.T) {
+	ctx := context.Background()
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	checkedAt := time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC)
+	wm := NewSessionWriteModel("sessionID", "org1")
+	err := AppendAndReduce(wm,
+		session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+		session.NewUserCheckedEvent(ctx, sessionAggregate, "userID", checkedAt),
+		session.NewPasswordCheckedEvent(ctx, sessionAggregate, checkedAt, ""),
+	)
+	require.NoError(t, err)
+
+	data, err := json.Marshal(wm)
+	require.NoError(t, err)
+	var got map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &got))
+	assert.Equal(t, "sessionID", got["id"])
+	assert.Equal(t, "userID", got["userID"])
+	assert.Equal(t, "2023-07-01T12:00:00Z", got["passwordCheckedAt"])
+	assert.Equal(t, []interface{}{float64(domain.UserAuthMethodTypePassword)}, got["authMethodTypes"])
+	assert.NotContains(t, got, "totpCheckedAt")
+	assert.NotContains(t, got, "aggregate")
+	assert.NotContains(t, got, "Events")
+	assert.NotContains(t, got, "WriteModel")
+}
+
+func TestSessionWriteModel_Reset(t *testing.T) {
+	ctx := context.Background()
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	wm := NewSessionWriteModel("sessionID", "org1")
+	err := AppendAndReduce(wm,
+		session.NewAddedEvent(ctx, sessionAggregate, time.Hour, "agentID", "", "", nil),
+		session.NewUserCheckedEvent(ctx, sessionAggregate, "userID", testNow),
+		session.NewPasswordCheckedEvent(ctx, sessionAggregate, testNow, ""),
+		session.NewTokenSetEvent(ctx, sessionAggregate, "tokenID"),
+		session.NewMetadataSetEvent(ctx, sessionAggregate, map[string][]byte{"key": []byte("value")}, nil),
+		session.NewWebAuthNChallengedEvent(ctx, sessionAggregate, "challenge", nil, nil, domain.UserVerificationRequirementRequired, "rpid", testNow.Add(time.Minute)),
+		session.NewTerminateEvent(ctx, sessionAggregate, domain.SessionTerminationTypeLogout),
+	)
+	require.NoError(t, err)
+
+	wm.Reset("sessionID2", "org2")
+	fresh := NewSessionWriteModel("sessionID2", "org2")
+	assert.Len(t, wm.Events, 0)
+	wm.Events = fresh.Events
+	assert.Equal(t, fresh, wm)
+
+	// the reset model must reduce the same way as a new one
+	sessionAggregate2 := &session.NewAggregate("sessionID2", "org2").Aggregate
+	events := []eventstore.Event{
+		session.NewAddedEvent(ctx, sessionAggregate2, 0, "", "", "", nil),
+		session.NewUserCheckedEvent(ctx, sessionAggregate2, "userID2", testNow),
+	}
+	require.NoError(t, AppendAndReduce(wm, events...))
+	require.NoError(t, AppendAndReduce(fresh, events...))
+	assert.Equal(t, fresh, wm)
+}
+
+func TestSessionWriteModel_Reset_keepsConfiguration(t *testing.T) {
+	ctx := context.Background()
+	configure := func(wm *SessionWriteModel) {
+		wm.MetadataLimits = SessionMetadataLimits{MaxEntries: 1}
+		wm.ScopeAuthLevels = map[string]domain.AuthLevel{"admin": domain.AuthLevel2}
+		wm.CheckHistoryLimit = 1
+	}
+	wm := NewSessionWriteModel("sessionID", "org1")
+	configure(wm)
+	err := AppendAndReduce(wm,
+		session.NewAddedEvent(ctx, &session.NewAggregate("sessionID", "org1").Aggregate, 0, "", "", "", nil),
+	)
+	require.NoError(t, err)
+
+	wm.Reset("sessionID2", "org2")
+	fresh := NewSessionWriteModel("sessionID2", "org2")
+	configure(fresh)
+	wm.Events = fresh.Events
+	assert.Equal(t, fresh, wm)
+
+	// the reset model must behave the same way as a new one configured the same way
+	sessionAggregate2 := &session.NewAggregate("sessionID2", "org2").Aggregate
+	events := []eventstore.Event{
+		session.NewAddedEvent(ctx, sessionAggregate2, 0, "", "", "", nil),
+		session.NewUserCheckedEvent(ctx, sessionAggregate2, "userID", testNow),
+		session.NewPasswordCheckedEvent(ctx, sessionAggregate2, testNow, ""),
+	}
+	require.NoError(t, AppendAndReduce(wm, events...))
+	require.NoError(t, AppendAndReduce(fresh, events...))
+	assert.Equal(t, fresh, wm)
+	assert.Len(t, wm.CheckHistory, 1)
+	require.NoError(t, wm.SetMetadata("key1", []byte("value")))
+	assert.Error(t, wm.SetMetadata("key2", []byte("value")))
+}
+
+type countingReduceObserver struct {
+	events          []string
+	authMethodTypes map[string][]domain.UserAuthMethodType
+}
+
+func (o *countingReduceObserver) OnEvent(eventType string, wm *SessionWriteModel) {
+	o.events = append(o.events, eventType)
+	o.authMethodTypes[eventType] = wm.AuthMethodTypes()
+}
+
+func TestSessionWriteModel_ReduceObserver(t *testing.T) {
+	ctx := context.Background()
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	observer := &countingReduceObserver{authMethodTypes: make(map[string][]domain.UserAuthMethodType)}
+	wm := NewSessionWriteModel("sessionID", "org1")
+	wm.ReduceObserver = observer
+	err := AppendAndReduce(wm,
+		session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+		session.NewUserCheckedEvent(ctx, sessionAggregate, "userID", testNow),
+		session.NewPasswordCheckedEvent(ctx, sessionAggregate, testNow, ""),
+		session.NewTerminateEvent(ctx, sessionAggregate, domain.SessionTerminationTypeLogout),
+		// events stored after the termination are not applied
+		session.NewTOTPCheckedEvent(ctx, sessionAggregate, testNow, "", 0),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		string(session.AddedType),
+		string(session.UserCheckedType),
+		string(session.PasswordCheckedType),
+		string(session.TerminateType),
+	}, observer.events)
+	// the observer is called after the event was applied
+	assert.Equal(t, []domain.UserAuthMethodType{domain.UserAuthMethodTypePassword}, observer.authMethodTypes[string(session.PasswordCheckedType)])
+
+	// the observer is kept on reset
+	wm.Reset("sessionID2", "org1")
+	assert.Equal(t, observer, wm.ReduceObserver)
+}
+
+func TestSessionWriteModel_reduceRecoveryCodeChecked(t *testing.T) {
+	ctx := context.Background()
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	wm := NewSessionWriteModel("sessionID", "org1")
+	err := AppendAndReduce(wm,
+		session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+		session.NewUserCheckedEvent(ctx, sessionAggregate, "userID", testNow),
+		session.NewPasswordCheckedEvent(ctx, sessionAggregate, testNow, ""),
+		session.NewRecoveryCodeCheckedEvent(ctx, sessionAggregate, testNow.Add(time.Minute)),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, testNow.Add(time.Minute), wm.RecoveryCodeCheckedAt)
+	assert.Equal(t, []domain.UserAuthMethodType{domain.UserAuthMethodTypePassword, domain.UserAuthMethodTypeRecoveryCode}, wm.AuthMethodTypes())
+	assert.Equal(t, testNow.Add(time.Minute), wm.AuthenticationTime())
+	assert.Equal(t, domain.AuthLevel2, wm.AuthenticationAssuranceLevel())
+}
+
+func TestSessionWriteModel_CheckHistory(t *testing.T) {
+	ctx := context.Background()
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	t.Run("multiple password checks", func(t *testing.T) {
+		wm := NewSessionWriteModel("sessionID", "org1")
+		err := AppendAndReduce(wm,
+			session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+			session.NewUserCheckedEvent(ctx, sessionAggregate, "userID", testNow),
+			session.NewPasswordCheckedEvent(ctx, sessionAggregate, testNow, ""),
+			session.NewPasswordCheckFailedEvent(ctx, sessionAggregate, testNow.Add(time.Minute)),
+			session.NewPasswordCheckedEvent(ctx, sessionAggregate, testNow.Add(2*time.Minute), ""),
+		)
+		require.NoError(t, err)
+		assert.Equal(t, testNow.Add(2*time.Minute), wm.PasswordCheckedAt)
+		assert.Equal(t, []FactorCheck{
+			{Factor: domain.UserAuthMethodTypePassword, CheckedAt: testNow, Succeeded: true},
+			{Factor: domain.UserAuthMethodTypePassword, CheckedAt: testNow.Add(time.Minute), Succeeded: false},
+			{Factor: domain.UserAuthMethodTypePassword, CheckedAt: testNow.Add(2 * time.Minute), Succeeded: true},
+		}, wm.CheckHistory)
+	})
+	t.Run("limited", func(t *testing.T) {
+		wm := NewSessionWriteModel("sessionID", "org1")
+		wm.CheckHistoryLimit = 2
+		err := AppendAndReduce(wm,
+			session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+			session.NewPasswordCheckedEvent(ctx, sessionAggregate, testNow, ""),
+			session.NewTOTPCheckedEvent(ctx, sessionAggregate, testNow.Add(time.Minute), "", 0),
+			session.NewOTPSMSCheckedEvent(ctx, sessionAggregate, testNow.Add(2*time.Minute), 0),
+		)
+		require.NoError(t, err)
+		assert.Equal(t, []FactorCheck{
+			{Factor: domain.UserAuthMethodTypeTOTP, CheckedAt: testNow.Add(time.Minute), Succeeded: true},
+			{Factor: domain.UserAuthMethodTypeOTPSMS, CheckedAt: testNow.Add(2 * time.Minute), Succeeded: true},
+		}, wm.CheckHistory)
+	})
+}
+
+func TestSessionWriteModel_IsMFACompleted(t *testing.T) {
+	tests := []struct {
+		name string
+		wm   *SessionWriteModel
+		want bool
+	}{
+		{
+			name: "no checks",
+			wm:   &SessionWriteModel{},
+			want: false,
+		},
+		{
+			name: "password only",
+			wm:   &SessionWriteModel{PasswordCheckedAt: testNow},
+			want: false,
+		},
+		{
+			name: "password and totp",
+			wm:   &SessionWriteModel{PasswordCheckedAt: testNow, TOTPCheckedAt: testNow},
+			want: true,
+		},
+		{
+			name: "password and u2f",
+			wm:   &SessionWriteModel{PasswordCheckedAt: testNow, WebAuthNCheckedAt: testNow},
+			want: true,
+		},
+		{
+			name: "totp and otp sms, both possession",
+			wm:   &SessionWriteModel{TOTPCheckedAt: testNow, OTPSMSCheckedAt: testNow},
+			want: false,
+		},
+		{
+			name: "passwordless only",
+			wm:   &SessionWriteModel{WebAuthNCheckedAt: testNow, WebAuthNIsPasswordless: true, WebAuthNUserVerified: true},
+			want: true,
+		},
+		{
+			name: "passwordless challenge, not user verified",
+			wm:   &SessionWriteModel{WebAuthNCheckedAt: testNow, WebAuthNIsPasswordless: true},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.wm.IsMFACompleted())
+		})
+	}
+}
+
+func TestSessionWriteModel_Reduce_resourceOwnerMismatch(t *testing.T) {
+	ctx := context.Background()
+	t.Run("mismatch", func(t *testing.T) {
+		wm := NewSessionWriteModel("sessionID", "org1")
+		err := AppendAndReduce(wm,
+			session.NewAddedEvent(ctx, &session.NewAggregate("sessionID", "org1").Aggregate, 0, "", "", "", nil),
+			session.NewUserCheckedEvent(ctx, &session.NewAggregate("sessionID", "org2").Aggregate, "userID", testNow),
+		)
+		require.ErrorIs(t, err, caos_errs.ThrowInternal(nil, "COMMAND-Ooy6u", "Errors.Session.ResourceOwnerMismatch"))
+		assert.Empty(t, wm.UserID)
+	})
+	t.Run("no resource owner set", func(t *testing.T) {
+		wm := NewSessionWriteModel("sessionID", "")
+		err := AppendAndReduce(wm,
+			session.NewAddedEvent(ctx, &session.NewAggregate("sessionID", "org2").Aggregate, 0, "", "", "", nil),
+			session.NewUserCheckedEvent(ctx, &session.NewAggregate("sessionID", "org2").Aggregate, "userID", testNow),
+		)
+		require.NoError(t, err)
+		assert.Equal(t, "userID", wm.UserID)
+	})
+}
+
+func TestSessionWriteModel_reduceLifetimeSet_extend(t *testing.T) {
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	start := time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC)
+	now := start.Add(2 * time.Hour)
+
+	wm := NewSessionWriteModel("sessionID", "org1")
+	err := eventstoreExpect(t,
+		expectFilter(
+			eventFromEventPusherWithCreationDate(session.NewAddedEvent(context.Background(), sessionAggregate, time.Hour, "", "", "", nil), start),
+		),
+	).FilterToQueryReducer(context.Background(), wm)
+	require.NoError(t, err)
+	assert.True(t, wm.IsExpired(now))
+
+	err = eventstoreExpect(t,
+		expectFilter(
+			eventFromEventPusherWithCreationDate(session.NewLifetimeSetEvent(context.Background(), sessionAggregate, 2*time.Hour, 0), start.Add(time.Hour)),
+		),
+	).FilterToQueryReducer(context.Background(), wm)
+	require.NoError(t, err)
+	assert.Equal(t, start.Add(3*time.Hour), wm.Expiration)
+	assert.False(t, wm.IsExpired(now))
+}
+
+func TestSessionWriteModel_reduceTOTPChecked_deviceID(t *testing.T) {
+	ctx := context.Background()
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	wm := NewSessionWriteModel("sessionID", "org1")
+	err := AppendAndReduce(wm,
+		session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+		session.NewTOTPCheckedEvent(ctx, sessionAggregate, testNow, "device1", 5),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "device1", wm.TOTPDeviceID)
+	assert.Equal(t, uint64(5), wm.TOTPLastStep)
+
+	// the device id must survive a snapshot
+	restored := NewSessionWriteModel("sessionID", "org1")
+	err = AppendAndReduce(restored, session.NewSnapshotEvent(ctx, sessionAggregate, wm.snapshotState()))
+	require.NoError(t, err)
+	assert.Equal(t, "device1", restored.TOTPDeviceID)
+	assert.Equal(t, uint64(5), restored.TOTPLastStep)
+}
+
+func TestSessionWriteModel_reduceUserChecked_userMismatch(t *testing.T) {
+	ctx := context.Background()
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	wm := NewSessionWriteModel("sessionID", "org1")
+	err := AppendAndReduce(wm,
+		session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+		session.NewUserCheckedEvent(ctx, sessionAggregate, "user1", testNow),
+		session.NewUserCheckedEvent(ctx, sessionAggregate, "user1", testNow),
+	)
+	require.NoError(t, err)
+	assert.False(t, wm.UserMismatch)
+
+	err = AppendAndReduce(wm, session.NewUserCheckedEvent(ctx, sessionAggregate, "user2", testNow))
+	require.NoError(t, err)
+	assert.True(t, wm.UserMismatch)
+	assert.Equal(t, "user2", wm.UserID)
+
+	// the anomaly must survive a snapshot
+	restored := NewSessionWriteModel("sessionID", "org1")
+	err = AppendAndReduce(restored, session.NewSnapshotEvent(ctx, sessionAggregate, wm.snapshotState()))
+	require.NoError(t, err)
+	assert.True(t, restored.UserMismatch)
+}
+
+func TestSessionWriteModel_reducePasswordChanged(t *testing.T) {
+	ctx := context.Background()
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	wm := NewSessionWriteModel("sessionID", "org1")
+	err := AppendAndReduce(wm,
+		session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+		session.NewUserCheckedEvent(ctx, sessionAggregate, "user1", testNow),
+		session.NewPasswordCheckedEvent(ctx, sessionAggregate, testNow, ""),
+		session.NewTOTPCheckedEvent(ctx, sessionAggregate, testNow, "", 0),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []domain.UserAuthMethodType{domain.UserAuthMethodTypeTOTP, domain.UserAuthMethodTypePassword}, wm.AuthMethodTypes())
+
+	changedAt := testNow.Add(time.Minute)
+	err = AppendAndReduce(wm, session.NewPasswordChangedEvent(ctx, sessionAggregate, changedAt))
+	require.NoError(t, err)
+	assert.True(t, wm.PasswordCheckedAt.IsZero())
+	assert.True(t, changedAt.Equal(wm.PasswordChangedAt))
+	assert.True(t, wm.SecurityLevelDegraded)
+	assert.Equal(t, []domain.UserAuthMethodType{domain.UserAuthMethodTypeTOTP}, wm.AuthMethodTypes())
+
+	// a check with the new password is valid again
+	err = AppendAndReduce(wm, session.NewPasswordCheckedEvent(ctx, sessionAggregate, changedAt.Add(time.Minute), ""))
+	require.NoError(t, err)
+	assert.Equal(t, []domain.UserAuthMethodType{domain.UserAuthMethodTypeTOTP, domain.UserAuthMethodTypePassword}, wm.AuthMethodTypes())
+
+	// a check at the exact time of a change is invalidated as well
+	err = AppendAndReduce(wm, session.NewPasswordChangedEvent(ctx, sessionAggregate, changedAt.Add(time.Minute)))
+	require.NoError(t, err)
+	assert.True(t, wm.PasswordCheckedAt.IsZero())
+}
+
+func TestSessionWriteModel_GetMetadata(t *testing.T) {
+	ctx := context.Background()
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	wm := NewSessionWriteModel("sessionID", "org1")
+	err := AppendAndReduce(wm,
+		session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+		session.NewMetadataSetEvent(ctx, sessionAggregate,
+			map[string][]byte{"key": []byte("value"), "temp": []byte("token")},
+			map[string]time.Time{"temp": testNow.Add(time.Minute), "removed": testNow.Add(time.Minute)},
+		),
+	)
+	require.NoError(t, err)
+	// expirations of keys not set are ignored
+	assert.Len(t, wm.MetadataExpirations, 1)
+
+	value, ok := wm.GetMetadata("temp", testNow)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("token"), value)
+	_, ok = wm.GetMetadata("temp", testNow.Add(2*time.Minute))
+	assert.False(t, ok)
+	_, ok = wm.GetMetadata("key", testNow.Add(2*time.Minute))
+	assert.True(t, ok)
+	_, ok = wm.GetMetadata("unknown", testNow)
+	assert.False(t, ok)
+
+	// the expirations must survive a snapshot
+	restored := NewSessionWriteModel("sessionID", "org1")
+	err = AppendAndReduce(restored, session.NewSnapshotEvent(ctx, sessionAggregate, wm.snapshotState()))
+	require.NoError(t, err)
+	_, ok = restored.GetMetadata("temp", testNow.Add(2*time.Minute))
+	assert.False(t, ok)
+
+	// setting the value without TTL removes the expiration
+	require.NoError(t, wm.SetMetadata("temp", []byte("token2")))
+	_, ok = wm.GetMetadata("temp", testNow.Add(2*time.Minute))
+	assert.True(t, ok)
+}
+
+func TestSessionWriteModel_CheckAuthMethodAllowed(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []domain.UserAuthMethodType
+		factor  domain.UserAuthMethodType
+		wantErr error
+	}{
+		{
+			name:   "no restriction",
+			factor: domain.UserAuthMethodTypeTOTP,
+		},
+		{
+			name:    "allowed",
+			allowed: []domain.UserAuthMethodType{domain.UserAuthMethodTypePassword, domain.UserAuthMethodTypeU2F},
+			factor:  domain.UserAuthMethodTypeU2F,
+		},
+		{
+			name:    "not allowed",
+			allowed: []domain.UserAuthMethodType{domain.UserAuthMethodTypePassword, domain.UserAuthMethodTypeU2F},
+			factor:  domain.UserAuthMethodTypeTOTP,
+			wantErr: ErrSessionAuthMethodNotAllowed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+			wm := NewSessionWriteModel("sessionID", "org1")
+			err := AppendAndReduce(wm, session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", tt.allowed))
+			require.NoError(t, err)
+			assert.ErrorIs(t, wm.CheckAuthMethodAllowed(tt.factor), tt.wantErr)
+		})
+	}
+}
+
+func TestSessionWriteModel_reduceAdded_resourceOwner(t *testing.T) {
+	ctx := context.Background()
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	wm := NewSessionWriteModel("sessionID", "")
+	err := AppendAndReduce(wm,
+		session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+		session.NewUserCheckedEvent(ctx, sessionAggregate, "userID", testNow),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "org1", wm.ResourceOwner)
+
+	// following events are scoped to the resource owner of the session
+	err = AppendAndReduce(wm, session.NewPasswordCheckedEvent(ctx, &session.NewAggregate("sessionID", "org2").Aggregate, testNow, ""))
+	require.Error(t, err)
+	assert.True(t, caos_errs.IsInternal(err))
+}
+
+func TestSessionWriteModel_ApplyEvents(t *testing.T) {
+	ctx := context.Background()
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	withSequence := func(event eventstore.Command, sequence uint64) *repository.Event {
+		e := eventFromEventPusherWithCreationDate(event, testNow)
+		e.Sequence = sequence
+		return e
+	}
+	es := eventstoreExpect(t,
+		expectFilter(
+			withSequence(session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil), 1),
+			withSequence(session.NewUserCheckedEvent(ctx, sessionAggregate, "userID", testNow), 2),
+			withSequence(session.NewPasswordCheckedEvent(ctx, sessionAggregate, testNow, ""), 3),
+		),
+		// the new events received by the subscription
+		expectFilter(
+			// already reduced, must not be reduced again
+			withSequence(session.NewPasswordCheckFailedEvent(ctx, sessionAggregate, testNow), 3),
+			withSequence(session.NewTOTPCheckedEvent(ctx, sessionAggregate, testNow, "deviceID", 1), 4),
+		),
+	)
+	wm := NewSessionWriteModel("sessionID", "org1")
+	require.NoError(t, es.FilterToQueryReducer(ctx, wm))
+	require.Equal(t, uint64(3), wm.ProcessedSequence)
+
+	events, err := es.Filter(ctx, wm.Query())
+	require.NoError(t, err)
+	require.NoError(t, wm.ApplyEvents(events))
+	assert.Equal(t, uint64(4), wm.ProcessedSequence)
+	assert.Equal(t, 0, wm.PasswordCheckFailures)
+	assert.True(t, testNow.Equal(wm.TOTPCheckedAt))
+	assert.Equal(t, "deviceID", wm.TOTPDeviceID)
+	assert.Equal(t, []domain.UserAuthMethodType{domain.UserAuthMethodTypeTOTP, domain.UserAuthMethodTypePassword}, wm.AuthMethodTypes())
+	assert.Empty(t, wm.Events)
+}
+
+func TestSessionWriteModel_reduceTOTPChecked_v1(t *testing.T) {
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	start := time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC)
+	v1Event := func(data string, creationDate time.Time) *repository.Event {
+		return &repository.Event{
+			Type:          repository.EventType(session.TOTPCheckedType),
+			Data:          []byte(data),
+			Version:       "v1",
+			CreationDate:  creationDate,
+			AggregateID:   "sessionID",
+			AggregateType: repository.AggregateType(session.AggregateType),
+			ResourceOwner: sql.NullString{String: "org1", Valid: true},
+		}
+	}
+	tests := []struct {
+		name          string
+		event         *repository.Event
+		wantCheckedAt time.Time
+	}{
+		{
+			name:          "without device id",
+			event:         v1Event(`{"checkedAt":"2023-07-01T12:01:00Z"}`, start.Add(2*time.Minute)),
+			wantCheckedAt: start.Add(time.Minute),
+		},
+		{
+			name:          "without payload",
+			event:         v1Event("", start.Add(2*time.Minute)),
+			wantCheckedAt: start.Add(2 * time.Minute),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wm := NewSessionWriteModel("sessionID", "org1")
+			err := eventstoreExpect(t, expectFilter(
+				eventFromEventPusherWithCreationDate(session.NewAddedEvent(context.Background(), sessionAggregate, 0, "", "", "", nil), start),
+				eventFromEventPusherWithCreationDate(session.NewLifetimeSetEvent(context.Background(), sessionAggregate, 0, 10*time.Minute), start),
+				tt.event,
+			)).FilterToQueryReducer(context.Background(), wm)
+			require.NoError(t, err)
+
+			assert.Equal(t, eventstore.Version("v1"), wm.SchemaVersion)
+			assert.Equal(t, tt.wantCheckedAt, wm.TOTPCheckedAt)
+			assert.Empty(t, wm.TOTPDeviceID)
+			assert.Equal(t, []domain.UserAuthMethodType{domain.UserAuthMethodTypeTOTP}, wm.AuthMethodTypes())
+			assert.Equal(t, tt.wantCheckedAt.Add(10*time.Minute), wm.IdleExpiration)
+		})
+	}
+}
+
+func TestSessionWriteModel_reduceWebAuthNChecked_extensionResults(t *testing.T) {
+	ctx := context.Background()
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	wm := NewSessionWriteModel("sessionID", "org1")
+	err := eventstoreExpect(t, expectFilter(
+		eventFromEventPusher(session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil)),
+		eventFromEventPusher(session.NewWebAuthNChallengedEvent(ctx, sessionAggregate, "challenge", nil, nil, domain.UserVerificationRequirementRequired, "example.com", testNow.Add(time.Minute))),
+		eventFromEventPusher(session.NewWebAuthNCheckedEvent(ctx, sessionAggregate, testNow, true, true, 0, "", "challenge",
+			map[string]interface{}{"credProps": map[string]interface{}{"rk": true}},
+		)),
+	)).FilterToQueryReducer(ctx, wm)
+	require.NoError(t, err)
+	residentKey, ok := wm.WebAuthNResidentKey()
+	assert.True(t, ok)
+	assert.True(t, residentKey)
+
+	// the extension results must survive a snapshot
+	restored := NewSessionWriteModel("sessionID", "org1")
+	err = AppendAndReduce(restored, session.NewSnapshotEvent(ctx, sessionAggregate, wm.snapshotState()))
+	require.NoError(t, err)
+	residentKey, ok = restored.WebAuthNResidentKey()
+	assert.True(t, ok)
+	assert.True(t, residentKey)
+
+	// not reported without the extension
+	_, ok = NewSessionWriteModel("sessionID", "org1").WebAuthNResidentKey()
+	assert.False(t, ok)
+}
+
+func TestSessionWriteModel_reduceWebAuthNChecked_noStepDown(t *testing.T) {
+	ctx := context.Background()
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	wm := NewSessionWriteModel("sessionID", "org1")
+	err := AppendAndReduce(wm,
+		session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+		session.NewWebAuthNChallengedEvent(ctx, sessionAggregate, "challenge1", nil, nil, domain.UserVerificationRequirementRequired, "example.com", time.Time{}),
+		session.NewWebAuthNCheckedEvent(ctx, sessionAggregate, testNow, true, true, 1, "", "challenge1", nil),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []domain.UserAuthMethodType{domain.UserAuthMethodTypePasswordless}, wm.AuthMethodTypes())
+
+	// a subsequent U2F check without user verification
+	checkedAt := testNow.Add(time.Minute)
+	err = AppendAndReduce(wm,
+		session.NewWebAuthNChallengedEvent(ctx, sessionAggregate, "challenge2", nil, nil, domain.UserVerificationRequirementDiscouraged, "example.com", time.Time{}),
+		session.NewWebAuthNCheckedEvent(ctx, sessionAggregate, checkedAt, false, true, 2, "", "challenge2", nil),
+	)
+	require.NoError(t, err)
+	assert.True(t, wm.WebAuthNUserVerified)
+	assert.True(t, wm.WebAuthNIsPasswordless)
+	assert.Equal(t, []domain.UserAuthMethodType{domain.UserAuthMethodTypePasswordless}, wm.AuthMethodTypes())
+	assert.True(t, checkedAt.Equal(wm.WebAuthNCheckedAt))
+	assert.Equal(t, uint32(2), wm.WebAuthNSignCount)
+	// the history records the actual factor of the check
+	assert.Equal(t, domain.UserAuthMethodTypeU2F, wm.CheckHistory[len(wm.CheckHistory)-1].Factor)
+
+	// only the invalidation of the factor clears the user verification
+	err = AppendAndReduce(wm, session.NewFactorInvalidatedEvent(ctx, sessionAggregate, domain.UserAuthMethodTypePasswordless))
+	require.NoError(t, err)
+	assert.False(t, wm.WebAuthNUserVerified)
+	assert.Empty(t, wm.AuthMethodTypes())
+}
+
+func TestSessionWriteModel_reduceIntentChecked_protocol(t *testing.T) {
+	ctx := context.Background()
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	wm := NewSessionWriteModel("sessionID", "org1")
+	err := AppendAndReduce(wm,
+		session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+		session.NewUserCheckedEvent(ctx, sessionAggregate, "userID", testNow),
+		session.NewIntentCheckedEvent(ctx, sessionAggregate, testNow, "idpID", domain.IDPIntentProtocolLDAP),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, domain.IDPIntentProtocolLDAP, wm.IntentProtocol)
+	assert.Equal(t, []domain.UserAuthMethodType{domain.UserAuthMethodTypeIDP}, wm.AuthMethodTypes())
+
+	// the protocol must survive a snapshot
+	restored := NewSessionWriteModel("sessionID", "org1")
+	err = AppendAndReduce(restored, session.NewSnapshotEvent(ctx, sessionAggregate, wm.snapshotState()))
+	require.NoError(t, err)
+	assert.Equal(t, domain.IDPIntentProtocolLDAP, restored.IntentProtocol)
+	assert.Equal(t, []domain.UserAuthMethodType{domain.UserAuthMethodTypeIDP}, restored.AuthMethodTypes())
+}
+
+func TestSessionWriteModel_reduceWebAuthNChallenged_transports(t *testing.T) {
+	ctx := context.Background()
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	credential1, credential2 := []byte("credential1"), []byte("credential2")
+	transports := map[string][]string{
+		base64.RawURLEncoding.EncodeToString(credential1): {"usb", "nfc"},
+		base64.RawURLEncoding.EncodeToString(credential2): {"internal"},
+	}
+	wm := NewSessionWriteModel("sessionID", "org1")
+	err := AppendAndReduce(wm,
+		session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+		session.NewWebAuthNChallengedEvent(ctx, sessionAggregate, "challenge", [][]byte{credential1, credential2}, transports, domain.UserVerificationRequirementRequired, "example.com", testNow),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"usb", "nfc"}, wm.WebAuthNChallenge.Transports(credential1))
+	assert.Equal(t, []string{"internal"}, wm.WebAuthNChallenge.Transports(credential2))
+	assert.Nil(t, wm.WebAuthNChallenge.Transports([]byte("unknown")))
+
+	// the transports must survive a clone and a snapshot
+	assert.Equal(t, []string{"usb", "nfc"}, wm.Clone().WebAuthNChallenge.Transports(credential1))
+	restored := NewSessionWriteModel("sessionID", "org1")
+	err = AppendAndReduce(restored, session.NewSnapshotEvent(ctx, sessionAggregate, wm.snapshotState()))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"usb", "nfc"}, restored.WebAuthNChallenge.Transports(credential1))
+	assert.Equal(t, []string{"internal"}, restored.WebAuthNChallenge.Transports(credential2))
+}
+
+func TestSessionWriteModel_reduceMetadataBulkSet(t *testing.T) {
+	ctx := context.Background()
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	t.Run("merge five keys", func(t *testing.T) {
+		wm := NewSessionWriteModel("sessionID", "org1")
+		err := AppendAndReduce(wm,
+			session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+			session.NewMetadataSetEvent(ctx, sessionAggregate, map[string][]byte{"existing": []byte("value"), "key1": []byte("old")}, nil),
+			session.NewMetadataBulkSetEvent(ctx, sessionAggregate, map[string][]byte{
+				"key1": []byte("value1"),
+				"key2": []byte("value2"),
+				"key3": []byte("value3"),
+				"key4": []byte("value4"),
+				"key5": []byte("value5"),
+			}),
+		)
+		require.NoError(t, err)
+		assert.Equal(t, map[string][]byte{
+			"existing": []byte("value"),
+			"key1":     []byte("value1"),
+			"key2":     []byte("value2"),
+			"key3":     []byte("value3"),
+			"key4":     []byte("value4"),
+			"key5":     []byte("value5"),
+		}, wm.Metadata)
+	})
+	t.Run("exceeding budget", func(t *testing.T) {
+		wm := NewSessionWriteModel("sessionID", "org1")
+		wm.MetadataLimits = SessionMetadataLimits{MaxTotalSize: 20}
+		// stored events must always be reduced
+		err := AppendAndReduce(wm,
+			session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+			session.NewMetadataSetEvent(ctx, sessionAggregate, map[string][]byte{"existing": []byte("value")}, nil),
+			session.NewMetadataBulkSetEvent(ctx, sessionAggregate, map[string][]byte{"key1": []byte("value1")}),
+		)
+		require.NoError(t, err)
+		assert.Equal(t, map[string][]byte{"existing": []byte("value"), "key1": []byte("value1")}, wm.Metadata)
+
+		// but the command validates the budget before the event is created
+		checks := &SessionCommands{sessionWriteModel: wm}
+		err = checks.SetMetadataBulk(ctx, map[string][]byte{"key2": []byte("value2")})
+		require.ErrorIs(t, err, caos_errs.ThrowInvalidArgument(nil, "COMMAND-Gae4u", "Errors.Session.Metadata.TooLarge"))
+		assert.Empty(t, checks.eventCommands)
+	})
+}
+
+func TestSessionWriteModel_reduceForked(t *testing.T) {
+	ctx := context.Background()
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	tests := []struct {
+		name      string
+		inherited []domain.UserAuthMethodType
+		want      []domain.UserAuthMethodType
+	}{
+		{
+			name:      "without inheritance",
+			inherited: nil,
+			want:      []domain.UserAuthMethodType{domain.UserAuthMethodTypePassword},
+		},
+		{
+			name:      "inherited auth methods",
+			inherited: []domain.UserAuthMethodType{domain.UserAuthMethodTypeTOTP, domain.UserAuthMethodTypePassword},
+			want:      []domain.UserAuthMethodType{domain.UserAuthMethodTypeTOTP, domain.UserAuthMethodTypePassword},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wm := NewSessionWriteModel("sessionID", "org1")
+			err := AppendAndReduce(wm,
+				session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+				session.NewForkedEvent(ctx, sessionAggregate, "parentID", "delegator", tt.inherited),
+				session.NewUserCheckedEvent(ctx, sessionAggregate, "userID", testNow),
+				session.NewPasswordCheckedEvent(ctx, sessionAggregate, testNow, ""),
+			)
+			require.NoError(t, err)
+			assert.Equal(t, "parentID", wm.ParentSessionID)
+			assert.Equal(t, "delegator", wm.DelegatorUserID)
+			assert.Equal(t, tt.want, wm.AuthMethodTypes())
+		})
+	}
+}
+
+func TestSessionWriteModel_PasswordReentryRequired(t *testing.T) {
+	tests := []struct {
+		name string
+		wm   *SessionWriteModel
+		want bool
+	}{
+		{
+			name: "never checked",
+			wm:   &SessionWriteModel{TOTPCheckedAt: testNow},
+			want: true,
+		},
+		{
+			name: "fresh",
+			wm:   &SessionWriteModel{PasswordCheckedAt: testNow.Add(-time.Minute)},
+			want: false,
+		},
+		{
+			name: "stale, other factor fresh",
+			wm:   &SessionWriteModel{PasswordCheckedAt: testNow.Add(-time.Hour), TOTPCheckedAt: testNow},
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.wm.PasswordReentryRequired(5*time.Minute, testNow))
+		})
+	}
+}
+
+func TestSessionWriteModel_UserCheckStale(t *testing.T) {
+	tests := []struct {
+		name string
+		wm   *SessionWriteModel
+		want bool
+	}{
+		{
+			name: "never checked",
+			wm:   &SessionWriteModel{},
+			want: true,
+		},
+		{
+			name: "fresh",
+			wm:   &SessionWriteModel{UserCheckedAt: testNow.Add(-time.Minute)},
+			want: false,
+		},
+		{
+			name: "exactly max age",
+			wm:   &SessionWriteModel{UserCheckedAt: testNow.Add(-5 * time.Minute)},
+			want: false,
+		},
+		{
+			name: "stale, factor fresh",
+			wm:   &SessionWriteModel{UserCheckedAt: testNow.Add(-time.Hour), PasswordCheckedAt: testNow},
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.wm.UserCheckStale(5*time.Minute, testNow))
+		})
+	}
+}
+
+func TestSessionWriteModel_ScopeUpgradeRequiresReauth(t *testing.T) {
+	scopeAuthLevels := map[string]domain.AuthLevel{
+		"admin":   domain.AuthLevel2,
+		"profile": domain.AuthLevel1,
+	}
+	tests := []struct {
+		name      string
+		wm        *SessionWriteModel
+		current   []string
+		requested []string
+		want      bool
+	}{
+		{
+			name:      "unmapped scope",
+			wm:        &SessionWriteModel{PasswordCheckedAt: testNow, ScopeAuthLevels: scopeAuthLevels},
+			current:   []string{"openid"},
+			requested: []string{"openid", "email"},
+			want:      false,
+		},
+		{
+			name:      "level reached",
+			wm:        &SessionWriteModel{PasswordCheckedAt: testNow, ScopeAuthLevels: scopeAuthLevels},
+			current:   []string{"openid"},
+			requested: []string{"openid", "profile"},
+			want:      false,
+		},
+		{
+			name:      "admin scope on AAL1 session",
+			wm:        &SessionWriteModel{PasswordCheckedAt: testNow, ScopeAuthLevels: scopeAuthLevels},
+			current:   []string{"openid"},
+			requested: []string{"openid", "admin"},
+			want:      true,
+		},
+		{
+			name:      "admin scope on AAL2 session",
+			wm:        &SessionWriteModel{PasswordCheckedAt: testNow, TOTPCheckedAt: testNow, ScopeAuthLevels: scopeAuthLevels},
+			current:   []string{"openid"},
+			requested: []string{"openid", "admin"},
+			want:      false,
+		},
+		{
+			name:      "admin scope already granted",
+			wm:        &SessionWriteModel{PasswordCheckedAt: testNow, ScopeAuthLevels: scopeAuthLevels},
+			current:   []string{"openid", "admin"},
+			requested: []string{"admin"},
+			want:      false,
+		},
+		{
+			name:      "no mapping",
+			wm:        &SessionWriteModel{PasswordCheckedAt: testNow},
+			current:   []string{"openid"},
+			requested: []string{"openid", "admin"},
+			want:      false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.wm.ScopeUpgradeRequiresReauth(tt.current, tt.requested))
+		})
+	}
+}
+
+func TestSessionWriteModel_AuthStateEqual(t *testing.T) {
+	authTime := testNow.Truncate(time.Second)
+	tests := []struct {
+		name  string
+		wm    *SessionWriteModel
+		other *SessionWriteModel
+		want  bool
+	}{
+		{
+			name:  "same factors at the same times",
+			wm:    &SessionWriteModel{UserID: "user1", UserCheckedAt: authTime, PasswordCheckedAt: authTime, TOTPCheckedAt: authTime},
+			other: &SessionWriteModel{UserID: "user1", UserCheckedAt: authTime, PasswordCheckedAt: authTime, TOTPCheckedAt: authTime},
+			want:  true,
+		},
+		{
+			name:  "same second",
+			wm:    &SessionWriteModel{UserID: "user1", PasswordCheckedAt: authTime.Add(100 * time.Millisecond)},
+			other: &SessionWriteModel{UserID: "user1", PasswordCheckedAt: authTime.Add(900 * time.Millisecond)},
+			want:  true,
+		},
+		{
+			name:  "other user",
+			wm:    &SessionWriteModel{UserID: "user1", PasswordCheckedAt: authTime},
+			other: &SessionWriteModel{UserID: "user2", PasswordCheckedAt: authTime},
+			want:  false,
+		},
+		{
+			name:  "other factors",
+			wm:    &SessionWriteModel{UserID: "user1", PasswordCheckedAt: authTime, TOTPCheckedAt: authTime},
+			other: &SessionWriteModel{UserID: "user1", PasswordCheckedAt: authTime, OTPEmailCheckedAt: authTime},
+			want:  false,
+		},
+		{
+			name:  "additional factor",
+			wm:    &SessionWriteModel{UserID: "user1", PasswordCheckedAt: authTime},
+			other: &SessionWriteModel{UserID: "user1", PasswordCheckedAt: authTime, TOTPCheckedAt: authTime.Add(-time.Minute)},
+			want:  false,
+		},
+		{
+			name:  "other authentication time",
+			wm:    &SessionWriteModel{UserID: "user1", PasswordCheckedAt: authTime},
+			other: &SessionWriteModel{UserID: "user1", PasswordCheckedAt: authTime.Add(time.Second)},
+			want:  false,
+		},
+		{
+			name: "no other",
+			wm:   &SessionWriteModel{UserID: "user1", PasswordCheckedAt: authTime},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.wm.AuthStateEqual(tt.other))
+		})
+	}
+}
+
+func TestSessionWriteModel_CompletedFactorCount(t *testing.T) {
+	tests := []struct {
+		name string
+		wm   *SessionWriteModel
+		want int
+	}{
+		{
+			name: "no checks",
+			wm:   &SessionWriteModel{},
+			want: 0,
+		},
+		{
+			name: "password, totp and idp",
+			wm:   &SessionWriteModel{PasswordCheckedAt: testNow, TOTPCheckedAt: testNow, IntentCheckedAt: testNow},
+			want: 3,
+		},
+		{
+			name: "passwordless counted once",
+			wm:   &SessionWriteModel{WebAuthNCheckedAt: testNow, WebAuthNIsPasswordless: true, WebAuthNUserVerified: true},
+			want: 1,
+		},
+		{
+			name: "inherited, deduplicated",
+			wm: &SessionWriteModel{
+				PasswordCheckedAt: testNow,
+				InheritedAuthMethodTypes: []domain.UserAuthMethodType{
+					domain.UserAuthMethodTypePassword,
+					domain.UserAuthMethodTypeTOTP,
+					domain.UserAuthMethodTypeTOTP,
+				},
+			},
+			want: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.wm.CompletedFactorCount())
+			assert.Len(t, tt.wm.AuthMethodTypes(), tt.want)
+		})
+	}
+}
+
+func TestSessionWriteModel_IsDeviceTrusted(t *testing.T) {
+	ctx := context.Background()
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	tests := []struct {
+		name   string
+		events []eventstore.Event
+		want   bool
+	}{
+		{
+			name: "not trusted",
+			events: []eventstore.Event{
+				session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+			},
+			want: false,
+		},
+		{
+			name: "trusted, not expired",
+			events: []eventstore.Event{
+				session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+				session.NewDeviceTrustedEvent(ctx, sessionAggregate, testNow.Add(time.Hour)),
+			},
+			want: true,
+		},
+		{
+			name: "trusted, expired",
+			events: []eventstore.Event{
+				session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+				session.NewDeviceTrustedEvent(ctx, sessionAggregate, testNow.Add(-time.Hour)),
+			},
+			want: false,
+		},
+		{
+			name: "trusted without expiration",
+			events: []eventstore.Event{
+				session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+				session.NewDeviceTrustedEvent(ctx, sessionAggregate, time.Time{}),
+			},
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wm := NewSessionWriteModel("sessionID", "org1")
+			require.NoError(t, AppendAndReduce(wm, tt.events...))
+			assert.Equal(t, tt.want, wm.IsDeviceTrusted(testNow))
+		})
+	}
+}
+
+func TestSessionWriteModel_FirstAuthentication(t *testing.T) {
+	tests := []struct {
+		name string
+		wm   *SessionWriteModel
+		want time.Time
+	}{
+		{
+			name: "no checks",
+			wm:   &SessionWriteModel{UserCheckedAt: testNow},
+			want: time.Time{},
+		},
+		{
+			name: "spread checks",
+			wm: &SessionWriteModel{
+				UserCheckedAt:     testNow.Add(-time.Hour),
+				PasswordCheckedAt: testNow.Add(-10 * time.Minute),
+				TOTPCheckedAt:     testNow.Add(-5 * time.Minute),
+				WebAuthNCheckedAt: testNow,
+			},
+			want: testNow.Add(-10 * time.Minute),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.wm.FirstAuthentication())
+		})
+	}
+}
+
+func testSessionsForAuthMethodTypes(t testing.TB, count int) []*SessionWriteModel {
+	ctx := context.Background()
+	sessions := make([]*SessionWriteModel, count)
+	for i := range sessions {
+		sessionAggregate := &session.NewAggregate(fmt.Sprintf("sessionID%d", i), "org1").Aggregate
+		sessions[i] = NewSessionWriteModel(sessionAggregate.ID, "org1")
+		events := []eventstore.Event{
+			session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+			session.NewUserCheckedEvent(ctx, sessionAggregate, "userID", testNow),
+			session.NewTOTPCheckedEvent(ctx, sessionAggregate, testNow, "", 0),
+			session.NewPasswordCheckedEvent(ctx, sessionAggregate, testNow, ""),
+		}
+		if i%2 == 1 {
+			events = append(events, session.NewForkedEvent(ctx, sessionAggregate, "parentID", "", []domain.UserAuthMethodType{domain.UserAuthMethodTypeIDP, domain.UserAuthMethodTypePassword}))
+		}
+		require.NoError(t, AppendAndReduce(sessions[i], events...))
+	}
+	return sessions
+}
+
+func TestSessionsAuthMethodTypes(t *testing.T) {
+	sessions := testSessionsForAuthMethodTypes(t, 3)
+	got := SessionsAuthMethodTypes(sessions)
+	require.Len(t, got, len(sessions))
+	for i, wm := range sessions {
+		assert.Equal(t, wm.AuthMethodTypes(), got[i])
+	}
+	assert.Equal(t, []domain.UserAuthMethodType{domain.UserAuthMethodTypeTOTP, domain.UserAuthMethodTypePassword, domain.UserAuthMethodTypeIDP}, got[1])
+
+	// appending to one of the lists must not change the others
+	_ = append(got[0], domain.UserAuthMethodTypeOTPEmail)
+	assert.Equal(t, sessions[1].AuthMethodTypes(), got[1])
+
+	assert.Empty(t, SessionsAuthMethodTypes(nil))
+}
+
+func BenchmarkSessionsAuthMethodTypes(b *testing.B) {
+	sessions := testSessionsForAuthMethodTypes(b, 500)
+
+	b.Run("loop", func(b *testing.B) {
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			types := make([][]domain.UserAuthMethodType, len(sessions))
+			for i, wm := range sessions {
+				types[i] = wm.AuthMethodTypes()
+			}
+		}
+	})
+	b.Run("batched", func(b *testing.B) {
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			SessionsAuthMethodTypes(sessions)
+		}
+	})
+}
+
+func TestSessionWriteModel_AuthMethodTypesForPhone(t *testing.T) {
+	ctx := context.Background()
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	tests := []struct {
+		name                 string
+		phoneSequence        uint64
+		phoneChangedSequence uint64
+		wantCheckedFor       bool
+		want                 []domain.UserAuthMethodType
+	}{
+		{
+			name:                 "same phone",
+			phoneSequence:        10,
+			phoneChangedSequence: 10,
+			wantCheckedFor:       true,
+			want:                 []domain.UserAuthMethodType{domain.UserAuthMethodTypePassword, domain.UserAuthMethodTypeOTPSMS},
+		},
+		{
+			name:                 "changed phone",
+			phoneSequence:        10,
+			phoneChangedSequence: 15,
+			wantCheckedFor:       false,
+			want:                 []domain.UserAuthMethodType{domain.UserAuthMethodTypePassword},
+		},
+		{
+			name:                 "check without phone",
+			phoneSequence:        0,
+			phoneChangedSequence: 15,
+			wantCheckedFor:       true,
+			want:                 []domain.UserAuthMethodType{domain.UserAuthMethodTypePassword, domain.UserAuthMethodTypeOTPSMS},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wm := NewSessionWriteModel("sessionID", "org1")
+			err := AppendAndReduce(wm,
+				session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+				session.NewPasswordCheckedEvent(ctx, sessionAggregate, testNow, ""),
+				session.NewOTPSMSCheckedEvent(ctx, sessionAggregate, testNow, tt.phoneSequence),
+			)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantCheckedFor, wm.OTPSMSCheckedFor(tt.phoneChangedSequence))
+			assert.Equal(t, tt.want, wm.AuthMethodTypesForPhone(tt.phoneChangedSequence))
+			// the unfiltered auth method types are not affected
+			assert.Contains(t, wm.AuthMethodTypes(), domain.UserAuthMethodTypeOTPSMS)
+		})
+	}
+}
+
+func TestSessionWriteModel_ValidateSignCount(t *testing.T) {
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	tests := []struct {
+		name      string
+		signCount uint32
+		previous  uint32
+		wantErr   error
+	}{
+		{
+			name:      "increasing",
+			signCount: 5,
+			previous:  4,
+		},
+		{
+			name:      "equal (cloned)",
+			signCount: 4,
+			previous:  4,
+			wantErr:   caos_errs.ThrowPreconditionFailed(nil, "COMMAND-Eeth4", "Errors.Session.WebAuthN.SignCountInvalid"),
+		},
+		{
+			name:      "decreasing (cloned)",
+			signCount: 3,
+			previous:  4,
+			wantErr:   caos_errs.ThrowPreconditionFailed(nil, "COMMAND-Eeth4", "Errors.Session.WebAuthN.SignCountInvalid"),
+		},
+		{
+			name:      "zero counters (unsupported)",
+			signCount: 0,
+			previous:  0,
+		},
+		{
+			name:      "zero after non zero",
+			signCount: 0,
+			previous:  4,
+			wantErr:   caos_errs.ThrowPreconditionFailed(nil, "COMMAND-Eeth4", "Errors.Session.WebAuthN.SignCountInvalid"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wm := NewSessionWriteModel("sessionID", "org1")
+			err := AppendAndReduce(wm,
+				session.NewAddedEvent(context.Background(), sessionAggregate, 0, "", "", "", nil),
+				session.NewWebAuthNCheckedEvent(context.Background(), sessionAggregate, testNow, true, true, tt.signCount, "", "", nil),
+			)
+			require.NoError(t, err)
+			assert.Equal(t, tt.signCount, wm.WebAuthNSignCount)
+			assert.ErrorIs(t, wm.ValidateSignCount(tt.previous), tt.wantErr)
+		})
+	}
+}
+
+func TestSessionWriteModel_reduceTokenSet_revoked(t *testing.T) {
+	ctx := context.Background()
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	wm := NewSessionWriteModel("sessionID", "org1")
+	err := AppendAndReduce(wm,
+		session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+		session.NewUserCheckedEvent(ctx, sessionAggregate, "userID", testNow),
+		session.NewTokenSetEvent(ctx, sessionAggregate, "tokenID"),
+		session.NewTokenSetEvent(ctx, sessionAggregate, ""),
+	)
+	require.NoError(t, err)
+	assert.Empty(t, wm.TokenID)
+	assert.Equal(t, []string{"tokenID"}, wm.RevokedTokenIDs)
+	assert.Equal(t, domain.SessionStateActive, wm.State)
+}
+
+func TestSessionWriteModel_CheckActive(t *testing.T) {
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	start := time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		events  []*repository.Event
+		now     time.Time
+		wantErr error
+	}{
+		{
+			name:    "not existing",
+			now:     start,
+			wantErr: ErrSessionNotExisting,
+		},
+		{
+			name: "terminated",
+			events: []*repository.Event{
+				eventFromEventPusherWithCreationDate(session.NewAddedEvent(context.Background(), sessionAggregate, 0, "", "", "", nil), start),
+				eventFromEventPusherWithCreationDate(session.NewTerminateEvent(context.Background(), sessionAggregate, domain.SessionTerminationTypeLogout), start),
+			},
+			now:     start,
+			wantErr: ErrSessionTerminated,
+		},
+		{
+			name: "expired",
+			events: []*repository.Event{
+				eventFromEventPusherWithCreationDate(session.NewAddedEvent(context.Background(), sessionAggregate, time.Hour, "", "", "", nil), start),
+			},
+			now:     start.Add(2 * time.Hour),
+			wantErr: ErrSessionExpired,
+		},
+		{
+			name: "idle expired",
+			events: []*repository.Event{
+				eventFromEventPusherWithCreationDate(session.NewAddedEvent(context.Background(), sessionAggregate, 0, "", "", "", nil), start),
+				eventFromEventPusherWithCreationDate(session.NewLifetimeSetEvent(context.Background(), sessionAggregate, 0, 10*time.Minute), start),
+				eventFromEventPusherWithCreationDate(session.NewPasswordCheckedEvent(context.Background(), sessionAggregate, start.Add(time.Minute), ""), start.Add(time.Minute)),
+			},
+			now:     start.Add(15 * time.Minute),
+			wantErr: ErrSessionIdleExpired,
+		},
+		{
+			name: "active",
+			events: []*repository.Event{
+				eventFromEventPusherWithCreationDate(session.NewAddedEvent(context.Background(), sessionAggregate, time.Hour, "", "", "", nil), start),
+				eventFromEventPusherWithCreationDate(session.NewLifetimeSetEvent(context.Background(), sessionAggregate, 0, 10*time.Minute), start),
+				eventFromEventPusherWithCreationDate(session.NewPasswordCheckedEvent(context.Background(), sessionAggregate, start.Add(time.Minute), ""), start.Add(time.Minute)),
+			},
+			now: start.Add(5 * time.Minute),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wm := NewSessionWriteModel("sessionID", "org1")
+			err := eventstoreExpect(t, expectFilter(tt.events...)).FilterToQueryReducer(context.Background(), wm)
+			require.NoError(t, err)
+			err = wm.CheckActive(tt.now)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.wantErr)
+			for _, other := range []error{ErrSessionNotExisting, ErrSessionTerminated, ErrSessionExpired, ErrSessionIdleExpired} {
+				if other != tt.wantErr {
+					assert.NotErrorIs(t, err, other)
+				}
+			}
+		})
+	}
+}
+
+func TestSessionWriteModel_Diff(t *testing.T) {
+	ctx := context.Background()
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	wm := NewSessionWriteModel("sessionID", "org1")
+	err := AppendAndReduce(wm,
+		session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+		session.NewUserCheckedEvent(ctx, sessionAggregate, "userID", testNow),
+	)
+	require.NoError(t, err)
+	previous := wm.Clone()
+	assert.Empty(t, wm.Diff(previous))
+
+	err = AppendAndReduce(wm, session.NewPasswordCheckedEvent(ctx, sessionAggregate, testNow, ""))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"PasswordCheckedAt", "CheckHistory"}, wm.Diff(previous))
+
+	err = AppendAndReduce(wm, session.NewTerminateEvent(ctx, sessionAggregate, domain.SessionTerminationTypeLogout))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"PasswordCheckedAt", "State", "TerminationReason", "CheckHistory"}, wm.Diff(previous))
+}
+
+func TestSessionWriteModel_ActiveAuthMethodTypes(t *testing.T) {
+	ctx := context.Background()
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	now := time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC)
+	wm := NewSessionWriteModel("sessionID", "org1")
+	err := AppendAndReduce(wm,
+		session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+		session.NewTOTPCheckedEvent(ctx, sessionAggregate, now.Add(-2*time.Hour), "", 0),
+		session.NewPasswordCheckedEvent(ctx, sessionAggregate, now.Add(-5*time.Minute), ""),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []domain.UserAuthMethodType{domain.UserAuthMethodTypeTOTP, domain.UserAuthMethodTypePassword}, wm.AuthMethodTypes())
+	assert.Equal(t, []domain.UserAuthMethodType{domain.UserAuthMethodTypePassword}, wm.ActiveAuthMethodTypes(time.Hour, now))
+	assert.Empty(t, wm.ActiveAuthMethodTypes(time.Minute, now))
+}
+
+func TestSessionWriteModel_FactorEverChecked(t *testing.T) {
+	ctx := context.Background()
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	tests := []struct {
+		name         string
+		userVerified bool
+		events       []eventstore.Event
+		factor       domain.UserAuthMethodType
+		want         bool
+	}{
+		{
+			name:   "unused factor",
+			factor: domain.UserAuthMethodTypeTOTP,
+			want:   false,
+		},
+		{
+			name:   "password checked",
+			factor: domain.UserAuthMethodTypePassword,
+			want:   true,
+		},
+		{
+			name: "password invalidated",
+			events: []eventstore.Event{
+				session.NewFactorInvalidatedEvent(ctx, sessionAggregate, domain.UserAuthMethodTypePassword),
+			},
+			factor: domain.UserAuthMethodTypePassword,
+			want:   true,
+		},
+		{
+			name:         "passwordless with user verification",
+			userVerified: true,
+			factor:       domain.UserAuthMethodTypePasswordless,
+			want:         true,
+		},
+		{
+			name:         "not u2f with user verification",
+			userVerified: true,
+			factor:       domain.UserAuthMethodTypeU2F,
+			want:         false,
+		},
+		{
+			name:         "u2f without user verification",
+			userVerified: false,
+			factor:       domain.UserAuthMethodTypeU2F,
+			want:         true,
+		},
+		{
+			name:         "not passwordless without user verification",
+			userVerified: false,
+			factor:       domain.UserAuthMethodTypePasswordless,
+			want:         false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wm := NewSessionWriteModel("sessionID", "org1")
+			err := AppendAndReduce(wm, append([]eventstore.Event{
+				session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+				session.NewPasswordCheckedEvent(ctx, sessionAggregate, testNow, ""),
+				session.NewWebAuthNChallengedEvent(ctx, sessionAggregate, "challenge", nil, nil, domain.UserVerificationRequirementRequired, "example.com", testNow.Add(time.Minute)),
+				session.NewWebAuthNCheckedEvent(ctx, sessionAggregate, testNow, tt.userVerified, true, 0, "", "challenge", nil),
+			}, tt.events...)...)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, wm.FactorEverChecked(tt.factor))
+		})
+	}
+}
+
+func TestSessionWriteModel_AuthenticationTimeByEventOrder(t *testing.T) {
+	ctx := context.Background()
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	start := time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name                   string
+		events                 []eventstore.Event
+		wantAuthenticationTime time.Time
+		want                   time.Time
+	}{
+		{
+			name:                   "no checks",
+			wantAuthenticationTime: time.Time{},
+			want:                   time.Time{},
+		},
+		{
+			name: "ordered timestamps",
+			events: []eventstore.Event{
+				session.NewPasswordCheckedEvent(ctx, sessionAggregate, start, ""),
+				session.NewTOTPCheckedEvent(ctx, sessionAggregate, start.Add(5*time.Second), "", 0),
+			},
+			wantAuthenticationTime: start.Add(5 * time.Second),
+			want:                   start.Add(5 * time.Second),
+		},
+		{
+			name: "skewed timestamps",
+			events: []eventstore.Event{
+				// recorded by a node with a clock running ahead
+				session.NewPasswordCheckedEvent(ctx, sessionAggregate, start.Add(10*time.Second), ""),
+				session.NewTOTPCheckedEvent(ctx, sessionAggregate, start.Add(5*time.Second), "", 0),
+			},
+			wantAuthenticationTime: start.Add(10 * time.Second),
+			want:                   start.Add(5 * time.Second),
+		},
+		{
+			name: "skewed timestamps, latest check invalidated",
+			events: []eventstore.Event{
+				session.NewPasswordCheckedEvent(ctx, sessionAggregate, start.Add(10*time.Second), ""),
+				session.NewTOTPCheckedEvent(ctx, sessionAggregate, start.Add(5*time.Second), "", 0),
+				session.NewFactorInvalidatedEvent(ctx, sessionAggregate, domain.UserAuthMethodTypeTOTP),
+			},
+			wantAuthenticationTime: start.Add(10 * time.Second),
+			want:                   start.Add(10 * time.Second),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wm := NewSessionWriteModel("sessionID", "org1")
+			err := AppendAndReduce(wm, append([]eventstore.Event{
+				session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+				session.NewUserCheckedEvent(ctx, sessionAggregate, "userID", start),
+			}, tt.events...)...)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantAuthenticationTime, wm.AuthenticationTime())
+			assert.Equal(t, tt.want, wm.AuthenticationTimeByEventOrder())
+		})
+	}
+}
+
+func TestSessionWriteModel_TokenContext(t *testing.T) {
+	ctx := context.Background()
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	wm := NewSessionWriteModel("sessionID", "org1")
+	err := AppendAndReduce(wm,
+		session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+		session.NewUserCheckedEvent(ctx, sessionAggregate, "userID", testNow),
+		session.NewPasswordCheckedEvent(ctx, sessionAggregate, testNow.Add(time.Second), ""),
+		session.NewTOTPCheckedEvent(ctx, sessionAggregate, testNow.Add(2*time.Second), "", 0),
+	)
+	require.NoError(t, err)
+
+	got := wm.TokenContext()
+	assert.Equal(t, domain.SessionTokenContext{
+		UserID:          wm.UserID,
+		AuthTime:        wm.AuthenticationTime(),
+		AuthMethodTypes: wm.AuthMethodTypes(),
+		AuthLevel:       wm.AuthenticationAssuranceLevel(),
+	}, got)
+	assert.Equal(t, "userID", got.UserID)
+	assert.Equal(t, testNow.Add(2*time.Second), got.AuthTime)
+	assert.Equal(t, []domain.UserAuthMethodType{domain.UserAuthMethodTypeTOTP, domain.UserAuthMethodTypePassword}, got.AuthMethodTypes)
+	assert.Equal(t, domain.AuthLevel2, got.AuthLevel)
+}
+
+func TestSessionWriteModel_AuthMethodChecks(t *testing.T) {
+	ctx := context.Background()
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	passwordCheckedAt := time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC)
+	webAuthNCheckedAt := passwordCheckedAt.Add(time.Minute)
+	otpCheckedAt := passwordCheckedAt.Add(2 * time.Minute)
+	tests := []struct {
+		name             string
+		userVerification domain.UserVerificationRequirement
+		want             func(wm *SessionWriteModel) map[domain.UserAuthMethodType]time.Time
+	}{
+		{
+			name:             "u2f",
+			userVerification: domain.UserVerificationRequirementDiscouraged,
+			want: func(wm *SessionWriteModel) map[domain.UserAuthMethodType]time.Time {
+				return map[domain.UserAuthMethodType]time.Time{
+					domain.UserAuthMethodTypePassword: wm.PasswordCheckedAt,
+					domain.UserAuthMethodTypeU2F:      wm.WebAuthNCheckedAt,
+					domain.UserAuthMethodTypeOTPEmail: wm.OTPEmailCheckedAt,
+				}
+			},
+		},
+		{
+			name:             "passwordless",
+			userVerification: domain.UserVerificationRequirementRequired,
+			want: func(wm *SessionWriteModel) map[domain.UserAuthMethodType]time.Time {
+				return map[domain.UserAuthMethodType]time.Time{
+					domain.UserAuthMethodTypePassword:     wm.PasswordCheckedAt,
+					domain.UserAuthMethodTypePasswordless: wm.WebAuthNCheckedAt,
+					domain.UserAuthMethodTypeOTPEmail:     wm.OTPEmailCheckedAt,
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wm := NewSessionWriteModel("sessionID", "org1")
+			err := AppendAndReduce(wm,
+				session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+				session.NewPasswordCheckedEvent(ctx, sessionAggregate, passwordCheckedAt, ""),
+				session.NewWebAuthNChallengedEvent(ctx, sessionAggregate, "challenge", nil, nil, tt.userVerification, "example.com", webAuthNCheckedAt.Add(time.Minute)),
+				session.NewWebAuthNCheckedEvent(ctx, sessionAggregate, webAuthNCheckedAt, true, true, 0, "", "challenge", nil),
+				session.NewOTPEmailCheckedEvent(ctx, sessionAggregate, otpCheckedAt),
+			)
+			require.NoError(t, err)
+			got := wm.AuthMethodChecks()
+			assert.Equal(t, tt.want(wm), got)
+			assert.Equal(t, passwordCheckedAt, got[domain.UserAuthMethodTypePassword])
+			assert.Len(t, got, len(wm.AuthMethodTypes()))
+		})
+	}
+}
+
+func TestSessionWriteModel_IsRetriedCheck(t *testing.T) {
+	ctx := context.Background()
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	wm := NewSessionWriteModel("sessionID", "org1")
+	err := AppendAndReduce(wm,
+		session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+		session.NewPasswordCheckedEvent(ctx, sessionAggregate, testNow, "key"),
+	)
+	require.NoError(t, err)
+	assert.True(t, wm.IsRetriedCheck(domain.UserAuthMethodTypePassword, "key", testNow.Add(time.Minute)))
+	assert.False(t, wm.IsRetriedCheck(domain.UserAuthMethodTypePassword, "key", testNow.Add(idempotentCheckWindow+time.Second)))
+	assert.False(t, wm.IsRetriedCheck(domain.UserAuthMethodTypePassword, "other", testNow))
+	assert.False(t, wm.IsRetriedCheck(domain.UserAuthMethodTypePassword, "", testNow))
+	assert.False(t, wm.IsRetriedCheck(domain.UserAuthMethodTypeTOTP, "key", testNow))
+
+	// the idempotent checks must survive a snapshot
+	restored := NewSessionWriteModel("sessionID", "org1")
+	err = AppendAndReduce(restored, session.NewSnapshotEvent(ctx, sessionAggregate, wm.snapshotState()))
+	require.NoError(t, err)
+	assert.Equal(t, wm.IdempotentChecks, restored.IdempotentChecks)
+}
+
+func TestSessionWriteModel_State(t *testing.T) {
+	ctx := context.Background()
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	tests := []struct {
+		name   string
+		events []eventstore.Event
+		want   domain.SessionState
+	}{
+		{
+			name: "added, pending",
+			events: []eventstore.Event{
+				session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+			},
+			want: domain.SessionStatePending,
+		},
+		{
+			name: "token set, still pending",
+			events: []eventstore.Event{
+				session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+				session.NewTokenSetEvent(ctx, sessionAggregate, "tokenID"),
+			},
+			want: domain.SessionStatePending,
+		},
+		{
+			name: "user checked, active",
+			events: []eventstore.Event{
+				session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+				session.NewUserCheckedEvent(ctx, sessionAggregate, "userID", testNow),
+			},
+			want: domain.SessionStateActive,
+		},
+		{
+			name: "terminated while pending",
+			events: []eventstore.Event{
+				session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+				session.NewTerminateEvent(ctx, sessionAggregate, domain.SessionTerminationTypeLogout),
+			},
+			want: domain.SessionStateTerminated,
+		},
+		{
+			name: "user checked after termination, still terminated",
+			events: []eventstore.Event{
+				session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+				session.NewTerminateEvent(ctx, sessionAggregate, domain.SessionTerminationTypeLogout),
+				session.NewUserCheckedEvent(ctx, sessionAggregate, "userID", testNow),
+			},
+			want: domain.SessionStateTerminated,
+		},
+		{
+			name: "terminated while active",
+			events: []eventstore.Event{
+				session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+				session.NewUserCheckedEvent(ctx, sessionAggregate, "userID", testNow),
+				session.NewTerminateEvent(ctx, sessionAggregate, domain.SessionTerminationTypeLogout),
+			},
+			want: domain.SessionStateTerminated,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wm := NewSessionWriteModel("sessionID", "org1")
+			require.NoError(t, AppendAndReduce(wm, tt.events...))
+			assert.Equal(t, tt.want, wm.State)
+			assert.Equal(t, tt.want != domain.SessionStateTerminated, wm.State.IsOpen())
+		})
+	}
+}
+
+func TestSessionWriteModel_AuthenticationTimeFor(t *testing.T) {
+	ctx := context.Background()
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	start := time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC)
+	wm := NewSessionWriteModel("sessionID", "org1")
+	err := AppendAndReduce(wm,
+		session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+		session.NewUserCheckedEvent(ctx, sessionAggregate, "userID", start),
+		session.NewPasswordCheckedEvent(ctx, sessionAggregate, start.Add(time.Minute), ""),
+		session.NewTOTPCheckedEvent(ctx, sessionAggregate, start.Add(2*time.Minute), "", 0),
+		session.NewOTPEmailCheckedEvent(ctx, sessionAggregate, start.Add(3*time.Minute)),
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, start.Add(2*time.Minute), wm.AuthenticationTimeFor(domain.UserAuthMethodTypeTOTP))
+	assert.Equal(t, start.Add(2*time.Minute), wm.AuthenticationTimeFor(domain.UserAuthMethodTypePassword, domain.UserAuthMethodTypeTOTP))
+	assert.Equal(t, start.Add(3*time.Minute), wm.AuthenticationTimeFor())
+	assert.Equal(t, wm.AuthenticationTime(), wm.AuthenticationTimeFor())
+	assert.True(t, wm.AuthenticationTimeFor(domain.UserAuthMethodTypeU2F).IsZero())
+}
+
+func TestSessionWriteModel_WebAuthNAttestationFormat(t *testing.T) {
+	ctx := context.Background()
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	wm := NewSessionWriteModel("sessionID", "org1")
+	err := AppendAndReduce(wm,
+		session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+		session.NewWebAuthNChallengedEvent(ctx, sessionAggregate, "challenge", nil, nil, domain.UserVerificationRequirementRequired, "example.com", testNow.Add(time.Minute)),
+		session.NewWebAuthNCheckedEvent(ctx, sessionAggregate, testNow, true, true, 1, "packed", "challenge", nil),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "packed", wm.WebAuthNAttestationFormat)
+
+	// further events do not change the format
+	err = AppendAndReduce(wm, session.NewPasswordCheckedEvent(ctx, sessionAggregate, testNow, ""))
+	require.NoError(t, err)
+	assert.Equal(t, "packed", wm.WebAuthNAttestationFormat)
+
+	// the format must survive a snapshot
+	restored := NewSessionWriteModel("sessionID", "org1")
+	err = AppendAndReduce(restored, session.NewSnapshotEvent(ctx, sessionAggregate, wm.snapshotState()))
+	require.NoError(t, err)
+	assert.Equal(t, "packed", restored.WebAuthNAttestationFormat)
+}
+
+func TestSessionWriteModel_reduceFactorInvalidated(t *testing.T) {
+	ctx := context.Background()
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	wm := NewSessionWriteModel("sessionID", "org1")
+	err := AppendAndReduce(wm,
+		session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+		session.NewUserCheckedEvent(ctx, sessionAggregate, "userID", testNow),
+		session.NewPasswordCheckedEvent(ctx, sessionAggregate, testNow, ""),
+		session.NewTOTPCheckedEvent(ctx, sessionAggregate, testNow, "device1", 0),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []domain.UserAuthMethodType{domain.UserAuthMethodTypeTOTP, domain.UserAuthMethodTypePassword}, wm.AuthMethodTypes())
+	assert.False(t, wm.SecurityLevelDegraded)
+
+	// invalidating a factor, which was not checked, does not degrade the session
+	err = AppendAndReduce(wm, session.NewFactorInvalidatedEvent(ctx, sessionAggregate, domain.UserAuthMethodTypeU2F))
+	require.NoError(t, err)
+	assert.False(t, wm.SecurityLevelDegraded)
+
+	err = AppendAndReduce(wm, session.NewFactorInvalidatedEvent(ctx, sessionAggregate, domain.UserAuthMethodTypeTOTP))
+	require.NoError(t, err)
+	assert.Equal(t, []domain.UserAuthMethodType{domain.UserAuthMethodTypePassword}, wm.AuthMethodTypes())
+	assert.Equal(t, testNow, wm.PasswordCheckedAt)
+	assert.True(t, wm.TOTPCheckedAt.IsZero())
+	assert.Empty(t, wm.TOTPDeviceID)
+	assert.True(t, wm.SecurityLevelDegraded)
+
+	// the flag must survive a snapshot
+	restored := NewSessionWriteModel("sessionID", "org1")
+	err = AppendAndReduce(restored, session.NewSnapshotEvent(ctx, sessionAggregate, wm.snapshotState()))
+	require.NoError(t, err)
+	assert.True(t, restored.SecurityLevelDegraded)
+}
+
+func TestSessionWriteModel_reduceClientCertChecked(t *testing.T) {
+	ctx := context.Background()
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	checkedAt := testNow.Add(time.Minute)
+	wm := NewSessionWriteModel("sessionID", "org1")
+	err := AppendAndReduce(wm,
+		session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+		session.NewUserCheckedEvent(ctx, sessionAggregate, "userID", testNow),
+		session.NewPasswordCheckedEvent(ctx, sessionAggregate, testNow, ""),
+		session.NewClientCertCheckedEvent(ctx, sessionAggregate, checkedAt, "fingerprint"),
+	)
+	require.NoError(t, err)
+	assert.True(t, checkedAt.Equal(wm.ClientCertCheckedAt))
+	assert.Equal(t, "fingerprint", wm.ClientCertFingerprint)
+	assert.Equal(t, []domain.UserAuthMethodType{domain.UserAuthMethodTypePassword, domain.UserAuthMethodTypeClientCert}, wm.AuthMethodTypes())
+	assert.True(t, checkedAt.Equal(wm.AuthenticationTime()))
+	assert.True(t, wm.HasPossessionFactor())
+	assert.True(t, wm.IsMFACompleted())
+
+	// the check must survive a snapshot
+	restored := NewSessionWriteModel("sessionID", "org1")
+	err = AppendAndReduce(restored, session.NewSnapshotEvent(ctx, sessionAggregate, wm.snapshotState()))
+	require.NoError(t, err)
+	assert.True(t, checkedAt.Equal(restored.ClientCertCheckedAt))
+	assert.Equal(t, "fingerprint", restored.ClientCertFingerprint)
+
+	err = AppendAndReduce(wm, session.NewFactorInvalidatedEvent(ctx, sessionAggregate, domain.UserAuthMethodTypeClientCert))
+	require.NoError(t, err)
+	assert.True(t, wm.ClientCertCheckedAt.IsZero())
+	assert.Empty(t, wm.ClientCertFingerprint)
+	assert.Equal(t, []domain.UserAuthMethodType{domain.UserAuthMethodTypePassword}, wm.AuthMethodTypes())
+}
+
+func TestSessionWriteModel_HasKnowledgeFactor_HasPossessionFactor(t *testing.T) {
+	ctx := context.Background()
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	type res struct {
+		knowledge  bool
+		possession bool
+	}
+	tests := []struct {
+		name   string
+		events []eventstore.Event
+		res    res
+	}{
+		{
+			name: "no checks",
+			events: []eventstore.Event{
+				session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+				session.NewUserCheckedEvent(ctx, sessionAggregate, "userID", testNow),
+			},
+			res: res{},
+		},
+		{
+			name: "password only",
+			events: []eventstore.Event{
+				session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+				session.NewPasswordCheckedEvent(ctx, sessionAggregate, testNow, ""),
+			},
+			res: res{knowledge: true},
+		},
+		{
+			name: "passwordless only",
+			events: []eventstore.Event{
+				session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+				session.NewWebAuthNChallengedEvent(ctx, sessionAggregate, "challenge", nil, nil, domain.UserVerificationRequirementRequired, "example.com", testNow.Add(time.Minute)),
+				session.NewWebAuthNCheckedEvent(ctx, sessionAggregate, testNow, true, true, 0, "", "challenge", nil),
+			},
+			res: res{possession: true},
+		},
+		{
+			name: "passwordless and password",
+			events: []eventstore.Event{
+				session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+				session.NewPasswordCheckedEvent(ctx, sessionAggregate, testNow, ""),
+				session.NewWebAuthNChallengedEvent(ctx, sessionAggregate, "challenge", nil, nil, domain.UserVerificationRequirementRequired, "example.com", testNow.Add(time.Minute)),
+				session.NewWebAuthNCheckedEvent(ctx, sessionAggregate, testNow, true, true, 0, "", "challenge", nil),
+			},
+			res: res{knowledge: true, possession: true},
+		},
+		{
+			name: "totp",
+			events: []eventstore.Event{
+				session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+				session.NewTOTPCheckedEvent(ctx, sessionAggregate, testNow, "", 0),
+			},
+			res: res{knowledge: true, possession: true},
+		},
+		{
+			name: "intent only",
+			events: []eventstore.Event{
+				session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+				session.NewIntentCheckedEvent(ctx, sessionAggregate, testNow, "idpID", domain.IDPIntentProtocolOIDC),
+			},
+			res: res{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wm := NewSessionWriteModel("sessionID", "org1")
+			require.NoError(t, AppendAndReduce(wm, tt.events...))
+			assert.Equal(t, tt.res.knowledge, wm.HasKnowledgeFactor())
+			assert.Equal(t, tt.res.possession, wm.HasPossessionFactor())
+		})
+	}
+}
+
+func TestSessionWriteModel_CanIssueRefreshToken(t *testing.T) {
+	ctx := context.Background()
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	now := testNow.Add(10 * time.Minute)
+	tests := []struct {
+		name     string
+		events   []eventstore.Event
+		minLevel domain.AuthLevel
+		maxAge   time.Duration
+		want     bool
+		wantErr  error
+	}{
+		{
+			name:     "not existing",
+			minLevel: domain.AuthLevel1,
+			wantErr:  ErrSessionNotExisting,
+		},
+		{
+			name: "terminated",
+			events: []eventstore.Event{
+				session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+				session.NewUserCheckedEvent(ctx, sessionAggregate, "userID", testNow),
+				session.NewPasswordCheckedEvent(ctx, sessionAggregate, testNow, ""),
+				session.NewTerminateEvent(ctx, sessionAggregate, domain.SessionTerminationTypeLogout),
+			},
+			minLevel: domain.AuthLevel1,
+			wantErr:  ErrSessionTerminated,
+		},
+		{
+			name: "level insufficient",
+			events: []eventstore.Event{
+				session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+				session.NewUserCheckedEvent(ctx, sessionAggregate, "userID", testNow),
+				session.NewPasswordCheckedEvent(ctx, sessionAggregate, testNow, ""),
+			},
+			minLevel: domain.AuthLevel2,
+			wantErr:  caos_errs.ThrowPreconditionFailed(nil, "COMMAND-Iek4a", "Errors.Session.Token.AuthLevelInsufficient"),
+		},
+		{
+			name: "authentication too old",
+			events: []eventstore.Event{
+				session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+				session.NewUserCheckedEvent(ctx, sessionAggregate, "userID", testNow),
+				session.NewPasswordCheckedEvent(ctx, sessionAggregate, testNow, ""),
+			},
+			minLevel: domain.AuthLevel1,
+			maxAge:   5 * time.Minute,
+			wantErr:  caos_errs.ThrowPreconditionFailed(nil, "COMMAND-Va4ae", "Errors.Session.Token.AuthenticationTooOld"),
+		},
+		{
+			name: "ok",
+			events: []eventstore.Event{
+				session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+				session.NewUserCheckedEvent(ctx, sessionAggregate, "userID", testNow),
+				session.NewPasswordCheckedEvent(ctx, sessionAggregate, testNow, ""),
+				session.NewTOTPCheckedEvent(ctx, sessionAggregate, testNow, "", 0),
+			},
+			minLevel: domain.AuthLevel2,
+			maxAge:   time.Hour,
+			want:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wm := NewSessionWriteModel("sessionID", "org1")
+			require.NoError(t, AppendAndReduce(wm, tt.events...))
+			got, err := wm.CanIssueRefreshToken(tt.minLevel, tt.maxAge, now)
+			require.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSessionWriteModel_AuthFactorCategories(t *testing.T) {
+	ctx := context.Background()
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	tests := []struct {
+		name   string
+		events []eventstore.Event
+		want   []domain.AuthFactorCategory
+	}{
+		{
+			name: "no checks",
+			events: []eventstore.Event{
+				session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+				session.NewUserCheckedEvent(ctx, sessionAggregate, "userID", testNow),
+			},
+			want: nil,
+		},
+		{
+			name: "password and totp",
+			events: []eventstore.Event{
+				session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+				session.NewPasswordCheckedEvent(ctx, sessionAggregate, testNow, ""),
+				session.NewTOTPCheckedEvent(ctx, sessionAggregate, testNow, "", 0),
+			},
+			want: []domain.AuthFactorCategory{domain.AuthFactorCategoryKnowledge, domain.AuthFactorCategoryPossession},
+		},
+		{
+			name: "totp and otp email deduplicated",
+			events: []eventstore.Event{
+				session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+				session.NewTOTPCheckedEvent(ctx, sessionAggregate, testNow, "", 0),
+				session.NewOTPEmailCheckedEvent(ctx, sessionAggregate, testNow),
+			},
+			want: []domain.AuthFactorCategory{domain.AuthFactorCategoryPossession},
+		},
+		{
+			name: "passwordless",
+			events: []eventstore.Event{
+				session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+				session.NewWebAuthNChallengedEvent(ctx, sessionAggregate, "challenge", nil, nil, domain.UserVerificationRequirementRequired, "example.com", testNow.Add(time.Minute)),
+				session.NewWebAuthNCheckedEvent(ctx, sessionAggregate, testNow, true, true, 0, "", "challenge", nil),
+			},
+			want: []domain.AuthFactorCategory{domain.AuthFactorCategoryPossession, domain.AuthFactorCategoryInherence},
+		},
+		{
+			name: "intent only",
+			events: []eventstore.Event{
+				session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+				session.NewIntentCheckedEvent(ctx, sessionAggregate, testNow, "idpID", domain.IDPIntentProtocolOIDC),
+			},
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wm := NewSessionWriteModel("sessionID", "org1")
+			require.NoError(t, AppendAndReduce(wm, tt.events...))
+			assert.Equal(t, tt.want, wm.AuthFactorCategories())
+		})
+	}
+}
+
+func TestSessionWriteModel_reduceLocked_reduceUnlocked(t *testing.T) {
+	ctx := context.Background()
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	tests := []struct {
+		name   string
+		events []eventstore.Event
+		want   domain.SessionState
+	}{
+		{
+			name: "locked",
+			events: []eventstore.Event{
+				session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+				session.NewUserCheckedEvent(ctx, sessionAggregate, "userID", testNow),
+				session.NewLockedEvent(ctx, sessionAggregate),
+			},
+			want: domain.SessionStateLocked,
+		},
+		{
+			name: "unlocked active",
+			events: []eventstore.Event{
+				session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+				session.NewUserCheckedEvent(ctx, sessionAggregate, "userID", testNow),
+				session.NewLockedEvent(ctx, sessionAggregate),
+				session.NewUnlockedEvent(ctx, sessionAggregate),
+			},
+			want: domain.SessionStateActive,
+		},
+		{
+			name: "unlocked pending",
+			events: []eventstore.Event{
+				session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+				session.NewLockedEvent(ctx, sessionAggregate),
+				session.NewUnlockedEvent(ctx, sessionAggregate),
+			},
+			want: domain.SessionStatePending,
+		},
+		{
+			name: "terminated not locked",
+			events: []eventstore.Event{
+				session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+				session.NewTerminateEvent(ctx, sessionAggregate, domain.SessionTerminationTypeLogout),
+				session.NewLockedEvent(ctx, sessionAggregate),
+			},
+			want: domain.SessionStateTerminated,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wm := NewSessionWriteModel("sessionID", "org1")
+			require.NoError(t, AppendAndReduce(wm, tt.events...))
+			assert.Equal(t, tt.want, wm.State)
+			if tt.want == domain.SessionStateLocked {
+				assert.ErrorIs(t, wm.CheckActive(testNow), ErrSessionLocked)
+			}
+		})
+	}
+}
+
+func TestSessionWriteModel_DebugQuery(t *testing.T) {
+	wm := NewSessionWriteModel("sessionID", "org1")
+	query := wm.DebugQuery()
+
+	assert.Contains(t, query, string(session.AggregateType))
+	assert.Contains(t, query, "sessionID")
+	assert.Contains(t, query, "resource owner: org1")
+	for _, typ := range sessionWriteModelEventTypes() {
+		assert.Contains(t, query, string(typ))
+	}
+}
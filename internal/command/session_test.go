@@ -2,6 +2,7 @@ package command
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"testing"
 	"time"
@@ -14,12 +15,16 @@ import (
 
 	"github.com/zitadel/zitadel/internal/api/authz"
 	"github.com/zitadel/zitadel/internal/crypto"
+	"github.com/zitadel/zitadel/internal/database"
 	"github.com/zitadel/zitadel/internal/domain"
 	caos_errs "github.com/zitadel/zitadel/internal/errors"
 	"github.com/zitadel/zitadel/internal/eventstore"
+	"github.com/zitadel/zitadel/internal/eventstore/repository"
 	"github.com/zitadel/zitadel/internal/id"
 	"github.com/zitadel/zitadel/internal/id/mock"
+	rep_idp "github.com/zitadel/zitadel/internal/repository/idp"
 	"github.com/zitadel/zitadel/internal/repository/idpintent"
+	"github.com/zitadel/zitadel/internal/repository/instance"
 	"github.com/zitadel/zitadel/internal/repository/session"
 	"github.com/zitadel/zitadel/internal/repository/user"
 )
@@ -148,6 +153,7 @@ func TestCommands_CreateSession(t *testing.T) {
 		ctx      context.Context
 		checks   []SessionCommand
 		metadata map[string][]byte
+		lifetime time.Duration
 	}
 	type res struct {
 		want *SessionChanged
@@ -205,7 +211,40 @@ func TestCommands_CreateSession(t *testing.T) {
 				expectFilter(),
 				expectPush(
 					eventPusherToEvents(
-						session.NewAddedEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate),
+						session.NewAddedEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate, 0, "", "", "", nil),
+						session.NewTokenSetEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate,
+							"tokenID",
+						),
+					),
+				),
+			},
+			res{
+				want: &SessionChanged{
+					ObjectDetails: &domain.ObjectDetails{ResourceOwner: "org1"},
+					ID:            "sessionID",
+					NewToken:      "token",
+				},
+			},
+		},
+		{
+			"session with lifetime",
+			fields{
+				idGenerator: mock.NewIDGeneratorExpectIDs(t, "sessionID"),
+				tokenCreator: func(sessionID string) (string, string, error) {
+					return "tokenID",
+						"token",
+						nil
+				},
+			},
+			args{
+				ctx:      authz.NewMockContext("", "org1", ""),
+				lifetime: 24 * time.Hour,
+			},
+			[]expect{
+				expectFilter(),
+				expectPush(
+					eventPusherToEvents(
+						session.NewAddedEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate, 24*time.Hour, "", "", "", nil),
 						session.NewTokenSetEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate,
 							"tokenID",
 						),
@@ -229,7 +268,7 @@ func TestCommands_CreateSession(t *testing.T) {
 				idGenerator:         tt.fields.idGenerator,
 				sessionTokenCreator: tt.fields.tokenCreator,
 			}
-			got, err := c.CreateSession(tt.args.ctx, tt.args.checks, tt.args.metadata)
+			got, err := c.CreateSession(tt.args.ctx, tt.args.checks, tt.args.metadata, tt.args.lifetime, nil)
 			require.ErrorIs(t, err, tt.res.err)
 			assert.Equal(t, tt.res.want, got)
 		})
@@ -276,9 +315,10 @@ func TestCommands_UpdateSession(t *testing.T) {
 			"invalid session token",
 			fields{
 				eventstore: eventstoreExpect(t,
+					expectFilter(),
 					expectFilter(
 						eventFromEventPusher(
-							session.NewAddedEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate)),
+							session.NewAddedEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate, 0, "", "", "", nil)),
 						eventFromEventPusher(
 							session.NewTokenSetEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate,
 								"tokenID")),
@@ -301,9 +341,10 @@ func TestCommands_UpdateSession(t *testing.T) {
 			"no change",
 			fields{
 				eventstore: eventstoreExpect(t,
+					expectFilter(),
 					expectFilter(
 						eventFromEventPusher(
-							session.NewAddedEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate)),
+							session.NewAddedEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate, 0, "", "", "", nil)),
 						eventFromEventPusher(
 							session.NewTokenSetEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate,
 								"tokenID")),
@@ -385,12 +426,98 @@ func TestCommands_updateSession(t *testing.T) {
 				ctx: context.Background(),
 				checks: &SessionCommands{
 					sessionWriteModel: &SessionWriteModel{State: domain.SessionStateTerminated},
+					now: func() time.Time {
+						return testNow
+					},
 				},
 			},
 			res{
 				err: caos_errs.ThrowPreconditionFailed(nil, "COMAND-SAjeh", "Errors.Session.Terminated"),
 			},
 		},
+		{
+			"locked",
+			fields{
+				eventstore: eventstoreExpect(t),
+			},
+			args{
+				ctx: context.Background(),
+				checks: &SessionCommands{
+					sessionWriteModel: &SessionWriteModel{State: domain.SessionStateLocked},
+					now: func() time.Time {
+						return testNow
+					},
+				},
+			},
+			res{
+				err: ErrSessionLocked,
+			},
+		},
+		{
+			"expired, no token",
+			fields{
+				eventstore: eventstoreExpect(t),
+			},
+			args{
+				ctx: context.Background(),
+				checks: &SessionCommands{
+					sessionWriteModel: &SessionWriteModel{
+						WriteModel: eventstore.WriteModel{AggregateID: "sessionID", ResourceOwner: "org1"},
+						State:      domain.SessionStateActive,
+						Expiration: testNow.Add(-time.Minute),
+						aggregate:  &session.NewAggregate("sessionID", "org1").Aggregate,
+					},
+					sessionCommands: []SessionCommand{
+						func(ctx context.Context, cmd *SessionCommands) error {
+							cmd.PasswordChecked(ctx, testNow, "")
+							return nil
+						},
+					},
+					createToken: func(sessionID string) (string, string, error) {
+						return "tokenID", "token", nil
+					},
+					now: func() time.Time {
+						return testNow
+					},
+				},
+			},
+			res{
+				err: ErrSessionExpired,
+			},
+		},
+		{
+			"idle expired, no token",
+			fields{
+				eventstore: eventstoreExpect(t),
+			},
+			args{
+				ctx: context.Background(),
+				checks: &SessionCommands{
+					sessionWriteModel: &SessionWriteModel{
+						WriteModel:     eventstore.WriteModel{AggregateID: "sessionID", ResourceOwner: "org1"},
+						State:          domain.SessionStateActive,
+						IdleTimeout:    time.Minute,
+						IdleExpiration: testNow.Add(-time.Minute),
+						aggregate:      &session.NewAggregate("sessionID", "org1").Aggregate,
+					},
+					sessionCommands: []SessionCommand{
+						func(ctx context.Context, cmd *SessionCommands) error {
+							cmd.PasswordChecked(ctx, testNow, "")
+							return nil
+						},
+					},
+					createToken: func(sessionID string) (string, string, error) {
+						return "tokenID", "token", nil
+					},
+					now: func() time.Time {
+						return testNow
+					},
+				},
+			},
+			res{
+				err: ErrSessionIdleExpired,
+			},
+		},
 		{
 			"check failed",
 			fields{
@@ -405,6 +532,9 @@ func TestCommands_updateSession(t *testing.T) {
 							return caos_errs.ThrowInternal(nil, "id", "check failed")
 						},
 					},
+					now: func() time.Time {
+						return testNow
+					},
 				},
 			},
 			res{
@@ -421,6 +551,9 @@ func TestCommands_updateSession(t *testing.T) {
 				checks: &SessionCommands{
 					sessionWriteModel: NewSessionWriteModel("sessionID", "org1"),
 					sessionCommands:   []SessionCommand{},
+					now: func() time.Time {
+						return testNow
+					},
 				},
 			},
 			res{
@@ -442,9 +575,9 @@ func TestCommands_updateSession(t *testing.T) {
 							session.NewUserCheckedEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate,
 								"userID", testNow),
 							session.NewPasswordCheckedEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate,
-								testNow),
+								testNow, ""),
 							session.NewMetadataSetEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate,
-								map[string][]byte{"key": []byte("value")}),
+								map[string][]byte{"key": []byte("value")}, nil),
 							session.NewTokenSetEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate,
 								"tokenID"),
 						),
@@ -624,9 +757,78 @@ func TestCommands_updateSession(t *testing.T) {
 							session.NewUserCheckedEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate,
 								"userID", testNow),
 							session.NewIntentCheckedEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate,
-								testNow),
+								testNow, "", domain.IDPIntentProtocolUnspecified),
+							session.NewMetadataSetEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate,
+								map[string][]byte{"key": []byte("value")}, nil),
+							session.NewTokenSetEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate,
+								"tokenID"),
+						),
+					),
+				),
+			},
+			args{
+				ctx: context.Background(),
+				checks: &SessionCommands{
+					sessionWriteModel: NewSessionWriteModel("sessionID", "org1"),
+					sessionCommands: []SessionCommand{
+						CheckUser("userID"),
+						CheckIntent("intent", "aW50ZW50"),
+					},
+					eventstore: eventstoreExpect(t,
+						expectFilter(
+							eventFromEventPusher(
+								user.NewHumanAddedEvent(context.Background(), &user.NewAggregate("userID", "org1").Aggregate,
+									"username", "", "", "", "", language.English, domain.GenderUnspecified, "", false),
+							),
+							eventFromEventPusher(
+								idpintent.NewSucceededEvent(context.Background(), &idpintent.NewAggregate("intent", "org1").Aggregate,
+									nil,
+									"idpUserID",
+									"idpUsername",
+									"userID",
+									nil,
+									"",
+								),
+							),
+						),
+						expectFilter(),
+					),
+					createToken: func(sessionID string) (string, string, error) {
+						return "tokenID",
+							"token",
+							nil
+					},
+					intentAlg: decryption(nil),
+					now: func() time.Time {
+						return testNow
+					},
+				},
+				metadata: map[string][]byte{
+					"key": []byte("value"),
+				},
+			},
+			res{
+				want: &SessionChanged{
+					ObjectDetails: &domain.ObjectDetails{
+						ResourceOwner: "org1",
+					},
+					ID:       "sessionID",
+					NewToken: "token",
+				},
+			},
+		},
+		{
+			"set user, intent of an OAuth provider, metadata and token",
+			fields{
+				eventstore: eventstoreExpect(t,
+					expectPush(
+						eventPusherToEvents(
+							session.NewUserCheckedEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate,
+								"userID", testNow),
+							session.NewIntentCheckedEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate,
+								testNow, "idpID", domain.IDPIntentProtocolOAuth),
 							session.NewMetadataSetEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate,
-								map[string][]byte{"key": []byte("value")}),
+								map[string][]byte{"key": []byte("value")}, nil),
 							session.NewTokenSetEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate,
 								"tokenID"),
 						),
@@ -647,6 +849,13 @@ func TestCommands_updateSession(t *testing.T) {
 								user.NewHumanAddedEvent(context.Background(), &user.NewAggregate("userID", "org1").Aggregate,
 									"username", "", "", "", "", language.English, domain.GenderUnspecified, "", false),
 							),
+							eventFromEventPusher(
+								idpintent.NewStartedEvent(context.Background(), &idpintent.NewAggregate("intent", "org1").Aggregate,
+									nil,
+									nil,
+									"idpID",
+								),
+							),
 							eventFromEventPusher(
 								idpintent.NewSucceededEvent(context.Background(), &idpintent.NewAggregate("intent", "org1").Aggregate,
 									nil,
@@ -658,6 +867,22 @@ func TestCommands_updateSession(t *testing.T) {
 								),
 							),
 						),
+						expectFilter(
+							eventFromEventPusher(
+								instance.NewOAuthIDPAddedEvent(context.Background(), &instance.NewAggregate("instanceID").Aggregate,
+									"idpID",
+									"name",
+									"clientID",
+									nil,
+									"auth",
+									"token",
+									"user",
+									"idAttribute",
+									nil,
+									rep_idp.Options{},
+								),
+							),
+						),
 					),
 					createToken: func(sessionID string) (string, string, error) {
 						return "tokenID",
@@ -708,6 +933,7 @@ func TestCheckTOTP(t *testing.T) {
 
 	code, err := totp.GenerateCode(key.Secret(), testNow)
 	require.NoError(t, err)
+	step := uint64(testNow.Unix()) / 30
 
 	type fields struct {
 		sessionWriteModel *SessionWriteModel
@@ -786,7 +1012,7 @@ func TestCheckTOTP(t *testing.T) {
 					),
 				),
 			},
-			wantErr: caos_errs.ThrowInvalidArgument(nil, "EVENT-8isk2", "Errors.User.MFA.OTP.InvalidCode"),
+			wantErr: caos_errs.ThrowInvalidArgument(nil, "DOMAIN-Eij3a", "Errors.User.MFA.OTP.InvalidCode"),
 		},
 		{
 			name: "ok",
@@ -809,8 +1035,31 @@ func TestCheckTOTP(t *testing.T) {
 				),
 			},
 			wantEventCommands: []eventstore.Command{
-				session.NewTOTPCheckedEvent(ctx, sessAgg, testNow),
+				session.NewTOTPCheckedEvent(ctx, sessAgg, testNow, "0", step),
+			},
+		},
+		{
+			name: "code of same time-step reused",
+			code: code,
+			fields: fields{
+				sessionWriteModel: &SessionWriteModel{
+					UserID:        "user1",
+					UserCheckedAt: testNow,
+					TOTPLastStep:  step,
+					aggregate:     sessAgg,
+				},
+				eventstore: expectEventstore(
+					expectFilter(
+						eventFromEventPusher(
+							user.NewHumanOTPAddedEvent(ctx, userAgg, secret),
+						),
+						eventFromEventPusher(
+							user.NewHumanOTPVerifiedEvent(ctx, userAgg, "agent1"),
+						),
+					),
+				),
 			},
+			wantErr: caos_errs.ThrowInvalidArgument(nil, "COMMAND-Uu3ie", "Errors.Session.TOTP.CodeReused"),
 		},
 	}
 	for _, tt := range tests {
@@ -830,8 +1079,9 @@ func TestCheckTOTP(t *testing.T) {
 
 func TestCommands_TerminateSession(t *testing.T) {
 	type fields struct {
-		eventstore    *eventstore.Eventstore
-		tokenVerifier func(ctx context.Context, sessionToken, sessionID, tokenID string) (err error)
+		eventstore      *eventstore.Eventstore
+		tokenVerifier   func(ctx context.Context, sessionToken, sessionID, tokenID string) (err error)
+		checkPermission domain.PermissionCheck
 	}
 	type args struct {
 		ctx          context.Context
@@ -866,9 +1116,10 @@ func TestCommands_TerminateSession(t *testing.T) {
 			"invalid session token",
 			fields{
 				eventstore: eventstoreExpect(t,
+					expectFilter(),
 					expectFilter(
 						eventFromEventPusher(
-							session.NewAddedEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate)),
+							session.NewAddedEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate, 0, "", "", "", nil)),
 						eventFromEventPusher(
 							session.NewTokenSetEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate,
 								"tokenID")),
@@ -891,14 +1142,15 @@ func TestCommands_TerminateSession(t *testing.T) {
 			"not active",
 			fields{
 				eventstore: eventstoreExpect(t,
+					expectFilter(),
 					expectFilter(
 						eventFromEventPusher(
-							session.NewAddedEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate)),
+							session.NewAddedEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate, 0, "", "", "", nil)),
 						eventFromEventPusher(
 							session.NewTokenSetEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate,
 								"tokenID")),
 						eventFromEventPusher(
-							session.NewTerminateEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate)),
+							session.NewTerminateEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate, domain.SessionTerminationTypeLogout)),
 					),
 				),
 				tokenVerifier: func(ctx context.Context, sessionToken, sessionID, tokenID string) (err error) {
@@ -920,9 +1172,10 @@ func TestCommands_TerminateSession(t *testing.T) {
 			"push failed",
 			fields{
 				eventstore: eventstoreExpect(t,
+					expectFilter(),
 					expectFilter(
 						eventFromEventPusher(
-							session.NewAddedEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate)),
+							session.NewAddedEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate, 0, "", "", "", nil)),
 						eventFromEventPusher(
 							session.NewTokenSetEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate,
 								"tokenID"),
@@ -931,7 +1184,7 @@ func TestCommands_TerminateSession(t *testing.T) {
 					expectPushFailed(
 						caos_errs.ThrowInternal(nil, "id", "pushed failed"),
 						eventPusherToEvents(
-							session.NewTerminateEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate)),
+							session.NewTerminateEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate, domain.SessionTerminationTypeLogout)),
 					),
 				),
 				tokenVerifier: func(ctx context.Context, sessionToken, sessionID, tokenID string) (err error) {
@@ -951,9 +1204,10 @@ func TestCommands_TerminateSession(t *testing.T) {
 			"terminate",
 			fields{
 				eventstore: eventstoreExpect(t,
+					expectFilter(),
 					expectFilter(
 						eventFromEventPusher(
-							session.NewAddedEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate)),
+							session.NewAddedEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate, 0, "", "", "", nil)),
 						eventFromEventPusher(
 							session.NewTokenSetEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate,
 								"tokenID"),
@@ -961,7 +1215,7 @@ func TestCommands_TerminateSession(t *testing.T) {
 					),
 					expectPush(
 						eventPusherToEvents(
-							session.NewTerminateEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate)),
+							session.NewTerminateEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate, domain.SessionTerminationTypeLogout)),
 					),
 				),
 				tokenVerifier: func(ctx context.Context, sessionToken, sessionID, tokenID string) (err error) {
@@ -979,12 +1233,43 @@ func TestCommands_TerminateSession(t *testing.T) {
 				},
 			},
 		},
+		{
+			"revoke with permission",
+			fields{
+				eventstore: eventstoreExpect(t,
+					expectFilter(),
+					expectFilter(
+						eventFromEventPusher(
+							session.NewAddedEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate, 0, "", "", "", nil)),
+						eventFromEventPusher(
+							session.NewTokenSetEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate,
+								"tokenID"),
+						),
+					),
+					expectPush(
+						eventPusherToEvents(
+							session.NewTerminateEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate, domain.SessionTerminationTypeRevoked)),
+					),
+				),
+				checkPermission: newMockPermissionCheckAllowed(),
+			},
+			args{
+				ctx:       authz.NewMockContext("", "org1", ""),
+				sessionID: "sessionID",
+			},
+			res{
+				want: &domain.ObjectDetails{
+					ResourceOwner: "org1",
+				},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			c := &Commands{
 				eventstore:           tt.fields.eventstore,
 				sessionTokenVerifier: tt.fields.tokenVerifier,
+				checkPermission:      tt.fields.checkPermission,
 			}
 			got, err := c.TerminateSession(tt.args.ctx, tt.args.sessionID, tt.args.sessionToken)
 			require.ErrorIs(t, err, tt.res.err)
@@ -992,3 +1277,2069 @@ func TestCommands_TerminateSession(t *testing.T) {
 		})
 	}
 }
+
+func TestCommands_EnforceMaxSessionsPerUser(t *testing.T) {
+	sessionAggregate := func(id string) *eventstore.Aggregate {
+		return &session.NewAggregate(id, "org1").Aggregate
+	}
+	userChecked := func(from, to int) []*repository.Event {
+		events := make([]*repository.Event, 0, to-from+1)
+		for i := from; i <= to; i++ {
+			events = append(events, eventFromEventPusher(session.NewUserCheckedEvent(context.Background(), sessionAggregate(fmt.Sprintf("sessionID%d", i)), "userID", testNow)))
+		}
+		return events
+	}
+	// sessionEvents returns the events of six sessions of the user, sessionID6 being the new one
+	// and sessionID2 the oldest (authenticated the longest time ago)
+	sessionEvents := func() []*repository.Event {
+		events := make([]*repository.Event, 0, 18)
+		authTimes := map[int]time.Duration{1: -2 * time.Hour, 2: -3 * time.Hour, 3: -time.Hour, 4: -time.Hour, 5: -time.Minute, 6: 0}
+		for i := 1; i <= 6; i++ {
+			id := fmt.Sprintf("sessionID%d", i)
+			events = append(events,
+				eventFromEventPusher(session.NewAddedEvent(context.Background(), sessionAggregate(id), 0, "", "", "", nil)),
+				eventFromEventPusher(session.NewUserCheckedEvent(context.Background(), sessionAggregate(id), "userID", testNow.Add(authTimes[i]))),
+				eventFromEventPusher(session.NewPasswordCheckedEvent(context.Background(), sessionAggregate(id), testNow.Add(authTimes[i]), "")),
+			)
+		}
+		return events
+	}
+	type fields struct {
+		eventstore         *eventstore.Eventstore
+		maxSessionsPerUser int
+	}
+	type res struct {
+		want *domain.ObjectDetails
+		err  error
+	}
+	tests := []struct {
+		name   string
+		fields fields
+		res    res
+	}{
+		{
+			"no limit",
+			fields{
+				eventstore: eventstoreExpect(t),
+			},
+			res{
+				want: &domain.ObjectDetails{},
+			},
+		},
+		{
+			"limit not exceeded",
+			fields{
+				eventstore: eventstoreExpect(t,
+					expectFilter(userChecked(1, 6)...),
+					expectFilter(sessionEvents()...),
+				),
+				maxSessionsPerUser: 6,
+			},
+			res{
+				want: &domain.ObjectDetails{},
+			},
+		},
+		{
+			"6th session evicts the oldest",
+			fields{
+				eventstore: eventstoreExpect(t,
+					expectFilter(userChecked(1, 6)...),
+					expectFilter(sessionEvents()...),
+					expectPush(
+						eventPusherToEvents(
+							session.NewTerminateEvent(context.Background(), sessionAggregate("sessionID2"), domain.SessionTerminationTypeLimitExceeded),
+						),
+					),
+				),
+				maxSessionsPerUser: 5,
+			},
+			res{
+				want: &domain.ObjectDetails{ResourceOwner: "org1"},
+			},
+		},
+		{
+			"terminated sessions not counted",
+			fields{
+				eventstore: eventstoreExpect(t,
+					expectFilter(userChecked(1, 6)...),
+					expectFilter(append(sessionEvents(),
+						eventFromEventPusher(session.NewTerminateEvent(context.Background(), sessionAggregate("sessionID3"), domain.SessionTerminationTypeLogout)),
+					)...),
+				),
+				maxSessionsPerUser: 5,
+			},
+			res{
+				want: &domain.ObjectDetails{},
+			},
+		},
+		{
+			"new session kept",
+			fields{
+				eventstore: eventstoreExpect(t,
+					expectFilter(userChecked(1, 6)...),
+					expectFilter(sessionEvents()...),
+					expectPush(
+						eventPusherToEvents(
+							session.NewTerminateEvent(context.Background(), sessionAggregate("sessionID2"), domain.SessionTerminationTypeLimitExceeded),
+							session.NewTerminateEvent(context.Background(), sessionAggregate("sessionID1"), domain.SessionTerminationTypeLimitExceeded),
+							session.NewTerminateEvent(context.Background(), sessionAggregate("sessionID3"), domain.SessionTerminationTypeLimitExceeded),
+							session.NewTerminateEvent(context.Background(), sessionAggregate("sessionID4"), domain.SessionTerminationTypeLimitExceeded),
+							session.NewTerminateEvent(context.Background(), sessionAggregate("sessionID5"), domain.SessionTerminationTypeLimitExceeded),
+						),
+					),
+				),
+				maxSessionsPerUser: 1,
+			},
+			res{
+				want: &domain.ObjectDetails{ResourceOwner: "org1"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Commands{
+				eventstore:         tt.fields.eventstore,
+				maxSessionsPerUser: tt.fields.maxSessionsPerUser,
+			}
+			got, err := c.EnforceMaxSessionsPerUser(context.Background(), "userID", "sessionID6")
+			require.ErrorIs(t, err, tt.res.err)
+			assert.Equal(t, tt.res.want, got)
+		})
+	}
+}
+
+func TestCommands_MergeSession(t *testing.T) {
+	targetAggregate := &session.NewAggregate("target", "org1").Aggregate
+	sourceAggregate := &session.NewAggregate("source", "org1").Aggregate
+	sessionEvents := func(sourceUserID string) []*repository.Event {
+		events := []*repository.Event{
+			eventFromEventPusher(session.NewAddedEvent(context.Background(), targetAggregate, 0, "", "", "", nil)),
+			eventFromEventPusher(session.NewUserCheckedEvent(context.Background(), targetAggregate, "userID", testNow)),
+			eventFromEventPusher(session.NewMetadataSetEvent(context.Background(), targetAggregate, map[string][]byte{"language": []byte("de")}, nil)),
+			eventFromEventPusher(session.NewAddedEvent(context.Background(), sourceAggregate, 0, "", "", "", nil)),
+			eventFromEventPusher(session.NewMetadataSetEvent(context.Background(), sourceAggregate, map[string][]byte{"cart": []byte("item1"), "language": []byte("en")}, nil)),
+		}
+		if sourceUserID != "" {
+			events = append(events, eventFromEventPusher(session.NewUserCheckedEvent(context.Background(), sourceAggregate, sourceUserID, testNow)))
+		}
+		return events
+	}
+	type fields struct {
+		eventstore      *eventstore.Eventstore
+		checkPermission domain.PermissionCheck
+	}
+	type args struct {
+		sourceID string
+		conflict SessionMetadataMergeConflict
+	}
+	type res struct {
+		want *domain.ObjectDetails
+		err  error
+	}
+	tests := []struct {
+		name   string
+		fields fields
+		args   args
+		res    res
+	}{
+		{
+			"same session",
+			fields{
+				eventstore: eventstoreExpect(t),
+			},
+			args{
+				sourceID: "target",
+			},
+			res{
+				err: caos_errs.ThrowInvalidArgument(nil, "COMMAND-ohT4e", "Errors.Session.Merge.SameSession"),
+			},
+		},
+		{
+			"source not existing",
+			fields{
+				eventstore: eventstoreExpect(t,
+					expectFilter(sessionEvents("")[:3]...),
+				),
+				checkPermission: newMockPermissionCheckAllowed(),
+			},
+			args{
+				sourceID: "source",
+			},
+			res{
+				err: ErrSessionNotExisting,
+			},
+		},
+		{
+			"source of other user",
+			fields{
+				eventstore: eventstoreExpect(t,
+					expectFilter(sessionEvents("otherUserID")...),
+				),
+				checkPermission: newMockPermissionCheckAllowed(),
+			},
+			args{
+				sourceID: "source",
+			},
+			res{
+				err: caos_errs.ThrowPreconditionFailed(nil, "COMMAND-Aen0u", "Errors.Session.Merge.OtherUser"),
+			},
+		},
+		{
+			"merged, prefer target",
+			fields{
+				eventstore: eventstoreExpect(t,
+					expectFilter(sessionEvents("")...),
+					expectPush(
+						eventPusherToEvents(
+							session.NewMetadataBulkSetEvent(context.Background(), targetAggregate, map[string][]byte{"cart": []byte("item1")}),
+							session.NewTerminateEvent(context.Background(), sourceAggregate, domain.SessionTerminationTypeMerged),
+						),
+					),
+				),
+				checkPermission: newMockPermissionCheckAllowed(),
+			},
+			args{
+				sourceID: "source",
+				conflict: SessionMetadataMergePreferTarget,
+			},
+			res{
+				want: &domain.ObjectDetails{ResourceOwner: "org1"},
+			},
+		},
+		{
+			"merged, prefer source",
+			fields{
+				eventstore: eventstoreExpect(t,
+					expectFilter(sessionEvents("userID")...),
+					expectPush(
+						eventPusherToEvents(
+							session.NewMetadataBulkSetEvent(context.Background(), targetAggregate, map[string][]byte{"cart": []byte("item1"), "language": []byte("en")}),
+							session.NewTerminateEvent(context.Background(), sourceAggregate, domain.SessionTerminationTypeMerged),
+						),
+					),
+				),
+				checkPermission: newMockPermissionCheckAllowed(),
+			},
+			args{
+				sourceID: "source",
+				conflict: SessionMetadataMergePreferSource,
+			},
+			res{
+				want: &domain.ObjectDetails{ResourceOwner: "org1"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Commands{
+				eventstore:      tt.fields.eventstore,
+				checkPermission: tt.fields.checkPermission,
+			}
+			got, err := c.MergeSession(context.Background(), "target", tt.args.sourceID, tt.args.conflict)
+			require.ErrorIs(t, err, tt.res.err)
+			assert.Equal(t, tt.res.want, got)
+		})
+	}
+}
+
+func TestCommands_TerminateSessions(t *testing.T) {
+	type fields struct {
+		eventstore      *eventstore.Eventstore
+		checkPermission domain.PermissionCheck
+	}
+	type args struct {
+		ctx        context.Context
+		sessionIDs []string
+	}
+	type res struct {
+		want *domain.ObjectDetails
+		err  error
+	}
+	tests := []struct {
+		name   string
+		fields fields
+		args   args
+		res    res
+	}{
+		{
+			"eventstore failed",
+			fields{
+				eventstore: eventstoreExpect(t,
+					expectFilterError(caos_errs.ThrowInternal(nil, "id", "filter failed")),
+				),
+			},
+			args{
+				ctx:        context.Background(),
+				sessionIDs: []string{"sessionID1"},
+			},
+			res{
+				err: caos_errs.ThrowInternal(nil, "id", "filter failed"),
+			},
+		},
+		{
+			"missing permission",
+			fields{
+				eventstore: eventstoreExpect(t,
+					expectFilter(
+						eventFromEventPusher(
+							session.NewAddedEvent(context.Background(), &session.NewAggregate("sessionID1", "org1").Aggregate, 0, "", "", "", nil)),
+					),
+				),
+				checkPermission: newMockPermissionCheckNotAllowed(),
+			},
+			args{
+				ctx:        context.Background(),
+				sessionIDs: []string{"sessionID1"},
+			},
+			res{
+				err: caos_errs.ThrowPermissionDenied(nil, "AUTHZ-HKJD33", "Errors.PermissionDenied"),
+			},
+		},
+		{
+			"no active sessions",
+			fields{
+				eventstore: eventstoreExpect(t,
+					expectFilter(
+						eventFromEventPusher(
+							session.NewAddedEvent(context.Background(), &session.NewAggregate("sessionID1", "org1").Aggregate, 0, "", "", "", nil)),
+						eventFromEventPusher(
+							session.NewTerminateEvent(context.Background(), &session.NewAggregate("sessionID1", "org1").Aggregate, domain.SessionTerminationTypeLogout)),
+					),
+				),
+				checkPermission: newMockPermissionCheckAllowed(),
+			},
+			args{
+				ctx:        context.Background(),
+				sessionIDs: []string{"sessionID1", "sessionID2"},
+			},
+			res{
+				want: &domain.ObjectDetails{},
+			},
+		},
+		{
+			"terminate active sessions",
+			fields{
+				eventstore: eventstoreExpect(t,
+					expectFilter(
+						eventFromEventPusher(
+							session.NewAddedEvent(context.Background(), &session.NewAggregate("sessionID1", "org1").Aggregate, 0, "", "", "", nil)),
+						eventFromEventPusher(
+							session.NewAddedEvent(context.Background(), &session.NewAggregate("sessionID2", "org1").Aggregate, 0, "", "", "", nil)),
+						eventFromEventPusher(
+							session.NewAddedEvent(context.Background(), &session.NewAggregate("sessionID3", "org1").Aggregate, 0, "", "", "", nil)),
+						eventFromEventPusher(
+							session.NewTerminateEvent(context.Background(), &session.NewAggregate("sessionID2", "org1").Aggregate, domain.SessionTerminationTypeLogout)),
+					),
+					expectPush(
+						eventPusherToEvents(
+							session.NewTerminateEvent(context.Background(), &session.NewAggregate("sessionID1", "org1").Aggregate, domain.SessionTerminationTypeRevoked),
+							session.NewTerminateEvent(context.Background(), &session.NewAggregate("sessionID3", "org1").Aggregate, domain.SessionTerminationTypeRevoked),
+						),
+					),
+				),
+				checkPermission: newMockPermissionCheckAllowed(),
+			},
+			args{
+				ctx:        context.Background(),
+				sessionIDs: []string{"sessionID1", "sessionID2", "sessionID3", "sessionID1"},
+			},
+			res{
+				want: &domain.ObjectDetails{
+					ResourceOwner: "org1",
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Commands{
+				eventstore:      tt.fields.eventstore,
+				checkPermission: tt.fields.checkPermission,
+			}
+			got, err := c.TerminateSessions(tt.args.ctx, tt.args.sessionIDs)
+			require.ErrorIs(t, err, tt.res.err)
+			assert.Equal(t, tt.res.want, got)
+		})
+	}
+}
+
+// sessionBenchmarkRepo is an in-memory event repository,
+// which simulates the latency of a round trip to the database for every query.
+type sessionBenchmarkRepo struct {
+	events  []*repository.Event
+	latency time.Duration
+}
+
+func (repo *sessionBenchmarkRepo) Health(context.Context) error { return nil }
+
+func (repo *sessionBenchmarkRepo) Push(context.Context, []*repository.Event, ...*repository.UniqueConstraint) error {
+	return nil
+}
+
+func (repo *sessionBenchmarkRepo) Filter(_ context.Context, searchQuery *repository.SearchQuery) ([]*repository.Event, error) {
+	time.Sleep(repo.latency)
+	aggregateIDs := make(map[string]bool)
+	for _, filters := range searchQuery.Filters {
+		for _, filter := range filters {
+			if filter.Field != repository.FieldAggregateID {
+				continue
+			}
+			switch value := filter.Value.(type) {
+			case string:
+				aggregateIDs[value] = true
+			case database.StringArray:
+				for _, id := range value {
+					aggregateIDs[id] = true
+				}
+			}
+		}
+	}
+	events := make([]*repository.Event, 0, len(repo.events))
+	for _, event := range repo.events {
+		if aggregateIDs[event.AggregateID] {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (repo *sessionBenchmarkRepo) LatestSequence(context.Context, *repository.SearchQuery) (uint64, error) {
+	return 0, nil
+}
+
+func (repo *sessionBenchmarkRepo) InstanceIDs(context.Context, *repository.SearchQuery) ([]string, error) {
+	return nil, nil
+}
+
+func (repo *sessionBenchmarkRepo) CreateInstance(context.Context, string) error { return nil }
+
+// BenchmarkCommands_sessionWriteModels compares querying and reducing every session on its own (sequential)
+// with [Commands.sessionWriteModels], which queries the events of all sessions at once (batched).
+func BenchmarkCommands_sessionWriteModels(b *testing.B) {
+	ctx := context.Background()
+	sessionIDs := make([]string, 100)
+	repo := &sessionBenchmarkRepo{latency: 100 * time.Microsecond}
+	for i := range sessionIDs {
+		sessionIDs[i] = fmt.Sprintf("sessionID%d", i)
+		sessionAggregate := &session.NewAggregate(sessionIDs[i], "org1").Aggregate
+		repo.events = append(repo.events, eventPusherToEvents(
+			session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+			session.NewUserCheckedEvent(ctx, sessionAggregate, "userID", testNow),
+			session.NewPasswordCheckedEvent(ctx, sessionAggregate, testNow, ""),
+			session.NewTokenSetEvent(ctx, sessionAggregate, "tokenID"),
+		)...)
+	}
+	es := eventstore.NewEventstore(eventstore.TestConfig(repo))
+	session.RegisterEventMappers(es)
+	c := &Commands{eventstore: es}
+
+	b.Run("sequential", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			for _, sessionID := range sessionIDs {
+				if err := es.FilterToQueryReducer(ctx, NewSessionWriteModel(sessionID, "")); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+	b.Run("batched", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			if _, err := c.sessionWriteModels(ctx, sessionIDs); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestCommands_TerminateSessionsOfRemovedUser(t *testing.T) {
+	type fields struct {
+		eventstore *eventstore.Eventstore
+	}
+	type args struct {
+		ctx    context.Context
+		userID string
+	}
+	type res struct {
+		want *domain.ObjectDetails
+		err  error
+	}
+	tests := []struct {
+		name   string
+		fields fields
+		args   args
+		res    res
+	}{
+		{
+			"eventstore failed",
+			fields{
+				eventstore: eventstoreExpect(t,
+					expectFilterError(caos_errs.ThrowInternal(nil, "id", "filter failed")),
+				),
+			},
+			args{
+				ctx:    context.Background(),
+				userID: "userID",
+			},
+			res{
+				err: caos_errs.ThrowInternal(nil, "id", "filter failed"),
+			},
+		},
+		{
+			"no sessions",
+			fields{
+				eventstore: eventstoreExpect(t,
+					expectFilter(),
+				),
+			},
+			args{
+				ctx:    context.Background(),
+				userID: "userID",
+			},
+			res{
+				want: &domain.ObjectDetails{},
+			},
+		},
+		{
+			"terminate active sessions of user",
+			fields{
+				eventstore: eventstoreExpect(t,
+					expectFilter(
+						eventFromEventPusher(
+							session.NewUserCheckedEvent(context.Background(), &session.NewAggregate("sessionID1", "org1").Aggregate, "userID", testNow)),
+						eventFromEventPusher(
+							session.NewUserCheckedEvent(context.Background(), &session.NewAggregate("sessionID2", "org1").Aggregate, "userID", testNow)),
+					),
+					expectFilter(
+						eventFromEventPusher(
+							session.NewAddedEvent(context.Background(), &session.NewAggregate("sessionID1", "org1").Aggregate, 0, "", "", "", nil)),
+						eventFromEventPusher(
+							session.NewUserCheckedEvent(context.Background(), &session.NewAggregate("sessionID1", "org1").Aggregate, "userID", testNow)),
+						eventFromEventPusher(
+							session.NewAddedEvent(context.Background(), &session.NewAggregate("sessionID2", "org1").Aggregate, 0, "", "", "", nil)),
+						eventFromEventPusher(
+							session.NewUserCheckedEvent(context.Background(), &session.NewAggregate("sessionID2", "org1").Aggregate, "userID", testNow)),
+						eventFromEventPusher(
+							session.NewTerminateEvent(context.Background(), &session.NewAggregate("sessionID2", "org1").Aggregate, domain.SessionTerminationTypeLogout)),
+					),
+					expectPush(
+						eventPusherToEvents(
+							session.NewTerminateEvent(context.Background(), &session.NewAggregate("sessionID1", "org1").Aggregate, domain.SessionTerminationTypeUserRemoved),
+						),
+					),
+				),
+			},
+			args{
+				ctx:    context.Background(),
+				userID: "userID",
+			},
+			res{
+				want: &domain.ObjectDetails{
+					ResourceOwner: "org1",
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Commands{
+				eventstore: tt.fields.eventstore,
+			}
+			got, err := c.TerminateSessionsOfRemovedUser(tt.args.ctx, tt.args.userID)
+			require.ErrorIs(t, err, tt.res.err)
+			assert.Equal(t, tt.res.want, got)
+		})
+	}
+}
+
+func TestCommands_RotateSessionToken(t *testing.T) {
+	type fields struct {
+		eventstore    *eventstore.Eventstore
+		tokenVerifier func(ctx context.Context, sessionToken, sessionID, tokenID string) (err error)
+		tokenCreator  func(sessionID string) (string, string, error)
+		now           func() time.Time
+	}
+	type args struct {
+		ctx          context.Context
+		sessionID    string
+		sessionToken string
+	}
+	type res struct {
+		want *SessionChanged
+		err  error
+	}
+	tests := []struct {
+		name   string
+		fields fields
+		args   args
+		res    res
+	}{
+		{
+			"invalid session token",
+			fields{
+				eventstore: eventstoreExpect(t,
+					expectFilter(),
+					expectFilter(
+						eventFromEventPusher(
+							session.NewAddedEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate, 0, "", "", "", nil)),
+						eventFromEventPusher(
+							session.NewTokenSetEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate,
+								"tokenID")),
+					),
+				),
+				tokenVerifier: func(ctx context.Context, sessionToken, sessionID, tokenID string) (err error) {
+					return caos_errs.ThrowPermissionDenied(nil, "COMMAND-sGr42", "Errors.Session.Token.Invalid")
+				},
+			},
+			args{
+				ctx:          context.Background(),
+				sessionID:    "sessionID",
+				sessionToken: "invalid",
+			},
+			res{
+				err: caos_errs.ThrowPermissionDenied(nil, "COMMAND-sGr42", "Errors.Session.Token.Invalid"),
+			},
+		},
+		{
+			"terminated",
+			fields{
+				eventstore: eventstoreExpect(t,
+					expectFilter(),
+					expectFilter(
+						eventFromEventPusher(
+							session.NewAddedEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate, 0, "", "", "", nil)),
+						eventFromEventPusher(
+							session.NewTokenSetEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate,
+								"tokenID")),
+						eventFromEventPusher(
+							session.NewTerminateEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate, domain.SessionTerminationTypeLogout)),
+					),
+				),
+				tokenVerifier: func(ctx context.Context, sessionToken, sessionID, tokenID string) (err error) {
+					return nil
+				},
+			},
+			args{
+				ctx:          context.Background(),
+				sessionID:    "sessionID",
+				sessionToken: "token",
+			},
+			res{
+				err: caos_errs.ThrowPreconditionFailed(nil, "COMMAND-Aiph3", "Errors.Session.Terminated"),
+			},
+		},
+		{
+			"expired",
+			fields{
+				eventstore: eventstoreExpect(t,
+					expectFilter(),
+					expectFilter(
+						eventFromEventPusherWithCreationDate(
+							session.NewAddedEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate, time.Hour, "", "", "", nil), time.Now().Add(-2*time.Hour)),
+						eventFromEventPusherWithCreationDate(
+							session.NewTokenSetEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate,
+								"tokenID"), time.Now().Add(-2*time.Hour)),
+					),
+				),
+				tokenVerifier: func(ctx context.Context, sessionToken, sessionID, tokenID string) (err error) {
+					return nil
+				},
+			},
+			args{
+				ctx:          context.Background(),
+				sessionID:    "sessionID",
+				sessionToken: "token",
+			},
+			res{
+				err: ErrSessionExpired,
+			},
+		},
+		{
+			"expired by clock",
+			fields{
+				eventstore: eventstoreExpect(t,
+					expectFilter(),
+					expectFilter(
+						eventFromEventPusherWithCreationDate(
+							session.NewAddedEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate, time.Hour, "", "", "", nil), testNow),
+						eventFromEventPusherWithCreationDate(
+							session.NewTokenSetEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate,
+								"tokenID"), testNow),
+					),
+				),
+				tokenVerifier: func(ctx context.Context, sessionToken, sessionID, tokenID string) (err error) {
+					return nil
+				},
+				now: func() time.Time {
+					return testNow.Add(2 * time.Hour)
+				},
+			},
+			args{
+				ctx:          context.Background(),
+				sessionID:    "sessionID",
+				sessionToken: "token",
+			},
+			res{
+				err: ErrSessionExpired,
+			},
+		},
+		{
+			"rotate token",
+			fields{
+				eventstore: eventstoreExpect(t,
+					expectFilter(),
+					expectFilter(
+						eventFromEventPusher(
+							session.NewAddedEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate, 0, "", "", "", nil)),
+						eventFromEventPusher(
+							session.NewTokenSetEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate,
+								"tokenID")),
+					),
+					expectPush(
+						eventPusherToEvents(
+							session.NewTokenSetEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate,
+								"tokenID2"),
+						),
+					),
+				),
+				tokenVerifier: func(ctx context.Context, sessionToken, sessionID, tokenID string) (err error) {
+					return nil
+				},
+				tokenCreator: func(sessionID string) (string, string, error) {
+					return "tokenID2",
+						"token2",
+						nil
+				},
+			},
+			args{
+				ctx:          context.Background(),
+				sessionID:    "sessionID",
+				sessionToken: "token",
+			},
+			res{
+				want: &SessionChanged{
+					ObjectDetails: &domain.ObjectDetails{
+						ResourceOwner: "org1",
+					},
+					ID:       "sessionID",
+					NewToken: "token2",
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Commands{
+				eventstore:           tt.fields.eventstore,
+				sessionTokenVerifier: tt.fields.tokenVerifier,
+				sessionTokenCreator:  tt.fields.tokenCreator,
+				now:                  tt.fields.now,
+			}
+			got, err := c.RotateSessionToken(tt.args.ctx, tt.args.sessionID, tt.args.sessionToken)
+			require.ErrorIs(t, err, tt.res.err)
+			assert.Equal(t, tt.res.want, got)
+		})
+	}
+}
+
+func TestCommands_SetSessionLifetime(t *testing.T) {
+	type fields struct {
+		eventstore      *eventstore.Eventstore
+		checkPermission domain.PermissionCheck
+	}
+	type args struct {
+		ctx       context.Context
+		sessionID string
+		lifetime  time.Duration
+	}
+	type res struct {
+		want *domain.ObjectDetails
+		err  error
+	}
+	tests := []struct {
+		name   string
+		fields fields
+		args   args
+		res    res
+	}{
+		{
+			"invalid lifetime",
+			fields{
+				eventstore: eventstoreExpect(t),
+			},
+			args{
+				ctx:       context.Background(),
+				sessionID: "sessionID",
+				lifetime:  0,
+			},
+			res{
+				err: caos_errs.ThrowInvalidArgument(nil, "COMMAND-ieJ1o", "Errors.Session.LifetimeInvalid"),
+			},
+		},
+		{
+			"missing permission",
+			fields{
+				eventstore: eventstoreExpect(t,
+					expectFilter(),
+					expectFilter(
+						eventFromEventPusher(
+							session.NewAddedEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate, 0, "", "", "", nil)),
+					),
+				),
+				checkPermission: newMockPermissionCheckNotAllowed(),
+			},
+			args{
+				ctx:       context.Background(),
+				sessionID: "sessionID",
+				lifetime:  time.Hour,
+			},
+			res{
+				err: caos_errs.ThrowPermissionDenied(nil, "AUTHZ-HKJD33", "Errors.PermissionDenied"),
+			},
+		},
+		{
+			"not existing",
+			fields{
+				eventstore: eventstoreExpect(t,
+					expectFilter(),
+					expectFilter(),
+				),
+				checkPermission: newMockPermissionCheckAllowed(),
+			},
+			args{
+				ctx:       context.Background(),
+				sessionID: "sessionID",
+				lifetime:  time.Hour,
+			},
+			res{
+				err: caos_errs.ThrowNotFound(nil, "COMMAND-aeL0a", "Errors.Session.NotExisting"),
+			},
+		},
+		{
+			"terminated",
+			fields{
+				eventstore: eventstoreExpect(t,
+					expectFilter(),
+					expectFilter(
+						eventFromEventPusher(
+							session.NewAddedEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate, 0, "", "", "", nil)),
+						eventFromEventPusher(
+							session.NewTerminateEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate, domain.SessionTerminationTypeLogout)),
+					),
+				),
+				checkPermission: newMockPermissionCheckAllowed(),
+			},
+			args{
+				ctx:       context.Background(),
+				sessionID: "sessionID",
+				lifetime:  time.Hour,
+			},
+			res{
+				err: caos_errs.ThrowPreconditionFailed(nil, "COMMAND-Uo8ee", "Errors.Session.Terminated"),
+			},
+		},
+		{
+			"lifetime set, idle timeout kept",
+			fields{
+				eventstore: eventstoreExpect(t,
+					expectFilter(),
+					expectFilter(
+						eventFromEventPusher(
+							session.NewAddedEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate, time.Minute, "", "", "", nil)),
+						eventFromEventPusher(
+							session.NewLifetimeSetEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate, 0, 10*time.Minute)),
+					),
+					expectPush(
+						eventPusherToEvents(
+							session.NewLifetimeSetEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate, time.Hour, 10*time.Minute),
+						),
+					),
+				),
+				checkPermission: newMockPermissionCheckAllowed(),
+			},
+			args{
+				ctx:       context.Background(),
+				sessionID: "sessionID",
+				lifetime:  time.Hour,
+			},
+			res{
+				want: &domain.ObjectDetails{
+					ResourceOwner: "org1",
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Commands{
+				eventstore:      tt.fields.eventstore,
+				checkPermission: tt.fields.checkPermission,
+			}
+			got, err := c.SetSessionLifetime(tt.args.ctx, tt.args.sessionID, tt.args.lifetime)
+			require.ErrorIs(t, err, tt.res.err)
+			assert.Equal(t, tt.res.want, got)
+		})
+	}
+}
+
+func TestCommands_RevokeSessionToken(t *testing.T) {
+	type fields struct {
+		eventstore      *eventstore.Eventstore
+		checkPermission domain.PermissionCheck
+	}
+	type args struct {
+		ctx       context.Context
+		sessionID string
+	}
+	type res struct {
+		want *domain.ObjectDetails
+		err  error
+	}
+	tests := []struct {
+		name   string
+		fields fields
+		args   args
+		res    res
+	}{
+		{
+			"missing permission",
+			fields{
+				eventstore: eventstoreExpect(t,
+					expectFilter(),
+					expectFilter(
+						eventFromEventPusher(
+							session.NewAddedEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate, 0, "", "", "", nil)),
+					),
+				),
+				checkPermission: newMockPermissionCheckNotAllowed(),
+			},
+			args{
+				ctx:       context.Background(),
+				sessionID: "sessionID",
+			},
+			res{
+				err: caos_errs.ThrowPermissionDenied(nil, "AUTHZ-HKJD33", "Errors.PermissionDenied"),
+			},
+		},
+		{
+			"not existing",
+			fields{
+				eventstore: eventstoreExpect(t,
+					expectFilter(),
+					expectFilter(),
+				),
+				checkPermission: newMockPermissionCheckAllowed(),
+			},
+			args{
+				ctx:       context.Background(),
+				sessionID: "sessionID",
+			},
+			res{
+				err: ErrSessionNotExisting,
+			},
+		},
+		{
+			"terminated",
+			fields{
+				eventstore: eventstoreExpect(t,
+					expectFilter(),
+					expectFilter(
+						eventFromEventPusher(
+							session.NewAddedEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate, 0, "", "", "", nil)),
+						eventFromEventPusher(
+							session.NewTokenSetEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate, "tokenID")),
+						eventFromEventPusher(
+							session.NewTerminateEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate, domain.SessionTerminationTypeLogout)),
+					),
+				),
+				checkPermission: newMockPermissionCheckAllowed(),
+			},
+			args{
+				ctx:       context.Background(),
+				sessionID: "sessionID",
+			},
+			res{
+				err: ErrSessionTerminated,
+			},
+		},
+		{
+			"no token, nothing to revoke",
+			fields{
+				eventstore: eventstoreExpect(t,
+					expectFilter(),
+					expectFilter(
+						eventFromEventPusher(
+							session.NewAddedEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate, 0, "", "", "", nil)),
+					),
+				),
+				checkPermission: newMockPermissionCheckAllowed(),
+			},
+			args{
+				ctx:       context.Background(),
+				sessionID: "sessionID",
+			},
+			res{
+				want: &domain.ObjectDetails{
+					ResourceOwner: "org1",
+				},
+			},
+		},
+		{
+			"token revoked",
+			fields{
+				eventstore: eventstoreExpect(t,
+					expectFilter(),
+					expectFilter(
+						eventFromEventPusher(
+							session.NewAddedEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate, 0, "", "", "", nil)),
+						eventFromEventPusher(
+							session.NewTokenSetEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate, "tokenID")),
+					),
+					expectPush(
+						eventPusherToEvents(
+							session.NewTokenSetEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate, ""),
+						),
+					),
+				),
+				checkPermission: newMockPermissionCheckAllowed(),
+			},
+			args{
+				ctx:       context.Background(),
+				sessionID: "sessionID",
+			},
+			res{
+				want: &domain.ObjectDetails{
+					ResourceOwner: "org1",
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Commands{
+				eventstore:      tt.fields.eventstore,
+				checkPermission: tt.fields.checkPermission,
+			}
+			got, err := c.RevokeSessionToken(tt.args.ctx, tt.args.sessionID)
+			require.ErrorIs(t, err, tt.res.err)
+			assert.Equal(t, tt.res.want, got)
+		})
+	}
+}
+
+func TestCommands_LockSession_UnlockSession(t *testing.T) {
+	ctx := authz.NewMockContext("", "org1", "user1")
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	added := eventFromEventPusher(session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil))
+	userChecked := eventFromEventPusher(session.NewUserCheckedEvent(ctx, sessionAggregate, "userID", testNow))
+	locked := eventFromEventPusher(session.NewLockedEvent(ctx, sessionAggregate))
+	unlocked := eventFromEventPusher(session.NewUnlockedEvent(ctx, sessionAggregate))
+	c := &Commands{
+		eventstore: eventstoreExpect(t,
+			// lock
+			expectFilter(),
+			expectFilter(added, userChecked),
+			expectPush(eventPusherToEvents(session.NewLockedEvent(ctx, sessionAggregate))),
+			// check is rejected
+			expectFilter(),
+			expectFilter(added, userChecked, locked),
+			// unlock
+			expectFilter(),
+			expectFilter(added, userChecked, locked),
+			expectPush(eventPusherToEvents(session.NewUnlockedEvent(ctx, sessionAggregate))),
+			// check is allowed
+			expectFilter(),
+			expectFilter(added, userChecked, locked, unlocked),
+			expectPush(eventPusherToEvents(
+				session.NewPasswordCheckedEvent(ctx, sessionAggregate, testNow, ""),
+				session.NewTokenSetEvent(ctx, sessionAggregate, "tokenID"),
+			)),
+		),
+		checkPermission: newMockPermissionCheckAllowed(),
+		sessionTokenCreator: func(sessionID string) (string, string, error) {
+			return "tokenID", "token", nil
+		},
+	}
+	check := []SessionCommand{
+		func(ctx context.Context, cmd *SessionCommands) error {
+			cmd.PasswordChecked(ctx, testNow, "")
+			return nil
+		},
+	}
+
+	_, err := c.LockSession(ctx, "sessionID")
+	require.NoError(t, err)
+
+	_, err = c.UpdateSession(ctx, "sessionID", "", check, nil)
+	require.ErrorIs(t, err, ErrSessionLocked)
+
+	_, err = c.UnlockSession(ctx, "sessionID")
+	require.NoError(t, err)
+
+	changed, err := c.UpdateSession(ctx, "sessionID", "", check, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "token", changed.NewToken)
+}
+
+func TestCommands_RecordSessionActivity(t *testing.T) {
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	type fields struct {
+		eventstore           *eventstore.Eventstore
+		sessionTokenVerifier func(ctx context.Context, sessionToken string, sessionID string, tokenID string) (err error)
+	}
+	type args struct {
+		ctx          context.Context
+		sessionToken string
+	}
+	type res struct {
+		want *domain.ObjectDetails
+		err  error
+	}
+	tests := []struct {
+		name   string
+		fields fields
+		args   args
+		res    res
+	}{
+		{
+			"missing token",
+			fields{
+				eventstore: eventstoreExpect(t),
+			},
+			args{
+				ctx: authz.NewMockContext("", "org1", "user1"),
+			},
+			res{
+				err: caos_errs.ThrowInvalidArgument(nil, "COMMAND-Ahx4o", "Errors.Session.Token.Invalid"),
+			},
+		},
+		{
+			"invalid token",
+			fields{
+				eventstore: eventstoreExpect(t,
+					expectFilter(),
+					expectFilter(
+						eventFromEventPusher(session.NewAddedEvent(context.Background(), sessionAggregate, 0, "", "", "", nil)),
+						eventFromEventPusher(session.NewTokenSetEvent(context.Background(), sessionAggregate, "tokenID")),
+					),
+				),
+				sessionTokenVerifier: func(ctx context.Context, sessionToken string, sessionID string, tokenID string) (err error) {
+					return caos_errs.ThrowPermissionDenied(nil, "COMMAND-sGr42", "Errors.Session.Token.Invalid")
+				},
+			},
+			args{
+				ctx:          authz.NewMockContext("", "org1", "user1"),
+				sessionToken: "invalid",
+			},
+			res{
+				err: caos_errs.ThrowPermissionDenied(nil, "COMMAND-sGr42", "Errors.Session.Token.Invalid"),
+			},
+		},
+		{
+			"terminated",
+			fields{
+				eventstore: eventstoreExpect(t,
+					expectFilter(),
+					expectFilter(
+						eventFromEventPusher(session.NewAddedEvent(context.Background(), sessionAggregate, 0, "", "", "", nil)),
+						eventFromEventPusher(session.NewTokenSetEvent(context.Background(), sessionAggregate, "tokenID")),
+						eventFromEventPusher(session.NewTerminateEvent(context.Background(), sessionAggregate, domain.SessionTerminationTypeLogout)),
+					),
+				),
+				sessionTokenVerifier: func(ctx context.Context, sessionToken string, sessionID string, tokenID string) (err error) {
+					return nil
+				},
+			},
+			args{
+				ctx:          authz.NewMockContext("", "org1", "user1"),
+				sessionToken: "token",
+			},
+			res{
+				err: ErrSessionTerminated,
+			},
+		},
+		{
+			"activity recorded",
+			fields{
+				eventstore: eventstoreExpect(t,
+					expectFilter(),
+					expectFilter(
+						eventFromEventPusher(session.NewAddedEvent(context.Background(), sessionAggregate, 0, "", "", "", nil)),
+						eventFromEventPusher(session.NewTokenSetEvent(context.Background(), sessionAggregate, "tokenID")),
+					),
+					expectPush(
+						eventPusherToEvents(session.NewActivityEvent(authz.NewMockContext("", "org1", "user1"), sessionAggregate)),
+					),
+				),
+				sessionTokenVerifier: func(ctx context.Context, sessionToken string, sessionID string, tokenID string) (err error) {
+					return nil
+				},
+			},
+			args{
+				ctx:          authz.NewMockContext("", "org1", "user1"),
+				sessionToken: "token",
+			},
+			res{
+				want: &domain.ObjectDetails{
+					ResourceOwner: "org1",
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Commands{
+				eventstore:           tt.fields.eventstore,
+				sessionTokenVerifier: tt.fields.sessionTokenVerifier,
+			}
+			got, err := c.RecordSessionActivity(tt.args.ctx, "sessionID", tt.args.sessionToken)
+			require.ErrorIs(t, err, tt.res.err)
+			assert.Equal(t, tt.res.want, got)
+		})
+	}
+}
+
+func TestCommands_UpdateSession_allowedAuthMethods(t *testing.T) {
+	ctx := authz.NewMockContext("", "org1", "user1")
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	c := &Commands{
+		eventstore: eventstoreExpect(t,
+			expectFilter(),
+			expectFilter(
+				eventFromEventPusher(session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", []domain.UserAuthMethodType{
+					domain.UserAuthMethodTypePassword,
+					domain.UserAuthMethodTypeU2F,
+					domain.UserAuthMethodTypePasswordless,
+				})),
+				eventFromEventPusher(session.NewUserCheckedEvent(ctx, sessionAggregate, "userID", testNow)),
+			),
+		),
+		checkPermission: newMockPermissionCheckAllowed(),
+	}
+	_, err := c.UpdateSession(ctx, "sessionID", "", []SessionCommand{CheckTOTP("123456")}, nil)
+	require.ErrorIs(t, err, ErrSessionAuthMethodNotAllowed)
+}
+
+func TestCommands_RefreshWebAuthNChallenge(t *testing.T) {
+	type fields struct {
+		eventstore      *eventstore.Eventstore
+		checkPermission domain.PermissionCheck
+	}
+	type args struct {
+		ctx       context.Context
+		sessionID string
+		challenge string
+	}
+	type res struct {
+		want *domain.ObjectDetails
+		err  error
+	}
+	tests := []struct {
+		name   string
+		fields fields
+		args   args
+		res    res
+	}{
+		{
+			"missing challenge",
+			fields{
+				eventstore: eventstoreExpect(t),
+			},
+			args{
+				ctx:       context.Background(),
+				sessionID: "sessionID",
+			},
+			res{
+				err: caos_errs.ThrowInvalidArgument(nil, "COMMAND-Eew2u", "Errors.Session.WebAuthN.NoChallenge"),
+			},
+		},
+		{
+			"missing permission",
+			fields{
+				eventstore: eventstoreExpect(t,
+					expectFilter(),
+					expectFilter(
+						eventFromEventPusher(
+							session.NewAddedEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate, 0, "", "", "", nil)),
+					),
+				),
+				checkPermission: newMockPermissionCheckNotAllowed(),
+			},
+			args{
+				ctx:       context.Background(),
+				sessionID: "sessionID",
+				challenge: "challenge2",
+			},
+			res{
+				err: caos_errs.ThrowPermissionDenied(nil, "AUTHZ-HKJD33", "Errors.PermissionDenied"),
+			},
+		},
+		{
+			"terminated",
+			fields{
+				eventstore: eventstoreExpect(t,
+					expectFilter(),
+					expectFilter(
+						eventFromEventPusher(
+							session.NewAddedEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate, 0, "", "", "", nil)),
+						eventFromEventPusher(
+							session.NewTerminateEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate, domain.SessionTerminationTypeLogout)),
+					),
+				),
+				checkPermission: newMockPermissionCheckAllowed(),
+			},
+			args{
+				ctx:       context.Background(),
+				sessionID: "sessionID",
+				challenge: "challenge2",
+			},
+			res{
+				err: ErrSessionTerminated,
+			},
+		},
+		{
+			"no challenge to refresh",
+			fields{
+				eventstore: eventstoreExpect(t,
+					expectFilter(),
+					expectFilter(
+						eventFromEventPusher(
+							session.NewAddedEvent(context.Background(), &session.NewAggregate("sessionID", "org1").Aggregate, 0, "", "", "", nil)),
+					),
+				),
+				checkPermission: newMockPermissionCheckAllowed(),
+			},
+			args{
+				ctx:       context.Background(),
+				sessionID: "sessionID",
+				challenge: "challenge2",
+			},
+			res{
+				err: caos_errs.ThrowPreconditionFailed(nil, "COMMAND-ooP6g", "Errors.Session.WebAuthN.NoChallenge"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Commands{
+				eventstore:      tt.fields.eventstore,
+				checkPermission: tt.fields.checkPermission,
+			}
+			got, err := c.RefreshWebAuthNChallenge(tt.args.ctx, tt.args.sessionID, tt.args.challenge, nil)
+			require.ErrorIs(t, err, tt.res.err)
+			assert.Equal(t, tt.res.want, got)
+		})
+	}
+}
+
+func Test_webAuthNChallengeRefreshed(t *testing.T) {
+	ctx := context.Background()
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	expiration := testNow.Add(5 * time.Minute)
+	newSession := func(t *testing.T) *SessionWriteModel {
+		wm := NewSessionWriteModel("sessionID", "org1")
+		err := AppendAndReduce(wm,
+			session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+			session.NewWebAuthNChallengedEvent(ctx, sessionAggregate, "challenge1", [][]byte{[]byte("credential1")}, nil, domain.UserVerificationRequirementRequired, "example.com", testNow),
+		)
+		require.NoError(t, err)
+		return wm
+	}
+
+	t.Run("credential ids preserved", func(t *testing.T) {
+		wm := newSession(t)
+		refreshed, err := webAuthNChallengeRefreshed(ctx, wm, "challenge2", nil, expiration)
+		require.NoError(t, err)
+		require.NoError(t, AppendAndReduce(wm, refreshed))
+
+		assert.Equal(t, &WebAuthNChallengeModel{
+			Challenge:            "challenge2",
+			AllowedCredentialIDs: [][]byte{[]byte("credential1")},
+			UserVerification:     domain.UserVerificationRequirementRequired,
+			RPID:                 "example.com",
+			Expiration:           expiration,
+		}, wm.WebAuthNChallenge)
+		_, ok := wm.WebAuthNChallengeByID("challenge1")
+		assert.False(t, ok)
+		_, ok = wm.WebAuthNChallengeByID("challenge2")
+		assert.True(t, ok)
+	})
+	t.Run("credential ids replaced", func(t *testing.T) {
+		wm := newSession(t)
+		refreshed, err := webAuthNChallengeRefreshed(ctx, wm, "challenge2", [][]byte{[]byte("credential2")}, expiration)
+		require.NoError(t, err)
+		require.NoError(t, AppendAndReduce(wm, refreshed))
+
+		assert.Equal(t, [][]byte{[]byte("credential2")}, wm.WebAuthNChallenge.AllowedCredentialIDs)
+	})
+}
+
+func TestCommands_InvalidateSessionPasswordChecks(t *testing.T) {
+	changedAt := testNow.Add(time.Minute)
+	type fields struct {
+		eventstore *eventstore.Eventstore
+	}
+	type res struct {
+		want *domain.ObjectDetails
+		err  error
+	}
+	tests := []struct {
+		name   string
+		fields fields
+		res    res
+	}{
+		{
+			"eventstore failed",
+			fields{
+				eventstore: eventstoreExpect(t,
+					expectFilterError(caos_errs.ThrowInternal(nil, "id", "filter failed")),
+				),
+			},
+			res{
+				err: caos_errs.ThrowInternal(nil, "id", "filter failed"),
+			},
+		},
+		{
+			"no password checked",
+			fields{
+				eventstore: eventstoreExpect(t,
+					expectFilter(
+						eventFromEventPusher(
+							session.NewUserCheckedEvent(context.Background(), &session.NewAggregate("sessionID1", "org1").Aggregate, "userID", testNow)),
+					),
+					expectFilter(
+						eventFromEventPusher(
+							session.NewAddedEvent(context.Background(), &session.NewAggregate("sessionID1", "org1").Aggregate, 0, "", "", "", nil)),
+						eventFromEventPusher(
+							session.NewUserCheckedEvent(context.Background(), &session.NewAggregate("sessionID1", "org1").Aggregate, "userID", testNow)),
+					),
+				),
+			},
+			res{
+				want: &domain.ObjectDetails{},
+			},
+		},
+		{
+			"invalidate password checks of active sessions before the change",
+			fields{
+				eventstore: eventstoreExpect(t,
+					expectFilter(
+						eventFromEventPusher(
+							session.NewUserCheckedEvent(context.Background(), &session.NewAggregate("sessionID1", "org1").Aggregate, "userID", testNow)),
+						eventFromEventPusher(
+							session.NewUserCheckedEvent(context.Background(), &session.NewAggregate("sessionID2", "org1").Aggregate, "userID", testNow)),
+						eventFromEventPusher(
+							session.NewUserCheckedEvent(context.Background(), &session.NewAggregate("sessionID3", "org1").Aggregate, "userID", testNow)),
+					),
+					expectFilter(
+						eventFromEventPusher(
+							session.NewAddedEvent(context.Background(), &session.NewAggregate("sessionID1", "org1").Aggregate, 0, "", "", "", nil)),
+						eventFromEventPusher(
+							session.NewUserCheckedEvent(context.Background(), &session.NewAggregate("sessionID1", "org1").Aggregate, "userID", testNow)),
+						eventFromEventPusher(
+							session.NewPasswordCheckedEvent(context.Background(), &session.NewAggregate("sessionID1", "org1").Aggregate, testNow, "")),
+						eventFromEventPusher(
+							session.NewAddedEvent(context.Background(), &session.NewAggregate("sessionID2", "org1").Aggregate, 0, "", "", "", nil)),
+						eventFromEventPusher(
+							session.NewUserCheckedEvent(context.Background(), &session.NewAggregate("sessionID2", "org1").Aggregate, "userID", testNow)),
+						eventFromEventPusher(
+							session.NewPasswordCheckedEvent(context.Background(), &session.NewAggregate("sessionID2", "org1").Aggregate, changedAt.Add(time.Second), "")),
+						eventFromEventPusher(
+							session.NewAddedEvent(context.Background(), &session.NewAggregate("sessionID3", "org1").Aggregate, 0, "", "", "", nil)),
+						eventFromEventPusher(
+							session.NewUserCheckedEvent(context.Background(), &session.NewAggregate("sessionID3", "org1").Aggregate, "userID", testNow)),
+						eventFromEventPusher(
+							session.NewPasswordCheckedEvent(context.Background(), &session.NewAggregate("sessionID3", "org1").Aggregate, testNow, "")),
+						eventFromEventPusher(
+							session.NewTerminateEvent(context.Background(), &session.NewAggregate("sessionID3", "org1").Aggregate, domain.SessionTerminationTypeLogout)),
+					),
+					expectPush(
+						eventPusherToEvents(
+							session.NewPasswordChangedEvent(context.Background(), &session.NewAggregate("sessionID1", "org1").Aggregate, changedAt),
+						),
+					),
+				),
+			},
+			res{
+				want: &domain.ObjectDetails{ResourceOwner: "org1"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Commands{
+				eventstore: tt.fields.eventstore,
+			}
+			got, err := c.InvalidateSessionPasswordChecks(context.Background(), "userID", changedAt)
+			require.ErrorIs(t, err, tt.res.err)
+			assert.Equal(t, tt.res.want, got)
+		})
+	}
+}
+
+func TestCommands_TerminateUserSessionsExcept(t *testing.T) {
+	type fields struct {
+		eventstore      *eventstore.Eventstore
+		checkPermission domain.PermissionCheck
+	}
+	type args struct {
+		ctx           context.Context
+		userID        string
+		keepSessionID string
+	}
+	type res struct {
+		want *domain.ObjectDetails
+		err  error
+	}
+	userSessionEvents := func() []*repository.Event {
+		var events []*repository.Event
+		for _, id := range []string{"sessionID1", "sessionID2", "sessionID3"} {
+			events = append(events,
+				eventFromEventPusher(
+					session.NewAddedEvent(context.Background(), &session.NewAggregate(id, "org1").Aggregate, 0, "", "", "", nil)),
+				eventFromEventPusher(
+					session.NewUserCheckedEvent(context.Background(), &session.NewAggregate(id, "org1").Aggregate, "userID", testNow)),
+			)
+		}
+		return events
+	}
+	tests := []struct {
+		name   string
+		fields fields
+		args   args
+		res    res
+	}{
+		{
+			"missing permission",
+			fields{
+				eventstore: eventstoreExpect(t,
+					expectFilter(
+						eventFromEventPusher(
+							session.NewUserCheckedEvent(context.Background(), &session.NewAggregate("sessionID1", "org1").Aggregate, "userID", testNow)),
+						eventFromEventPusher(
+							session.NewUserCheckedEvent(context.Background(), &session.NewAggregate("sessionID2", "org1").Aggregate, "userID", testNow)),
+					),
+					expectFilter(userSessionEvents()...),
+				),
+				checkPermission: newMockPermissionCheckNotAllowed(),
+			},
+			args{
+				ctx:           context.Background(),
+				userID:        "userID",
+				keepSessionID: "sessionID2",
+			},
+			res{
+				err: caos_errs.ThrowPermissionDenied(nil, "AUTHZ-HKJD33", "Errors.PermissionDenied"),
+			},
+		},
+		{
+			"only current session",
+			fields{
+				eventstore: eventstoreExpect(t,
+					expectFilter(
+						eventFromEventPusher(
+							session.NewUserCheckedEvent(context.Background(), &session.NewAggregate("sessionID2", "org1").Aggregate, "userID", testNow)),
+					),
+					expectFilter(
+						eventFromEventPusher(
+							session.NewAddedEvent(context.Background(), &session.NewAggregate("sessionID2", "org1").Aggregate, 0, "", "", "", nil)),
+						eventFromEventPusher(
+							session.NewUserCheckedEvent(context.Background(), &session.NewAggregate("sessionID2", "org1").Aggregate, "userID", testNow)),
+					),
+				),
+				checkPermission: newMockPermissionCheckAllowed(),
+			},
+			args{
+				ctx:           context.Background(),
+				userID:        "userID",
+				keepSessionID: "sessionID2",
+			},
+			res{
+				want: &domain.ObjectDetails{},
+			},
+		},
+		{
+			"terminate all other sessions",
+			fields{
+				eventstore: eventstoreExpect(t,
+					expectFilter(
+						eventFromEventPusher(
+							session.NewUserCheckedEvent(context.Background(), &session.NewAggregate("sessionID1", "org1").Aggregate, "userID", testNow)),
+						eventFromEventPusher(
+							session.NewUserCheckedEvent(context.Background(), &session.NewAggregate("sessionID2", "org1").Aggregate, "userID", testNow)),
+						eventFromEventPusher(
+							session.NewUserCheckedEvent(context.Background(), &session.NewAggregate("sessionID3", "org1").Aggregate, "userID", testNow)),
+					),
+					expectFilter(userSessionEvents()...),
+					expectPush(
+						eventPusherToEvents(
+							session.NewTerminateEvent(context.Background(), &session.NewAggregate("sessionID1", "org1").Aggregate, domain.SessionTerminationTypeLogout),
+							session.NewTerminateEvent(context.Background(), &session.NewAggregate("sessionID3", "org1").Aggregate, domain.SessionTerminationTypeLogout),
+						),
+					),
+				),
+				checkPermission: newMockPermissionCheckAllowed(),
+			},
+			args{
+				ctx:           context.Background(),
+				userID:        "userID",
+				keepSessionID: "sessionID2",
+			},
+			res{
+				want: &domain.ObjectDetails{
+					ResourceOwner: "org1",
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Commands{
+				eventstore:      tt.fields.eventstore,
+				checkPermission: tt.fields.checkPermission,
+			}
+			got, err := c.TerminateUserSessionsExcept(tt.args.ctx, tt.args.userID, tt.args.keepSessionID)
+			require.ErrorIs(t, err, tt.res.err)
+			assert.Equal(t, tt.res.want, got)
+		})
+	}
+}
+
+func TestCheckUserAndPassword(t *testing.T) {
+	ctx := context.Background()
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	c := &Commands{
+		eventstore: eventstoreExpect(t,
+			expectPush(
+				eventPusherToEvents(
+					session.NewUserCheckedEvent(ctx, sessionAggregate, "userID", testNow),
+					session.NewPasswordCheckedEvent(ctx, sessionAggregate, testNow, ""),
+					session.NewTokenSetEvent(ctx, sessionAggregate, "tokenID"),
+				),
+			),
+		),
+	}
+	checks := &SessionCommands{
+		sessionWriteModel: NewSessionWriteModel("sessionID", "org1"),
+		sessionCommands:   []SessionCommand{CheckUserAndPassword("userID", "password")},
+		eventstore: eventstoreExpect(t,
+			expectFilter(
+				eventFromEventPusher(
+					user.NewHumanAddedEvent(ctx, &user.NewAggregate("userID", "org1").Aggregate,
+						"username", "", "", "", "", language.English, domain.GenderUnspecified, "", false),
+				),
+				eventFromEventPusher(
+					user.NewHumanPasswordChangedEvent(ctx, &user.NewAggregate("userID", "org1").Aggregate,
+						"$plain$x$password", false, ""),
+				),
+			),
+		),
+		createToken: func(sessionID string) (string, string, error) {
+			return "tokenID", "token", nil
+		},
+		hasher: mockPasswordHasher("x"),
+		now: func() time.Time {
+			return testNow
+		},
+	}
+	changed, err := c.updateSession(ctx, checks, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "token", changed.NewToken)
+	assert.Equal(t, "userID", checks.sessionWriteModel.UserID)
+	assert.True(t, testNow.Equal(checks.sessionWriteModel.UserCheckedAt))
+	assert.True(t, testNow.Equal(checks.sessionWriteModel.PasswordCheckedAt))
+}
+
+func TestSessionCommands_ChangeMetadata_limits(t *testing.T) {
+	ctx := context.Background()
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	sessionWriteModel := NewSessionWriteModel("sessionID", "org1")
+	sessionWriteModel.MetadataLimits = SessionMetadataLimits{MaxEntries: 1}
+	// stored metadata exceeding the limits is still reduced
+	err := AppendAndReduce(sessionWriteModel,
+		session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+		session.NewMetadataSetEvent(ctx, sessionAggregate, map[string][]byte{"key1": []byte("value"), "key2": []byte("value")}, nil),
+	)
+	require.NoError(t, err)
+	checks := &SessionCommands{
+		sessionWriteModel: sessionWriteModel,
+	}
+
+	err = checks.ChangeMetadata(ctx, map[string][]byte{"key3": []byte("value")})
+	require.ErrorIs(t, err, caos_errs.ThrowInvalidArgument(nil, "COMMAND-Ohb3e", "Errors.Session.Metadata.TooManyEntries"))
+	assert.Empty(t, checks.eventCommands)
+
+	// removing entries is always possible
+	err = checks.ChangeMetadata(ctx, map[string][]byte{"key2": nil})
+	require.NoError(t, err)
+	assert.Len(t, checks.eventCommands, 1)
+}
+
+func TestSetMetadataWithTTL(t *testing.T) {
+	ctx := context.Background()
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	c := &Commands{
+		eventstore: eventstoreExpect(t,
+			expectPush(
+				eventPusherToEvents(
+					session.NewMetadataSetEvent(ctx, sessionAggregate,
+						map[string][]byte{"keep": []byte("value"), "temp": []byte("token")},
+						map[string]time.Time{"temp": testNow.Add(time.Minute)},
+					),
+					session.NewTokenSetEvent(ctx, sessionAggregate, "tokenID"),
+				),
+			),
+		),
+	}
+	sessionWriteModel := NewSessionWriteModel("sessionID", "org1")
+	err := AppendAndReduce(sessionWriteModel,
+		session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+		session.NewMetadataSetEvent(ctx, sessionAggregate,
+			map[string][]byte{"keep": []byte("value"), "old": []byte("token")},
+			map[string]time.Time{"old": testNow.Add(-time.Minute)},
+		),
+	)
+	require.NoError(t, err)
+	checks := &SessionCommands{
+		sessionWriteModel: sessionWriteModel,
+		sessionCommands:   []SessionCommand{SetMetadataWithTTL("temp", []byte("token"), time.Minute)},
+		createToken: func(sessionID string) (string, string, error) {
+			return "tokenID", "token", nil
+		},
+		now: func() time.Time {
+			return testNow
+		},
+	}
+	_, err = c.updateSession(ctx, checks, nil)
+	require.NoError(t, err)
+
+	value, ok := sessionWriteModel.GetMetadata("temp", testNow)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("token"), value)
+	_, ok = sessionWriteModel.GetMetadata("temp", testNow.Add(2*time.Minute))
+	assert.False(t, ok)
+	// keys without TTL do not expire
+	_, ok = sessionWriteModel.GetMetadata("keep", testNow.Add(time.Hour))
+	assert.True(t, ok)
+	// the expired key was removed
+	_, ok = sessionWriteModel.Metadata["old"]
+	assert.False(t, ok)
+}
+
+func TestCheckUserAndPassword_invalidPassword(t *testing.T) {
+	ctx := context.Background()
+	c := &Commands{
+		// only the failed check must be pushed, but not the user check
+		eventstore: eventstoreExpect(t,
+			expectPush(
+				eventPusherToEvents(
+					session.NewPasswordCheckFailedEvent(ctx, &session.NewAggregate("sessionID", "org1").Aggregate, testNow),
+				),
+			),
+		),
+	}
+	checks := &SessionCommands{
+		sessionWriteModel: NewSessionWriteModel("sessionID", "org1"),
+		sessionCommands:   []SessionCommand{CheckUserAndPassword("userID", "wrong")},
+		eventstore: eventstoreExpect(t,
+			expectFilter(
+				eventFromEventPusher(
+					user.NewHumanAddedEvent(ctx, &user.NewAggregate("userID", "org1").Aggregate,
+						"username", "", "", "", "", language.English, domain.GenderUnspecified, "", false),
+				),
+				eventFromEventPusher(
+					user.NewHumanPasswordChangedEvent(ctx, &user.NewAggregate("userID", "org1").Aggregate,
+						"$plain$x$password", false, ""),
+				),
+			),
+		),
+		hasher: mockPasswordHasher("x"),
+		now: func() time.Time {
+			return testNow
+		},
+	}
+	_, err := c.updateSession(ctx, checks, nil)
+	require.Error(t, err)
+	assert.True(t, caos_errs.IsErrorInvalidArgument(err))
+}
+
+func TestCheckPassword_locked(t *testing.T) {
+	ctx := context.Background()
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	sessionWriteModel := NewSessionWriteModel("sessionID", "org1")
+	err := AppendAndReduce(sessionWriteModel,
+		session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+		session.NewUserCheckedEvent(ctx, sessionAggregate, "userID", testNow),
+		session.NewPasswordCheckFailedEvent(ctx, sessionAggregate, testNow),
+		session.NewPasswordCheckFailedEvent(ctx, sessionAggregate, testNow),
+	)
+	require.NoError(t, err)
+	c := &Commands{
+		// nothing must be pushed
+		eventstore: eventstoreExpect(t),
+	}
+	checks := &SessionCommands{
+		sessionWriteModel: sessionWriteModel,
+		sessionCommands:   []SessionCommand{CheckPassword("password")},
+		// the password must not be verified anymore
+		eventstore: eventstoreExpect(t),
+		hasher:     mockPasswordHasher("x"),
+		now: func() time.Time {
+			return testNow
+		},
+		maxPasswordCheckFailures: 2,
+	}
+	_, err = c.updateSession(ctx, checks, nil)
+	require.ErrorIs(t, err, ErrSessionPasswordLocked)
+}
+
+func TestCheckPassword_userLocked(t *testing.T) {
+	ctx := context.Background()
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	sessionWriteModel := NewSessionWriteModel("sessionID", "org1")
+	err := AppendAndReduce(sessionWriteModel,
+		session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+		session.NewUserCheckedEvent(ctx, sessionAggregate, "userID", testNow),
+	)
+	require.NoError(t, err)
+	// the failed checks of the other sessions of the user are counted
+	counter := &Commands{
+		eventstore: eventstoreExpect(t,
+			expectFilter(
+				eventFromEventPusher(
+					session.NewUserCheckedEvent(ctx, &session.NewAggregate("sessionID1", "org1").Aggregate, "userID", testNow)),
+				eventFromEventPusher(
+					session.NewUserCheckedEvent(ctx, &session.NewAggregate("sessionID2", "org2").Aggregate, "userID", testNow)),
+			),
+			expectFilter(
+				eventFromEventPusher(
+					session.NewPasswordCheckFailedEvent(ctx, &session.NewAggregate("sessionID1", "org1").Aggregate, testNow)),
+				eventFromEventPusher(
+					session.NewPasswordCheckFailedEvent(ctx, &session.NewAggregate("sessionID2", "org2").Aggregate, testNow)),
+			),
+		),
+	}
+	c := &Commands{
+		// nothing must be pushed
+		eventstore: eventstoreExpect(t),
+	}
+	checks := &SessionCommands{
+		sessionWriteModel: sessionWriteModel,
+		sessionCommands:   []SessionCommand{CheckPassword("password")},
+		// the password must not be verified anymore
+		eventstore: eventstoreExpect(t),
+		hasher:     mockPasswordHasher("x"),
+		now: func() time.Time {
+			return testNow
+		},
+		userPasswordChecks:              counter.UserPasswordChecks,
+		maxUserPasswordCheckFailures:    2,
+		userPasswordCheckFailuresWindow: time.Hour,
+	}
+	_, err = c.updateSession(ctx, checks, nil)
+	require.ErrorIs(t, err, ErrUserPasswordLocked)
+}
+
+func TestCheckPasswordIdempotent(t *testing.T) {
+	ctx := context.Background()
+	passwordEvents := func() []*repository.Event {
+		return []*repository.Event{
+			eventFromEventPusher(
+				user.NewHumanAddedEvent(ctx, &user.NewAggregate("userID", "org1").Aggregate,
+					"username", "", "", "", "", language.English, domain.GenderUnspecified, "", false),
+			),
+			eventFromEventPusher(
+				user.NewHumanPasswordChangedEvent(ctx, &user.NewAggregate("userID", "org1").Aggregate,
+					"$plain$x$password", false, ""),
+			),
+		}
+	}
+	sessionModel := NewSessionWriteModel("sessionID", "org1")
+	err := AppendAndReduce(sessionModel,
+		session.NewAddedEvent(ctx, &session.NewAggregate("sessionID", "org1").Aggregate, 0, "", "", "", nil),
+		session.NewUserCheckedEvent(ctx, &session.NewAggregate("sessionID", "org1").Aggregate, "userID", testNow),
+	)
+	require.NoError(t, err)
+	es := eventstoreExpect(t,
+		expectFilter(passwordEvents()...),
+		expectFilter(passwordEvents()...),
+		expectFilter(passwordEvents()...),
+	)
+	check := func(idempotencyKey string, now time.Time) []eventstore.Command {
+		cmd := &SessionCommands{
+			sessionWriteModel: sessionModel,
+			eventstore:        es,
+			hasher:            mockPasswordHasher("x"),
+			now: func() time.Time {
+				return now
+			},
+		}
+		require.NoError(t, CheckPasswordIdempotent("password", idempotencyKey)(ctx, cmd))
+		for _, command := range cmd.eventCommands {
+			require.NoError(t, AppendAndReduce(sessionModel, command.(eventstore.Event)))
+		}
+		return cmd.eventCommands
+	}
+
+	// first check is recorded
+	assert.Len(t, check("key1", testNow), 1)
+	// retry with the same key is not recorded again
+	assert.Empty(t, check("key1", testNow.Add(time.Second)))
+	// a new key is recorded
+	assert.Len(t, check("key2", testNow.Add(2*time.Second)), 1)
+
+	assert.Equal(t, IdempotentCheck{IdempotencyKey: "key2", CheckedAt: testNow.Add(2 * time.Second)}, sessionModel.IdempotentChecks[domain.UserAuthMethodTypePassword])
+	passwordChecks := 0
+	for _, check := range sessionModel.CheckHistory {
+		if check.Factor == domain.UserAuthMethodTypePassword {
+			passwordChecks++
+		}
+	}
+	assert.Equal(t, 2, passwordChecks)
+}
+
+func TestCommands_SessionIDsWithMetadata(t *testing.T) {
+	type fields struct {
+		eventstore *eventstore.Eventstore
+	}
+	type res struct {
+		want []string
+		err  error
+	}
+	tests := []struct {
+		name   string
+		fields fields
+		res    res
+	}{
+		{
+			"filter failed",
+			fields{
+				eventstore: eventstoreExpect(t,
+					expectFilterError(caos_errs.ThrowInternal(nil, "id", "filter failed")),
+				),
+			},
+			res{
+				err: caos_errs.ThrowInternal(nil, "id", "filter failed"),
+			},
+		},
+		{
+			"no sessions",
+			fields{
+				eventstore: eventstoreExpect(t,
+					expectFilter(),
+				),
+			},
+			res{
+				want: []string{},
+			},
+		},
+		{
+			"one of multiple sessions",
+			fields{
+				eventstore: eventstoreExpect(t,
+					expectFilter(
+						eventFromEventPusher(
+							session.NewMetadataSetEvent(context.Background(), &session.NewAggregate("sessionID1", "org1").Aggregate, map[string][]byte{"kiosk": []byte("lobby")}, nil)),
+						eventFromEventPusher(
+							session.NewMetadataSetEvent(context.Background(), &session.NewAggregate("sessionID2", "org1").Aggregate, map[string][]byte{"kiosk": []byte("entrance")}, nil)),
+						eventFromEventPusher(
+							session.NewMetadataBulkSetEvent(context.Background(), &session.NewAggregate("sessionID3", "org1").Aggregate, map[string][]byte{"kiosk": []byte("entrance"), "other": []byte("value")})),
+						eventFromEventPusher(
+							session.NewMetadataSetEvent(context.Background(), &session.NewAggregate("sessionID4", "org1").Aggregate, map[string][]byte{"other": []byte("lobby")}, nil)),
+						eventFromEventPusher(
+							session.NewMetadataSetEvent(context.Background(), &session.NewAggregate("sessionID2", "org1").Aggregate, map[string][]byte{"kiosk": []byte("entrance")}, nil)),
+					),
+				),
+			},
+			res{
+				want: []string{"sessionID2", "sessionID3"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Commands{
+				eventstore: tt.fields.eventstore,
+			}
+			got, err := c.SessionIDsWithMetadata(context.Background(), "org1", "kiosk", []byte("entrance"))
+			require.ErrorIs(t, err, tt.res.err)
+			assert.Equal(t, tt.res.want, got)
+		})
+	}
+}
+
+func TestCommands_userPasswordChecks(t *testing.T) {
+	type fields struct {
+		eventstore *eventstore.Eventstore
+	}
+	type res struct {
+		want *UserPasswordChecks
+		err  error
+	}
+	tests := []struct {
+		name   string
+		fields fields
+		res    res
+	}{
+		{
+			"filter failed",
+			fields{
+				eventstore: eventstoreExpect(t,
+					expectFilterError(caos_errs.ThrowInternal(nil, "id", "filter failed")),
+				),
+			},
+			res{
+				err: caos_errs.ThrowInternal(nil, "id", "filter failed"),
+			},
+		},
+		{
+			"no sessions",
+			fields{
+				eventstore: eventstoreExpect(t,
+					expectFilter(),
+				),
+			},
+			res{
+				want: &UserPasswordChecks{},
+			},
+		},
+		{
+			"checks of multiple sessions",
+			fields{
+				eventstore: eventstoreExpect(t,
+					expectFilter(
+						eventFromEventPusher(
+							session.NewUserCheckedEvent(context.Background(), &session.NewAggregate("sessionID1", "org1").Aggregate, "userID", testNow)),
+						eventFromEventPusher(
+							session.NewUserCheckedEvent(context.Background(), &session.NewAggregate("sessionID2", "org1").Aggregate, "userID", testNow)),
+					),
+					expectFilter(
+						eventFromEventPusher(
+							session.NewPasswordCheckFailedEvent(context.Background(), &session.NewAggregate("sessionID1", "org1").Aggregate, testNow)),
+						eventFromEventPusher(
+							session.NewPasswordCheckFailedEvent(context.Background(), &session.NewAggregate("sessionID1", "org1").Aggregate, testNow)),
+						eventFromEventPusher(
+							session.NewPasswordCheckedEvent(context.Background(), &session.NewAggregate("sessionID1", "org1").Aggregate, testNow, "")),
+						eventFromEventPusher(
+							session.NewPasswordCheckFailedEvent(context.Background(), &session.NewAggregate("sessionID2", "org1").Aggregate, testNow)),
+					),
+				),
+			},
+			res{
+				want: &UserPasswordChecks{
+					Succeeded: 1,
+					Failed:    3,
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Commands{
+				eventstore: tt.fields.eventstore,
+			}
+			got, err := c.UserPasswordChecks(context.Background(), "userID", "org1", testNow.Add(-time.Hour))
+			require.ErrorIs(t, err, tt.res.err)
+			assert.Equal(t, tt.res.want, got)
+		})
+	}
+}
@@ -3,11 +3,22 @@ package command
 import (
 	"context"
 	"encoding/json"
+	"time"
 
 	"github.com/zitadel/zitadel/internal/domain"
 	caos_errs "github.com/zitadel/zitadel/internal/errors"
+	webauthn_helper "github.com/zitadel/zitadel/internal/webauthn"
 )
 
+const defaultWebAuthNChallengeLifetime = 5 * time.Minute
+
+func (c *Commands) webAuthNChallengeLifetime() time.Duration {
+	if c.webauthnChallengeLifetime > 0 {
+		return c.webauthnChallengeLifetime
+	}
+	return defaultWebAuthNChallengeLifetime
+}
+
 type humanWebAuthNTokens struct {
 	human  *domain.Human
 	tokens []*domain.WebAuthNToken
@@ -46,6 +57,14 @@ func (c *Commands) CreateWebAuthNChallenge(userVerification domain.UserVerificat
 		if err != nil {
 			return err
 		}
+		policy, err := c.getOrgLoginPolicy(ctx, humanPasskeys.human.ResourceOwner)
+		if err != nil {
+			return err
+		}
+		// prevent a downgrade of the user verification the login policy requires
+		if err = checkPolicyUserVerification(userVerification, loginPolicyUserVerification(policy, cmd.sessionWriteModel)); err != nil {
+			return err
+		}
 		webAuthNLogin, err := c.webauthnConfig.BeginLogin(ctx, humanPasskeys.human, userVerification, rpid, humanPasskeys.tokens...)
 		if err != nil {
 			return err
@@ -54,7 +73,7 @@ func (c *Commands) CreateWebAuthNChallenge(userVerification domain.UserVerificat
 			return caos_errs.ThrowInternal(err, "COMMAND-Yah6A", "Errors.Internal")
 		}
 
-		cmd.WebAuthNChallenged(ctx, webAuthNLogin.Challenge, webAuthNLogin.AllowedCredentialIDs, webAuthNLogin.UserVerification, rpid)
+		cmd.WebAuthNChallenged(ctx, webAuthNLogin.Challenge, webAuthNLogin.AllowedCredentialIDs, webAuthNLogin.AllowedTransports, webAuthNLogin.UserVerification, rpid, cmd.now().Add(c.webAuthNChallengeLifetime()))
 		return nil
 	}
 }
@@ -65,15 +84,38 @@ func (c *Commands) CheckWebAuthN(credentialAssertionData json.Marshaler) Session
 		if err != nil {
 			return caos_errs.ThrowInternal(err, "COMMAND-ohG2o", "Errors.Internal")
 		}
-		challenge := cmd.sessionWriteModel.WebAuthNChallenge
-		if challenge == nil {
+		challenge, ok := cmd.sessionWriteModel.ActiveWebAuthNChallenge()
+		// with multiple outstanding challenges, use the one the assertion was created for
+		clientData, clientDataErr := webauthn_helper.AssertionClientData(credentialAssertionData)
+		if clientDataErr == nil {
+			challenge, ok = cmd.sessionWriteModel.WebAuthNChallengeByID(clientData.Challenge)
+		}
+		if !ok {
 			return caos_errs.ThrowPreconditionFailed(nil, "COMMAND-Ioqu5", "Errors.Session.WebAuthN.NoChallenge")
 		}
+		if !challenge.IsValid(cmd.now()) {
+			return caos_errs.ThrowPreconditionFailed(nil, "COMMAND-Oonu6", "Errors.Session.WebAuthN.ChallengeExpired")
+		}
+		if err = cmd.sessionWriteModel.CheckAuthMethodAllowed(challenge.AuthMethodType()); err != nil {
+			return err
+		}
+		if clientDataErr == nil {
+			if err = challenge.ValidateRPID(clientData.Origin); err != nil {
+				return err
+			}
+		}
 		webAuthNTokens, err := cmd.getHumanWebAuthNTokens(ctx, challenge.UserVerification)
 		if err != nil {
 			return err
 		}
-		webAuthN := challenge.WebAuthNLogin(webAuthNTokens.human, credentialAssertionData)
+		policy, err := c.getOrgLoginPolicy(ctx, webAuthNTokens.human.ResourceOwner)
+		if err != nil {
+			return err
+		}
+		webAuthN, err := challenge.WebAuthNLogin(webAuthNTokens.human, credentialAssertionData, loginPolicyUserVerification(policy, cmd.sessionWriteModel))
+		if err != nil {
+			return err
+		}
 
 		credential, err := c.webauthnConfig.FinishLogin(ctx, webAuthNTokens.human, webAuthN, credentialAssertionData, webAuthNTokens.tokens...)
 		if err != nil && (credential == nil || credential.ID == nil) {
@@ -83,7 +125,31 @@ func (c *Commands) CheckWebAuthN(credentialAssertionData json.Marshaler) Session
 		if token == nil {
 			return caos_errs.ThrowPreconditionFailed(nil, "COMMAND-Aej7i", "Errors.User.WebAuthN.NotFound")
 		}
-		cmd.WebAuthNChecked(ctx, cmd.now(), token.WebAuthNTokenID, credential.Authenticator.SignCount, credential.Flags.UserVerified)
+		// the extension results are informational only, the assertion was already validated
+		extensionResults, _ := webauthn_helper.AssertionExtensionResults(credentialAssertionData)
+		cmd.WebAuthNChecked(ctx, cmd.now(), challenge, token.WebAuthNTokenID, credential.Authenticator.SignCount, credential.Flags.UserVerified, credential.Flags.UserPresent, token.AttestationType, extensionResults)
 		return nil
 	}
 }
+
+// loginPolicyUserVerification returns the user verification the login policy requires for a WebAuthN check on the session.
+// If the policy enforces multiple factors and passwordless authentication is allowed,
+// a WebAuthN check without any other checked factor must be user verified.
+func loginPolicyUserVerification(policy *domain.LoginPolicy, wm *SessionWriteModel) domain.UserVerificationRequirement {
+	if policy == nil || !policy.ForceMFA || policy.PasswordlessType != domain.PasswordlessTypeAllowed {
+		return domain.UserVerificationRequirementUnspecified
+	}
+	if !wm.PasswordCheckedAt.IsZero() || !wm.IntentCheckedAt.IsZero() {
+		return domain.UserVerificationRequirementUnspecified
+	}
+	return domain.UserVerificationRequirementRequired
+}
+
+// checkPolicyUserVerification returns an error if the policy requires user verification,
+// but the requested (or stored) user verification of the challenge is weaker.
+func checkPolicyUserVerification(userVerification, policyUserVerification domain.UserVerificationRequirement) error {
+	if policyUserVerification == domain.UserVerificationRequirementRequired && userVerification != domain.UserVerificationRequirementRequired {
+		return caos_errs.ThrowPreconditionFailed(nil, "COMMAND-Ahr0e", "Errors.Session.WebAuthN.UserVerificationRequired")
+	}
+	return nil
+}
@@ -2,8 +2,10 @@ package command
 
 import (
 	"context"
+	"encoding/json"
 	"io"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -14,6 +16,7 @@ import (
 	"github.com/zitadel/zitadel/internal/eventstore"
 	"github.com/zitadel/zitadel/internal/eventstore/v1/models"
 	"github.com/zitadel/zitadel/internal/repository/org"
+	"github.com/zitadel/zitadel/internal/repository/session"
 	"github.com/zitadel/zitadel/internal/repository/user"
 )
 
@@ -248,3 +251,371 @@ func TestSessionCommands_getHumanWebAuthNTokens(t *testing.T) {
 		assert.Equal(t, tt.res.want, got)
 	}
 }
+
+func TestCommands_CreateWebAuthNChallenge(t *testing.T) {
+	userAggr := &user.NewAggregate("user1", "org1").Aggregate
+	type args struct {
+		userVerification domain.UserVerificationRequirement
+	}
+	tests := []struct {
+		name       string
+		eventstore *eventstore.Eventstore
+		args       args
+		err        error
+	}{
+		{
+			name: "policy requires user verification, discouraged",
+			eventstore: eventstoreExpect(t,
+				expectFilter(
+					eventFromEventPusher(
+						user.NewHumanAddedEvent(context.Background(),
+							userAggr,
+							"", "", "", "", "", language.Georgian,
+							domain.GenderDiverse, "", true,
+						),
+					),
+				),
+				expectFilter(eventFromEventPusher(
+					user.NewHumanWebAuthNAddedEvent(eventstore.NewBaseEventForPush(
+						context.Background(), &org.NewAggregate("org1").Aggregate, user.HumanU2FTokenAddedType,
+					), "111", "challenge", "rpID"),
+				)),
+				expectFilter(
+					eventFromEventPusher(
+						org.NewLoginPolicyAddedEvent(context.Background(),
+							&org.NewAggregate("org1").Aggregate,
+							true, true, true, true, false, false, false, false, false, false,
+							domain.PasswordlessTypeAllowed,
+							"",
+							time.Hour, time.Hour, time.Hour, time.Hour, time.Hour,
+						),
+					),
+				),
+			),
+			args: args{
+				userVerification: domain.UserVerificationRequirementDiscouraged,
+			},
+			err: caos_errs.ThrowPreconditionFailed(nil, "COMMAND-Ahr0e", "Errors.Session.WebAuthN.UserVerificationRequired"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &SessionCommands{
+				sessionWriteModel: &SessionWriteModel{
+					UserID: "user1",
+				},
+				eventstore: tt.eventstore,
+				now: func() time.Time {
+					return testNow
+				},
+			}
+			c := &Commands{
+				eventstore: tt.eventstore,
+			}
+			err := c.CreateWebAuthNChallenge(tt.args.userVerification, "example.com", new(json.RawMessage))(context.Background(), cmd)
+			require.ErrorIs(t, err, tt.err)
+			assert.Empty(t, cmd.eventCommands)
+		})
+	}
+}
+
+func TestCommands_CheckWebAuthN(t *testing.T) {
+	type fields struct {
+		sessionWriteModel *SessionWriteModel
+	}
+	tests := []struct {
+		name   string
+		fields fields
+		err    error
+	}{
+		{
+			name: "no challenge",
+			fields: fields{
+				sessionWriteModel: &SessionWriteModel{
+					UserID: "user1",
+				},
+			},
+			err: caos_errs.ThrowPreconditionFailed(nil, "COMMAND-Ioqu5", "Errors.Session.WebAuthN.NoChallenge"),
+		},
+		{
+			name: "expired challenge",
+			fields: fields{
+				sessionWriteModel: &SessionWriteModel{
+					UserID: "user1",
+					WebAuthNChallenge: &WebAuthNChallengeModel{
+						Challenge:        "challenge",
+						UserVerification: domain.UserVerificationRequirementRequired,
+						RPID:             "example.com",
+						Expiration:       testNow.Add(-time.Minute),
+					},
+				},
+			},
+			err: caos_errs.ThrowPreconditionFailed(nil, "COMMAND-Oonu6", "Errors.Session.WebAuthN.ChallengeExpired"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &SessionCommands{
+				sessionWriteModel: tt.fields.sessionWriteModel,
+				now: func() time.Time {
+					return testNow
+				},
+			}
+			err := new(Commands).CheckWebAuthN(json.RawMessage(`{}`))(context.Background(), cmd)
+			require.ErrorIs(t, err, tt.err)
+			assert.Empty(t, cmd.eventCommands)
+		})
+	}
+}
+
+func TestWebAuthNChallengeModel_IsValid(t *testing.T) {
+	tests := []struct {
+		name       string
+		expiration time.Time
+		want       bool
+	}{
+		{
+			name:       "no expiration",
+			expiration: time.Time{},
+			want:       true,
+		},
+		{
+			name:       "not expired",
+			expiration: testNow.Add(time.Minute),
+			want:       true,
+		},
+		{
+			name:       "expired",
+			expiration: testNow.Add(-time.Minute),
+			want:       false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &WebAuthNChallengeModel{
+				Expiration: tt.expiration,
+			}
+			assert.Equal(t, tt.want, p.IsValid(testNow))
+		})
+	}
+}
+
+func TestWebAuthNChallengeModel_ValidateRPID(t *testing.T) {
+	tests := []struct {
+		name    string
+		rpid    string
+		origin  string
+		wantErr error
+	}{
+		{
+			name:   "no rpid",
+			rpid:   "",
+			origin: "https://login.example.com",
+		},
+		{
+			name:   "exact match",
+			rpid:   "login.example.com",
+			origin: "https://login.example.com",
+		},
+		{
+			name:   "exact match with port",
+			rpid:   "localhost",
+			origin: "http://localhost:8080",
+		},
+		{
+			name:   "matching subdomain",
+			rpid:   "example.com",
+			origin: "https://login.example.com",
+		},
+		{
+			name:    "mismatched domain",
+			rpid:    "example.com",
+			origin:  "https://login.example.org",
+			wantErr: caos_errs.ThrowPreconditionFailed(nil, "COMMAND-ahT4o", "Errors.Session.WebAuthN.RPIDMismatch"),
+		},
+		{
+			name:    "suffix without subdomain separator",
+			rpid:    "example.com",
+			origin:  "https://attackerexample.com",
+			wantErr: caos_errs.ThrowPreconditionFailed(nil, "COMMAND-ahT4o", "Errors.Session.WebAuthN.RPIDMismatch"),
+		},
+		{
+			name:    "rpid subdomain of origin",
+			rpid:    "login.example.com",
+			origin:  "https://example.com",
+			wantErr: caos_errs.ThrowPreconditionFailed(nil, "COMMAND-ahT4o", "Errors.Session.WebAuthN.RPIDMismatch"),
+		},
+		{
+			name:    "invalid origin",
+			rpid:    "example.com",
+			origin:  "",
+			wantErr: caos_errs.ThrowPreconditionFailed(nil, "COMMAND-eiF6u", "Errors.Session.WebAuthN.RPIDMismatch"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &WebAuthNChallengeModel{
+				RPID: tt.rpid,
+			}
+			err := p.ValidateRPID(tt.origin)
+			require.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestWebAuthNChallengeModel_WebAuthNLogin(t *testing.T) {
+	human := &domain.Human{ObjectRoot: models.ObjectRoot{AggregateID: "user1", ResourceOwner: "org1"}}
+	tests := []struct {
+		name      string
+		challenge domain.UserVerificationRequirement
+		policy    domain.UserVerificationRequirement
+		wantErr   error
+	}{
+		{
+			name:      "no policy requirement",
+			challenge: domain.UserVerificationRequirementPreferred,
+			policy:    domain.UserVerificationRequirementUnspecified,
+		},
+		{
+			name:      "required by policy and challenge",
+			challenge: domain.UserVerificationRequirementRequired,
+			policy:    domain.UserVerificationRequirementRequired,
+		},
+		{
+			name:      "required by policy, preferred by challenge",
+			challenge: domain.UserVerificationRequirementPreferred,
+			policy:    domain.UserVerificationRequirementRequired,
+			wantErr:   caos_errs.ThrowPreconditionFailed(nil, "COMMAND-Ahr0e", "Errors.Session.WebAuthN.UserVerificationRequired"),
+		},
+		{
+			name:      "required by policy, discouraged by challenge",
+			challenge: domain.UserVerificationRequirementDiscouraged,
+			policy:    domain.UserVerificationRequirementRequired,
+			wantErr:   caos_errs.ThrowPreconditionFailed(nil, "COMMAND-Ahr0e", "Errors.Session.WebAuthN.UserVerificationRequired"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &WebAuthNChallengeModel{
+				Challenge:        "challenge",
+				UserVerification: tt.challenge,
+				RPID:             "example.com",
+			}
+			got, err := p.WebAuthNLogin(human, []byte("data"), tt.policy)
+			require.ErrorIs(t, err, tt.wantErr)
+			if tt.wantErr != nil {
+				assert.Nil(t, got)
+				return
+			}
+			assert.Equal(t, &domain.WebAuthNLogin{
+				ObjectRoot:              human.ObjectRoot,
+				CredentialAssertionData: []byte("data"),
+				Challenge:               "challenge",
+				UserVerification:        tt.challenge,
+				RPID:                    "example.com",
+			}, got)
+		})
+	}
+}
+
+func TestWebAuthNChallengeModel_WebAuthNLogin_conditional(t *testing.T) {
+	human := &domain.Human{ObjectRoot: models.ObjectRoot{AggregateID: "user1", ResourceOwner: "org1"}}
+	ctx := context.Background()
+	sessionAggregate := &session.NewAggregate("sessionID", "org1").Aggregate
+	wm := NewSessionWriteModel("sessionID", "org1")
+	err := AppendAndReduce(wm,
+		session.NewAddedEvent(ctx, sessionAggregate, 0, "", "", "", nil),
+		session.NewUserCheckedEvent(ctx, sessionAggregate, "user1", testNow),
+		session.NewWebAuthNChallengedEvent(ctx, sessionAggregate, "challenge", [][]byte{}, nil, domain.UserVerificationRequirementRequired, "example.com", time.Time{}),
+	)
+	require.NoError(t, err)
+	challenge, ok := wm.ActiveWebAuthNChallenge()
+	require.True(t, ok)
+	assert.True(t, challenge.IsConditional())
+	assert.Nil(t, challenge.AllowedCredentialIDs)
+
+	got, err := challenge.WebAuthNLogin(human, []byte("data"), domain.UserVerificationRequirementRequired)
+	require.NoError(t, err)
+	assert.Equal(t, &domain.WebAuthNLogin{
+		ObjectRoot:              human.ObjectRoot,
+		CredentialAssertionData: []byte("data"),
+		Challenge:               "challenge",
+		UserVerification:        domain.UserVerificationRequirementRequired,
+		RPID:                    "example.com",
+	}, got)
+
+	restricted := &WebAuthNChallengeModel{AllowedCredentialIDs: [][]byte{[]byte("credentialID")}}
+	assert.False(t, restricted.IsConditional())
+}
+
+func TestWebAuthNChallengeModel_MarshalBinary(t *testing.T) {
+	challenge := &WebAuthNChallengeModel{
+		Challenge:            "challenge",
+		AllowedCredentialIDs: [][]byte{[]byte("credentialID1"), []byte("credentialID2")},
+		AllowedTransports:    map[string][]string{"Y3JlZGVudGlhbElEMQ": {"usb", "nfc"}},
+		UserVerification:     domain.UserVerificationRequirementRequired,
+		RPID:                 "example.com",
+		Expiration:           testNow.Add(5 * time.Minute).UTC().Truncate(time.Second),
+	}
+	data, err := challenge.MarshalBinary()
+	require.NoError(t, err)
+
+	got := new(WebAuthNChallengeModel)
+	require.NoError(t, got.UnmarshalBinary(data))
+	assert.Equal(t, challenge, got)
+	assert.Equal(t, challenge.AllowedCrentialIDs(), got.AllowedCrentialIDs())
+
+	err = got.UnmarshalBinary([]byte("invalid"))
+	assert.True(t, caos_errs.IsInternal(err))
+}
+
+func Test_loginPolicyUserVerification(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy *domain.LoginPolicy
+		wm     *SessionWriteModel
+		want   domain.UserVerificationRequirement
+	}{
+		{
+			name:   "no policy",
+			policy: nil,
+			wm:     &SessionWriteModel{},
+			want:   domain.UserVerificationRequirementUnspecified,
+		},
+		{
+			name:   "mfa not forced",
+			policy: &domain.LoginPolicy{PasswordlessType: domain.PasswordlessTypeAllowed},
+			wm:     &SessionWriteModel{},
+			want:   domain.UserVerificationRequirementUnspecified,
+		},
+		{
+			name:   "passwordless not allowed",
+			policy: &domain.LoginPolicy{ForceMFA: true, PasswordlessType: domain.PasswordlessTypeNotAllowed},
+			wm:     &SessionWriteModel{},
+			want:   domain.UserVerificationRequirementUnspecified,
+		},
+		{
+			name:   "password already checked",
+			policy: &domain.LoginPolicy{ForceMFA: true, PasswordlessType: domain.PasswordlessTypeAllowed},
+			wm:     &SessionWriteModel{PasswordCheckedAt: testNow},
+			want:   domain.UserVerificationRequirementUnspecified,
+		},
+		{
+			name:   "intent already checked",
+			policy: &domain.LoginPolicy{ForceMFA: true, PasswordlessType: domain.PasswordlessTypeAllowed},
+			wm:     &SessionWriteModel{IntentCheckedAt: testNow},
+			want:   domain.UserVerificationRequirementUnspecified,
+		},
+		{
+			name:   "only factor, required",
+			policy: &domain.LoginPolicy{ForceMFA: true, PasswordlessType: domain.PasswordlessTypeAllowed},
+			wm:     &SessionWriteModel{},
+			want:   domain.UserVerificationRequirementRequired,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, loginPolicyUserVerification(tt.policy, tt.wm))
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package command
 
 import (
+	"strconv"
 	"time"
 
 	"github.com/zitadel/zitadel/internal/crypto"
@@ -14,6 +15,10 @@ type HumanTOTPWriteModel struct {
 
 	State  domain.MFAState
 	Secret *crypto.CryptoValue
+	// DeviceID identifies the registration of the TOTP authenticator.
+	// Since a user can only have a single TOTP, the sequence of the added event is used,
+	// so a removed and newly added TOTP will result in a different DeviceID.
+	DeviceID string
 }
 
 func NewHumanTOTPWriteModel(userID, resourceOwner string) *HumanTOTPWriteModel {
@@ -31,6 +36,7 @@ func (wm *HumanTOTPWriteModel) Reduce() error {
 		case *user.HumanOTPAddedEvent:
 			wm.Secret = e.Secret
 			wm.State = domain.MFAStateNotReady
+			wm.DeviceID = strconv.FormatUint(e.Sequence(), 10)
 		case *user.HumanOTPVerifiedEvent:
 			wm.State = domain.MFAStateReady
 		case *user.HumanOTPRemovedEvent:
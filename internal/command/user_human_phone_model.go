@@ -16,6 +16,9 @@ type HumanPhoneWriteModel struct {
 
 	Phone           domain.PhoneNumber
 	IsPhoneVerified bool
+	// PhoneChangedSequence is the sequence of the latest event, which set or removed the phone number.
+	// It identifies the phone number without the need to store it, e.g. for the OTP SMS check of a session.
+	PhoneChangedSequence uint64
 
 	Code             *crypto.CryptoValue
 	CodeCreationDate time.Time
@@ -41,12 +44,14 @@ func (wm *HumanPhoneWriteModel) Reduce() error {
 			if e.PhoneNumber != "" {
 				wm.Phone = e.PhoneNumber
 				wm.State = domain.PhoneStateActive
+				wm.PhoneChangedSequence = e.Sequence()
 			}
 			wm.UserState = domain.UserStateActive
 		case *user.HumanRegisteredEvent:
 			if e.PhoneNumber != "" {
 				wm.Phone = e.PhoneNumber
 				wm.State = domain.PhoneStateActive
+				wm.PhoneChangedSequence = e.Sequence()
 			}
 			wm.UserState = domain.UserStateActive
 		case *user.HumanInitialCodeAddedEvent:
@@ -58,6 +63,7 @@ func (wm *HumanPhoneWriteModel) Reduce() error {
 			wm.IsPhoneVerified = false
 			wm.State = domain.PhoneStateActive
 			wm.Code = nil
+			wm.PhoneChangedSequence = e.Sequence()
 		case *user.HumanPhoneVerifiedEvent:
 			wm.IsPhoneVerified = true
 			wm.Code = nil
@@ -69,6 +75,7 @@ func (wm *HumanPhoneWriteModel) Reduce() error {
 			wm.State = domain.PhoneStateRemoved
 			wm.IsPhoneVerified = false
 			wm.Phone = ""
+			wm.PhoneChangedSequence = e.Sequence()
 		case *user.UserRemovedEvent:
 			wm.UserState = domain.UserStateDeleted
 			wm.IsPhoneVerified = false
@@ -13,6 +13,7 @@ type SystemDefaults struct {
 	DomainVerification DomainVerification
 	Notifications      Notifications
 	KeyConfig          KeyConfig
+	Session            SessionConfig
 }
 
 type SecretGenerators struct {
@@ -37,6 +38,16 @@ type Notifications struct {
 	FileSystemPath string
 }
 
+type SessionConfig struct {
+	WebAuthNChallengeLifetime time.Duration
+	MaxSessionsPerUser        int
+	MaxPasswordCheckFailures  int
+	// MaxUserPasswordCheckFailures limits the failed password checks of a user across all sessions
+	// within the UserPasswordCheckFailuresWindow
+	MaxUserPasswordCheckFailures    int
+	UserPasswordCheckFailuresWindow time.Duration
+}
+
 type KeyConfig struct {
 	Size                int
 	PrivateKeyLifetime  time.Duration
@@ -1,6 +1,8 @@
 package domain
 
 import (
+	"time"
+
 	"github.com/pquerna/otp"
 	"github.com/pquerna/otp/totp"
 
@@ -39,3 +41,30 @@ func VerifyTOTP(code string, secret *crypto.CryptoValue, cryptoAlg crypto.Encryp
 	}
 	return nil
 }
+
+// totpPeriod is the validity of a TOTP code in seconds, as used by [totp.Validate]
+const totpPeriod = 30
+
+// VerifyTOTPStep verifies the code like [VerifyTOTP] at the provided time
+// and returns the time-step (unix time divided by the period) the code was generated for,
+// so the reuse of a code can be detected.
+// As [totp.Validate], codes of the previous and the next time-step are accepted.
+func VerifyTOTPStep(code string, secret *crypto.CryptoValue, cryptoAlg crypto.EncryptionAlgorithm, now time.Time) (uint64, error) {
+	decrypt, err := crypto.DecryptString(secret, cryptoAlg)
+	if err != nil {
+		return 0, err
+	}
+	current := uint64(now.Unix()) / totpPeriod
+	for _, step := range []uint64{current, current - 1, current + 1} {
+		valid, err := totp.ValidateCustom(code, decrypt, time.Unix(int64(step*totpPeriod), 0).UTC(), totp.ValidateOpts{
+			Period:    totpPeriod,
+			Skew:      0,
+			Digits:    otp.DigitsSix,
+			Algorithm: otp.AlgorithmSHA1,
+		})
+		if err == nil && valid {
+			return step, nil
+		}
+	}
+	return 0, caos_errs.ThrowInvalidArgument(nil, "DOMAIN-Eij3a", "Errors.User.MFA.OTP.InvalidCode")
+}
@@ -32,8 +32,10 @@ type WebAuthNLogin struct {
 	CredentialAssertionData []byte
 	Challenge               string
 	AllowedCredentialIDs    [][]byte
-	UserVerification        UserVerificationRequirement
-	RPID                    string
+	// AllowedTransports are the transports of the allowed credentials by their (base64 URL encoded) credential id
+	AllowedTransports map[string][]string
+	UserVerification  UserVerificationRequirement
+	RPID              string
 }
 
 type UserVerificationRequirement int32
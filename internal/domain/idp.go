@@ -112,3 +112,39 @@ func (s IDPIntentState) Valid() bool {
 func (s IDPIntentState) Exists() bool {
 	return s != IDPIntentStateUnspecified && s != IDPIntentStateFailed //TODO: ?
 }
+
+// IDPIntentProtocol is the federation protocol used for the login of an IDP intent,
+// it's derived from the [IDPType] of the identity provider (see [IDPType.IntentProtocol])
+type IDPIntentProtocol int32
+
+const (
+	IDPIntentProtocolUnspecified IDPIntentProtocol = iota
+	IDPIntentProtocolOIDC
+	IDPIntentProtocolOAuth
+	IDPIntentProtocolJWT
+	IDPIntentProtocolLDAP
+)
+
+// IntentProtocol returns the federation protocol the provider of the type uses for the login
+func (t IDPType) IntentProtocol() IDPIntentProtocol {
+	switch t {
+	case IDPTypeOIDC,
+		IDPTypeGitLab,
+		IDPTypeGitLabSelfHosted,
+		IDPTypeGoogle:
+		return IDPIntentProtocolOIDC
+	case IDPTypeOAuth,
+		IDPTypeAzureAD,
+		IDPTypeGitHub,
+		IDPTypeGitHubEnterprise:
+		return IDPIntentProtocolOAuth
+	case IDPTypeJWT:
+		return IDPIntentProtocolJWT
+	case IDPTypeLDAP:
+		return IDPIntentProtocolLDAP
+	case IDPTypeUnspecified:
+		fallthrough
+	default:
+		return IDPIntentProtocolUnspecified
+	}
+}
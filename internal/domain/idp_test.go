@@ -0,0 +1,56 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIDPType_IntentProtocol(t *testing.T) {
+	tests := []struct {
+		name    string
+		idpType IDPType
+		want    IDPIntentProtocol
+	}{
+		{
+			name:    "unspecified",
+			idpType: IDPTypeUnspecified,
+			want:    IDPIntentProtocolUnspecified,
+		},
+		{
+			name:    "oidc",
+			idpType: IDPTypeOIDC,
+			want:    IDPIntentProtocolOIDC,
+		},
+		{
+			name:    "google (oidc)",
+			idpType: IDPTypeGoogle,
+			want:    IDPIntentProtocolOIDC,
+		},
+		{
+			name:    "oauth",
+			idpType: IDPTypeOAuth,
+			want:    IDPIntentProtocolOAuth,
+		},
+		{
+			name:    "github (oauth)",
+			idpType: IDPTypeGitHub,
+			want:    IDPIntentProtocolOAuth,
+		},
+		{
+			name:    "jwt",
+			idpType: IDPTypeJWT,
+			want:    IDPIntentProtocolJWT,
+		},
+		{
+			name:    "ldap",
+			idpType: IDPTypeLDAP,
+			want:    IDPIntentProtocolLDAP,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.idpType.IntentProtocol())
+		})
+	}
+}
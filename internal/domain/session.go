@@ -1,9 +1,83 @@
 package domain
 
+import "time"
+
 type SessionState int32
 
 const (
 	SessionStateUnspecified SessionState = iota
 	SessionStateActive
 	SessionStateTerminated
+	// SessionStatePending is the state of a created session until the user is checked
+	SessionStatePending
+	// SessionStateLocked is the state of a temporarily suspended session, which can neither be checked nor used until it's unlocked
+	SessionStateLocked
+)
+
+// IsOpen returns true if the session was created and not terminated yet, so it's either pending, active or locked
+func (s SessionState) IsOpen() bool {
+	return s == SessionStatePending || s == SessionStateActive || s == SessionStateLocked
+}
+
+type SessionTerminationType int32
+
+const (
+	SessionTerminationTypeUnspecified SessionTerminationType = iota
+	SessionTerminationTypeLogout
+	SessionTerminationTypeRevoked
+	SessionTerminationTypeExpired
+	SessionTerminationTypeReplaced
+	SessionTerminationTypeUserRemoved
+	// SessionTerminationTypeMerged is the reason of a session, which was merged into another one
+	SessionTerminationTypeMerged
+	// SessionTerminationTypeLimitExceeded is the reason of a session, which was terminated because the user
+	// exceeded the maximum amount of concurrent sessions
+	SessionTerminationTypeLimitExceeded
 )
+
+// AuthLevel is the authentication assurance level (AAL) as defined in NIST SP 800-63B
+type AuthLevel int32
+
+const (
+	AuthLevelUnspecified AuthLevel = iota
+	// AuthLevel1 is reached by any single factor
+	AuthLevel1
+	// AuthLevel2 is reached by a combination of a knowledge and a possession factor
+	AuthLevel2
+	// AuthLevel3 is reached by a hardware-backed, user verified passwordless authenticator
+	AuthLevel3
+)
+
+const (
+	// ACRInCommonIAPBronze requires any single factor
+	ACRInCommonIAPBronze = "urn:mace:incommon:iap:bronze"
+	// ACRInCommonIAPSilver requires a multi factor authentication
+	ACRInCommonIAPSilver = "urn:mace:incommon:iap:silver"
+	// ACRPAPEMultiFactor requires a multi factor authentication
+	ACRPAPEMultiFactor = "http://schemas.openid.net/pape/policies/2007/06/multi-factor"
+	// ACRPAPEMultiFactorPhysical requires a multi factor authentication using a physical (hardware) authenticator
+	ACRPAPEMultiFactorPhysical = "http://schemas.openid.net/pape/policies/2007/06/multi-factor-physical"
+)
+
+// ACRAuthLevel returns the minimal [AuthLevel] required by the provided (known) acr value
+func ACRAuthLevel(acr string) (AuthLevel, bool) {
+	switch acr {
+	case ACRInCommonIAPBronze:
+		return AuthLevel1, true
+	case ACRInCommonIAPSilver,
+		ACRPAPEMultiFactor:
+		return AuthLevel2, true
+	case ACRPAPEMultiFactorPhysical:
+		return AuthLevel3, true
+	default:
+		return AuthLevelUnspecified, false
+	}
+}
+
+// SessionTokenContext bundles the information of a session required to issue tokens (e.g. in OIDC)
+type SessionTokenContext struct {
+	UserID          string
+	AuthTime        time.Time
+	AuthMethodTypes []UserAuthMethodType
+	AuthLevel       AuthLevel
+}
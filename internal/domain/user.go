@@ -55,6 +55,9 @@ const (
 	UserAuthMethodTypeIDP
 	UserAuthMethodTypeOTPSMS
 	UserAuthMethodTypeOTPEmail
+	UserAuthMethodTypeRecoveryCode
+	// UserAuthMethodTypeClientCert is the authentication by a client certificate (mTLS)
+	UserAuthMethodTypeClientCert
 	userAuthMethodTypeCount
 )
 
@@ -76,6 +79,8 @@ func HasMFA(methods []UserAuthMethodType) bool {
 			UserAuthMethodTypeTOTP,
 			UserAuthMethodTypeOTPSMS,
 			UserAuthMethodTypeOTPEmail,
+			UserAuthMethodTypeRecoveryCode,
+			UserAuthMethodTypeClientCert,
 			UserAuthMethodTypeIDP:
 			factors++
 		case UserAuthMethodTypeUnspecified,
@@ -86,6 +91,41 @@ func HasMFA(methods []UserAuthMethodType) bool {
 	return factors > 1
 }
 
+// AuthFactorCategory is the category of an authentication factor (something you know, have or are)
+type AuthFactorCategory int32
+
+const (
+	AuthFactorCategoryUnspecified AuthFactorCategory = iota
+	AuthFactorCategoryKnowledge
+	AuthFactorCategoryPossession
+	AuthFactorCategoryInherence
+)
+
+// FactorCategories returns the [AuthFactorCategory]s proven by the auth method.
+// Passwordless proves the possession of the authenticator and the inherence by its user verification.
+// Logins using an external identity provider are not categorised, since the used factors are not known.
+func (f UserAuthMethodType) FactorCategories() []AuthFactorCategory {
+	switch f {
+	case UserAuthMethodTypePassword:
+		return []AuthFactorCategory{AuthFactorCategoryKnowledge}
+	case UserAuthMethodTypePasswordless:
+		return []AuthFactorCategory{AuthFactorCategoryPossession, AuthFactorCategoryInherence}
+	case UserAuthMethodTypeU2F,
+		UserAuthMethodTypeTOTP,
+		UserAuthMethodTypeOTPSMS,
+		UserAuthMethodTypeOTPEmail,
+		UserAuthMethodTypeRecoveryCode,
+		UserAuthMethodTypeClientCert:
+		return []AuthFactorCategory{AuthFactorCategoryPossession}
+	case UserAuthMethodTypeUnspecified,
+		UserAuthMethodTypeIDP,
+		userAuthMethodTypeCount:
+		fallthrough
+	default:
+		return nil
+	}
+}
+
 // RequiresMFA checks whether the user requires to authenticate with multiple auth factors based on the LoginPolicy and the authentication type.
 // Internal authentication will require MFA if either option is activated.
 // External authentication will only require MFA if it's forced generally and not local only.
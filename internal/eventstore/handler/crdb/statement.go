@@ -272,6 +272,28 @@ func NewArrayIntersectCol(column string, value interface{}) handler.Column {
 	}
 }
 
+// NewJSONBMergeCol merges the value (a JSON object) into the JSONB column, existing keys will be overwritten
+func NewJSONBMergeCol(column string, value interface{}) handler.Column {
+	return handler.Column{
+		Name:  column,
+		Value: value,
+		ParameterOpt: func(placeholder string) string {
+			return "COALESCE(" + column + ", '{}'::JSONB) || " + placeholder + "::JSONB"
+		},
+	}
+}
+
+// NewJSONBRemoveKeysCol removes the keys from the JSONB column (object)
+func NewJSONBRemoveKeysCol(column string, keys []string) handler.Column {
+	return handler.Column{
+		Name:  column,
+		Value: database.StringArray(keys),
+		ParameterOpt: func(placeholder string) string {
+			return column + " - " + placeholder + "::TEXT[]"
+		},
+	}
+}
+
 func NewCopyCol(column, from string) handler.Column {
 	return handler.Column{
 		Name:  column,
@@ -285,6 +307,12 @@ func NewLessThanCond(column string, value interface{}) handler.Condition {
 	}
 }
 
+func NewLessOrEqualCond(column string, value interface{}) handler.Condition {
+	return func(param string) (string, interface{}) {
+		return column + " <= " + param, value
+	}
+}
+
 func NewIsNullCond(column string) handler.Condition {
 	return func(param string) (string, interface{}) {
 		return column + " IS NULL", nil
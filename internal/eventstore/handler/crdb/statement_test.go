@@ -1431,6 +1431,18 @@ func Test_conditionsToWhere(t *testing.T) {
 				values: []interface{}{"val1"},
 			},
 		},
+		{
+			name: "less or equal",
+			args: args{
+				conds: []handler.Condition{
+					NewLessOrEqualCond("col1", "val1"),
+				},
+			},
+			want: want{
+				wheres: []string{"(col1 <= $1)"},
+				values: []interface{}{"val1"},
+			},
+		},
 		{
 			name: "is null",
 			args: args{
@@ -2,6 +2,8 @@ package eventstore
 
 import (
 	"database/sql"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/zitadel/zitadel/internal/database"
@@ -215,6 +217,59 @@ func (query *SearchQuery) Builder() *SearchQueryBuilder {
 	return query.builder
 }
 
+// String renders the builder into a human readable description of the filter.
+// It is intended for debugging and is not guaranteed to be stable.
+func (builder *SearchQueryBuilder) String() string {
+	var parts []string
+	if builder.resourceOwner != "" {
+		parts = append(parts, "resource owner: "+builder.resourceOwner)
+	}
+	if builder.instanceID != "" {
+		parts = append(parts, "instance id: "+builder.instanceID)
+	}
+	if builder.limit > 0 {
+		parts = append(parts, fmt.Sprintf("limit: %d", builder.limit))
+	}
+	if builder.desc {
+		parts = append(parts, "order: desc")
+	}
+	queries := make([]string, len(builder.queries))
+	for i, query := range builder.queries {
+		queries[i] = "(" + query.String() + ")"
+	}
+	if len(queries) > 0 {
+		parts = append(parts, "queries: "+strings.Join(queries, " OR "))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// String renders the sub query into a human readable description of the filter
+func (query *SearchQuery) String() string {
+	var parts []string
+	if len(query.aggregateTypes) > 0 {
+		parts = append(parts, fmt.Sprintf("aggregate types: %v", query.aggregateTypes))
+	}
+	if len(query.aggregateIDs) > 0 {
+		parts = append(parts, fmt.Sprintf("aggregate ids: %v", query.aggregateIDs))
+	}
+	if query.instanceID != "" {
+		parts = append(parts, "instance id: "+query.instanceID)
+	}
+	if len(query.eventTypes) > 0 {
+		parts = append(parts, fmt.Sprintf("event types: %v", query.eventTypes))
+	}
+	if query.eventSequenceGreater > 0 {
+		parts = append(parts, fmt.Sprintf("sequence greater: %d", query.eventSequenceGreater))
+	}
+	if query.eventSequenceLess > 0 {
+		parts = append(parts, fmt.Sprintf("sequence less: %d", query.eventSequenceLess))
+	}
+	if !query.creationDateAfter.IsZero() {
+		parts = append(parts, "creation date after: "+query.creationDateAfter.Format(time.RFC3339Nano))
+	}
+	return strings.Join(parts, ", ")
+}
+
 func (query *SearchQuery) matches(event Event) bool {
 	if query.eventSequenceLess > 0 && event.Sequence() >= query.eventSequenceLess {
 		return false
@@ -2,7 +2,10 @@ package projection
 
 import (
 	"context"
+	"sort"
+	"time"
 
+	"github.com/zitadel/zitadel/internal/database"
 	"github.com/zitadel/zitadel/internal/domain"
 	"github.com/zitadel/zitadel/internal/errors"
 	"github.com/zitadel/zitadel/internal/eventstore"
@@ -14,25 +17,31 @@ import (
 )
 
 const (
-	SessionsProjectionTable = "projections.sessions4"
-
-	SessionColumnID                   = "id"
-	SessionColumnCreationDate         = "creation_date"
-	SessionColumnChangeDate           = "change_date"
-	SessionColumnSequence             = "sequence"
-	SessionColumnState                = "state"
-	SessionColumnResourceOwner        = "resource_owner"
-	SessionColumnInstanceID           = "instance_id"
-	SessionColumnCreator              = "creator"
-	SessionColumnUserID               = "user_id"
-	SessionColumnUserCheckedAt        = "user_checked_at"
-	SessionColumnPasswordCheckedAt    = "password_checked_at"
-	SessionColumnIntentCheckedAt      = "intent_checked_at"
-	SessionColumnWebAuthNCheckedAt    = "webauthn_checked_at"
-	SessionColumnWebAuthNUserVerified = "webauthn_user_verified"
-	SessionColumnTOTPCheckedAt        = "totp_checked_at"
-	SessionColumnMetadata             = "metadata"
-	SessionColumnTokenID              = "token_id"
+	SessionsProjectionTable = "projections.sessions6"
+
+	SessionColumnID                    = "id"
+	SessionColumnCreationDate          = "creation_date"
+	SessionColumnChangeDate            = "change_date"
+	SessionColumnSequence              = "sequence"
+	SessionColumnState                 = "state"
+	SessionColumnResourceOwner         = "resource_owner"
+	SessionColumnInstanceID            = "instance_id"
+	SessionColumnCreator               = "creator"
+	SessionColumnUserID                = "user_id"
+	SessionColumnUserCheckedAt         = "user_checked_at"
+	SessionColumnPasswordCheckedAt     = "password_checked_at"
+	SessionColumnIntentCheckedAt       = "intent_checked_at"
+	SessionColumnWebAuthNCheckedAt     = "webauthn_checked_at"
+	SessionColumnWebAuthNUserVerified  = "webauthn_user_verified"
+	SessionColumnTOTPCheckedAt         = "totp_checked_at"
+	SessionColumnOTPSMSCheckedAt       = "otp_sms_checked_at"
+	SessionColumnOTPEmailCheckedAt     = "otp_email_checked_at"
+	SessionColumnRecoveryCodeCheckedAt = "recovery_code_checked_at"
+	SessionColumnClientCertCheckedAt   = "client_cert_checked_at"
+	SessionColumnMetadata              = "metadata"
+	SessionColumnMetadataExpirations   = "metadata_expirations"
+	SessionColumnTokenID               = "token_id"
+	SessionColumnClientID              = "client_id"
 )
 
 type sessionProjection struct {
@@ -60,8 +69,14 @@ func newSessionProjection(ctx context.Context, config crdb.StatementHandlerConfi
 			crdb.NewColumn(SessionColumnWebAuthNCheckedAt, crdb.ColumnTypeTimestamp, crdb.Nullable()),
 			crdb.NewColumn(SessionColumnWebAuthNUserVerified, crdb.ColumnTypeBool, crdb.Nullable()),
 			crdb.NewColumn(SessionColumnTOTPCheckedAt, crdb.ColumnTypeTimestamp, crdb.Nullable()),
+			crdb.NewColumn(SessionColumnOTPSMSCheckedAt, crdb.ColumnTypeTimestamp, crdb.Nullable()),
+			crdb.NewColumn(SessionColumnOTPEmailCheckedAt, crdb.ColumnTypeTimestamp, crdb.Nullable()),
+			crdb.NewColumn(SessionColumnRecoveryCodeCheckedAt, crdb.ColumnTypeTimestamp, crdb.Nullable()),
+			crdb.NewColumn(SessionColumnClientCertCheckedAt, crdb.ColumnTypeTimestamp, crdb.Nullable()),
 			crdb.NewColumn(SessionColumnMetadata, crdb.ColumnTypeJSONB, crdb.Nullable()),
+			crdb.NewColumn(SessionColumnMetadataExpirations, crdb.ColumnTypeJSONB, crdb.Nullable()),
 			crdb.NewColumn(SessionColumnTokenID, crdb.ColumnTypeText, crdb.Nullable()),
+			crdb.NewColumn(SessionColumnClientID, crdb.ColumnTypeText, crdb.Nullable()),
 		},
 			crdb.NewPrimaryKey(SessionColumnInstanceID, SessionColumnID),
 		),
@@ -99,6 +114,26 @@ func (p *sessionProjection) reducers() []handler.AggregateReducer {
 					Event:  session.TOTPCheckedType,
 					Reduce: p.reduceTOTPChecked,
 				},
+				{
+					Event:  session.OTPSMSCheckedType,
+					Reduce: p.reduceOTPSMSChecked,
+				},
+				{
+					Event:  session.OTPEmailCheckedType,
+					Reduce: p.reduceOTPEmailChecked,
+				},
+				{
+					Event:  session.RecoveryCodeCheckedType,
+					Reduce: p.reduceRecoveryCodeChecked,
+				},
+				{
+					Event:  session.PasswordChangedType,
+					Reduce: p.reduceSessionPasswordChanged,
+				},
+				{
+					Event:  session.ClientCertCheckedType,
+					Reduce: p.reduceClientCertChecked,
+				},
 				{
 					Event:  session.TokenSetType,
 					Reduce: p.reduceTokenSet,
@@ -107,6 +142,26 @@ func (p *sessionProjection) reducers() []handler.AggregateReducer {
 					Event:  session.MetadataSetType,
 					Reduce: p.reduceMetadataSet,
 				},
+				{
+					Event:  session.MetadataBulkSetType,
+					Reduce: p.reduceMetadataBulkSet,
+				},
+				{
+					Event:  session.MetadataRemovedType,
+					Reduce: p.reduceMetadataRemoved,
+				},
+				{
+					Event:  session.FactorInvalidatedType,
+					Reduce: p.reduceFactorInvalidated,
+				},
+				{
+					Event:  session.LockedType,
+					Reduce: p.reduceSessionLocked,
+				},
+				{
+					Event:  session.UnlockedType,
+					Reduce: p.reduceSessionUnlocked,
+				},
 				{
 					Event:  session.TerminateType,
 					Reduce: p.reduceSessionTerminated,
@@ -148,9 +203,10 @@ func (p *sessionProjection) reduceSessionAdded(event eventstore.Event) (*handler
 			handler.NewCol(SessionColumnCreationDate, e.CreationDate()),
 			handler.NewCol(SessionColumnChangeDate, e.CreationDate()),
 			handler.NewCol(SessionColumnResourceOwner, e.Aggregate().ResourceOwner),
-			handler.NewCol(SessionColumnState, domain.SessionStateActive),
+			handler.NewCol(SessionColumnState, domain.SessionStatePending),
 			handler.NewCol(SessionColumnSequence, e.Sequence()),
 			handler.NewCol(SessionColumnCreator, e.User),
+			handler.NewCol(SessionColumnClientID, e.ClientID),
 		},
 	), nil
 }
@@ -167,6 +223,8 @@ func (p *sessionProjection) reduceUserChecked(event eventstore.Event) (*handler.
 			handler.NewCol(SessionColumnSequence, e.Sequence()),
 			handler.NewCol(SessionColumnUserID, e.UserID),
 			handler.NewCol(SessionColumnUserCheckedAt, e.CheckedAt),
+			// terminated sessions are removed, so the session is either pending or already active
+			handler.NewCol(SessionColumnState, domain.SessionStateActive),
 		},
 		[]handler.Condition{
 			handler.NewCond(SessionColumnID, e.Aggregate().ID),
@@ -255,6 +313,86 @@ func (p *sessionProjection) reduceTOTPChecked(event eventstore.Event) (*handler.
 	), nil
 }
 
+func (p *sessionProjection) reduceOTPSMSChecked(event eventstore.Event) (*handler.Statement, error) {
+	e, ok := event.(*session.OTPSMSCheckedEvent)
+	if !ok {
+		return nil, errors.ThrowInvalidArgumentf(nil, "HANDL-Aeth1", "reduce.wrong.event.type %s", session.OTPSMSCheckedType)
+	}
+
+	return crdb.NewUpdateStatement(
+		e,
+		[]handler.Column{
+			handler.NewCol(SessionColumnChangeDate, e.CreationDate()),
+			handler.NewCol(SessionColumnSequence, e.Sequence()),
+			handler.NewCol(SessionColumnOTPSMSCheckedAt, e.CheckedAt),
+		},
+		[]handler.Condition{
+			handler.NewCond(SessionColumnID, e.Aggregate().ID),
+			handler.NewCond(SessionColumnInstanceID, e.Aggregate().InstanceID),
+		},
+	), nil
+}
+
+func (p *sessionProjection) reduceOTPEmailChecked(event eventstore.Event) (*handler.Statement, error) {
+	e, ok := event.(*session.OTPEmailCheckedEvent)
+	if !ok {
+		return nil, errors.ThrowInvalidArgumentf(nil, "HANDL-Iex4o", "reduce.wrong.event.type %s", session.OTPEmailCheckedType)
+	}
+
+	return crdb.NewUpdateStatement(
+		e,
+		[]handler.Column{
+			handler.NewCol(SessionColumnChangeDate, e.CreationDate()),
+			handler.NewCol(SessionColumnSequence, e.Sequence()),
+			handler.NewCol(SessionColumnOTPEmailCheckedAt, e.CheckedAt),
+		},
+		[]handler.Condition{
+			handler.NewCond(SessionColumnID, e.Aggregate().ID),
+			handler.NewCond(SessionColumnInstanceID, e.Aggregate().InstanceID),
+		},
+	), nil
+}
+
+func (p *sessionProjection) reduceRecoveryCodeChecked(event eventstore.Event) (*handler.Statement, error) {
+	e, ok := event.(*session.RecoveryCodeCheckedEvent)
+	if !ok {
+		return nil, errors.ThrowInvalidArgumentf(nil, "HANDL-Ahx5i", "reduce.wrong.event.type %s", session.RecoveryCodeCheckedType)
+	}
+
+	return crdb.NewUpdateStatement(
+		e,
+		[]handler.Column{
+			handler.NewCol(SessionColumnChangeDate, e.CreationDate()),
+			handler.NewCol(SessionColumnSequence, e.Sequence()),
+			handler.NewCol(SessionColumnRecoveryCodeCheckedAt, e.CheckedAt),
+		},
+		[]handler.Condition{
+			handler.NewCond(SessionColumnID, e.Aggregate().ID),
+			handler.NewCond(SessionColumnInstanceID, e.Aggregate().InstanceID),
+		},
+	), nil
+}
+
+func (p *sessionProjection) reduceClientCertChecked(event eventstore.Event) (*handler.Statement, error) {
+	e, ok := event.(*session.ClientCertCheckedEvent)
+	if !ok {
+		return nil, errors.ThrowInvalidArgumentf(nil, "HANDL-Ohqu5", "reduce.wrong.event.type %s", session.ClientCertCheckedType)
+	}
+
+	return crdb.NewUpdateStatement(
+		e,
+		[]handler.Column{
+			handler.NewCol(SessionColumnChangeDate, e.CreationDate()),
+			handler.NewCol(SessionColumnSequence, e.Sequence()),
+			handler.NewCol(SessionColumnClientCertCheckedAt, e.CheckedAt),
+		},
+		[]handler.Condition{
+			handler.NewCond(SessionColumnID, e.Aggregate().ID),
+			handler.NewCond(SessionColumnInstanceID, e.Aggregate().InstanceID),
+		},
+	), nil
+}
+
 func (p *sessionProjection) reduceTokenSet(event eventstore.Event) (*handler.Statement, error) {
 	e, ok := event.(*session.TokenSetEvent)
 	if !ok {
@@ -280,6 +418,12 @@ func (p *sessionProjection) reduceMetadataSet(event eventstore.Event) (*handler.
 	if !ok {
 		return nil, errors.ThrowInvalidArgumentf(nil, "HANDL-SAfd3", "reduce.wrong.event.type %s", session.MetadataSetType)
 	}
+	expirations := make(database.Map[time.Time], len(e.Expirations))
+	for key, expiration := range e.Expirations {
+		if _, ok := e.Metadata[key]; ok {
+			expirations[key] = expiration
+		}
+	}
 
 	return crdb.NewUpdateStatement(
 		e,
@@ -287,6 +431,7 @@ func (p *sessionProjection) reduceMetadataSet(event eventstore.Event) (*handler.
 			handler.NewCol(SessionColumnChangeDate, e.CreationDate()),
 			handler.NewCol(SessionColumnSequence, e.Sequence()),
 			handler.NewCol(SessionColumnMetadata, e.Metadata),
+			handler.NewCol(SessionColumnMetadataExpirations, expirations),
 		},
 		[]handler.Condition{
 			handler.NewCond(SessionColumnID, e.Aggregate().ID),
@@ -295,6 +440,152 @@ func (p *sessionProjection) reduceMetadataSet(event eventstore.Event) (*handler.
 	), nil
 }
 
+func (p *sessionProjection) reduceMetadataBulkSet(event eventstore.Event) (*handler.Statement, error) {
+	e, ok := event.(*session.MetadataBulkSetEvent)
+	if !ok {
+		return nil, errors.ThrowInvalidArgumentf(nil, "HANDL-ohP5e", "reduce.wrong.event.type %s", session.MetadataBulkSetType)
+	}
+	keys := make([]string, 0, len(e.Metadata))
+	for key := range e.Metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return crdb.NewUpdateStatement(
+		e,
+		[]handler.Column{
+			handler.NewCol(SessionColumnChangeDate, e.CreationDate()),
+			handler.NewCol(SessionColumnSequence, e.Sequence()),
+			crdb.NewJSONBMergeCol(SessionColumnMetadata, e.Metadata),
+			// the merged entries are set without a TTL
+			crdb.NewJSONBRemoveKeysCol(SessionColumnMetadataExpirations, keys),
+		},
+		[]handler.Condition{
+			handler.NewCond(SessionColumnID, e.Aggregate().ID),
+			handler.NewCond(SessionColumnInstanceID, e.Aggregate().InstanceID),
+		},
+	), nil
+}
+
+func (p *sessionProjection) reduceMetadataRemoved(event eventstore.Event) (*handler.Statement, error) {
+	e, ok := event.(*session.MetadataRemovedEvent)
+	if !ok {
+		return nil, errors.ThrowInvalidArgumentf(nil, "HANDL-Thoh4", "reduce.wrong.event.type %s", session.MetadataRemovedType)
+	}
+
+	return crdb.NewUpdateStatement(
+		e,
+		[]handler.Column{
+			handler.NewCol(SessionColumnChangeDate, e.CreationDate()),
+			handler.NewCol(SessionColumnSequence, e.Sequence()),
+			crdb.NewJSONBRemoveKeysCol(SessionColumnMetadata, e.Keys),
+			crdb.NewJSONBRemoveKeysCol(SessionColumnMetadataExpirations, e.Keys),
+		},
+		[]handler.Condition{
+			handler.NewCond(SessionColumnID, e.Aggregate().ID),
+			handler.NewCond(SessionColumnInstanceID, e.Aggregate().InstanceID),
+		},
+	), nil
+}
+
+func (p *sessionProjection) reduceFactorInvalidated(event eventstore.Event) (*handler.Statement, error) {
+	e, ok := event.(*session.FactorInvalidatedEvent)
+	if !ok {
+		return nil, errors.ThrowInvalidArgumentf(nil, "HANDL-eiN9a", "reduce.wrong.event.type %s", session.FactorInvalidatedType)
+	}
+	var columns []handler.Column
+	conditions := []handler.Condition{
+		handler.NewCond(SessionColumnID, e.Aggregate().ID),
+		handler.NewCond(SessionColumnInstanceID, e.Aggregate().InstanceID),
+	}
+	switch e.Factor {
+	case domain.UserAuthMethodTypePassword:
+		columns = []handler.Column{handler.NewCol(SessionColumnPasswordCheckedAt, nil)}
+	case domain.UserAuthMethodTypeIDP:
+		columns = []handler.Column{handler.NewCol(SessionColumnIntentCheckedAt, nil)}
+	case domain.UserAuthMethodTypeTOTP:
+		columns = []handler.Column{handler.NewCol(SessionColumnTOTPCheckedAt, nil)}
+	case domain.UserAuthMethodTypeOTPSMS:
+		columns = []handler.Column{handler.NewCol(SessionColumnOTPSMSCheckedAt, nil)}
+	case domain.UserAuthMethodTypeOTPEmail:
+		columns = []handler.Column{handler.NewCol(SessionColumnOTPEmailCheckedAt, nil)}
+	case domain.UserAuthMethodTypeRecoveryCode:
+		columns = []handler.Column{handler.NewCol(SessionColumnRecoveryCodeCheckedAt, nil)}
+	case domain.UserAuthMethodTypeClientCert:
+		columns = []handler.Column{handler.NewCol(SessionColumnClientCertCheckedAt, nil)}
+	case domain.UserAuthMethodTypeU2F, domain.UserAuthMethodTypePasswordless:
+		columns = []handler.Column{
+			handler.NewCol(SessionColumnWebAuthNCheckedAt, nil),
+			handler.NewCol(SessionColumnWebAuthNUserVerified, nil),
+		}
+		// like the SessionWriteModel, only invalidate the WebAuthN check if it's the one of the event:
+		// a user verified check is passwordless, any other is U2F
+		conditions = append(conditions, handler.NewCond(SessionColumnWebAuthNUserVerified, e.Factor == domain.UserAuthMethodTypePasswordless))
+	default:
+		return crdb.NewNoOpStatement(e), nil
+	}
+	return crdb.NewUpdateStatement(
+		e,
+		append([]handler.Column{
+			handler.NewCol(SessionColumnChangeDate, e.CreationDate()),
+			handler.NewCol(SessionColumnSequence, e.Sequence()),
+		}, columns...),
+		conditions,
+	), nil
+}
+
+func (p *sessionProjection) reduceSessionLocked(event eventstore.Event) (*handler.Statement, error) {
+	e, ok := event.(*session.LockedEvent)
+	if !ok {
+		return nil, errors.ThrowInvalidArgumentf(nil, "HANDL-Ohk3e", "reduce.wrong.event.type %s", session.LockedType)
+	}
+	return crdb.NewUpdateStatement(
+		e,
+		[]handler.Column{
+			handler.NewCol(SessionColumnChangeDate, e.CreationDate()),
+			handler.NewCol(SessionColumnSequence, e.Sequence()),
+			handler.NewCol(SessionColumnState, domain.SessionStateLocked),
+		},
+		[]handler.Condition{
+			handler.NewCond(SessionColumnID, e.Aggregate().ID),
+			handler.NewCond(SessionColumnInstanceID, e.Aggregate().InstanceID),
+		},
+	), nil
+}
+
+// reduceSessionUnlocked restores the state of the session:
+// active if the user was already checked, pending otherwise
+func (p *sessionProjection) reduceSessionUnlocked(event eventstore.Event) (*handler.Statement, error) {
+	e, ok := event.(*session.UnlockedEvent)
+	if !ok {
+		return nil, errors.ThrowInvalidArgumentf(nil, "HANDL-ieG3o", "reduce.wrong.event.type %s", session.UnlockedType)
+	}
+	return crdb.NewMultiStatement(
+		e,
+		crdb.AddUpdateStatement(
+			[]handler.Column{
+				handler.NewCol(SessionColumnChangeDate, e.CreationDate()),
+				handler.NewCol(SessionColumnSequence, e.Sequence()),
+				handler.NewCol(SessionColumnState, domain.SessionStateActive),
+			},
+			[]handler.Condition{
+				handler.NewCond(SessionColumnID, e.Aggregate().ID),
+				handler.NewCond(SessionColumnInstanceID, e.Aggregate().InstanceID),
+			},
+		),
+		crdb.AddUpdateStatement(
+			[]handler.Column{
+				handler.NewCol(SessionColumnState, domain.SessionStatePending),
+			},
+			[]handler.Condition{
+				handler.NewCond(SessionColumnID, e.Aggregate().ID),
+				handler.NewCond(SessionColumnInstanceID, e.Aggregate().InstanceID),
+				crdb.NewIsNullCond(SessionColumnUserID),
+			},
+		),
+	), nil
+}
+
 func (p *sessionProjection) reduceSessionTerminated(event eventstore.Event) (*handler.Statement, error) {
 	e, ok := event.(*session.TerminateEvent)
 	if !ok {
@@ -310,6 +601,32 @@ func (p *sessionProjection) reduceSessionTerminated(event eventstore.Event) (*ha
 	), nil
 }
 
+func (p *sessionProjection) reduceSessionPasswordChanged(event eventstore.Event) (*handler.Statement, error) {
+	e, ok := event.(*session.PasswordChangedEvent)
+	if !ok {
+		return nil, errors.ThrowInvalidArgumentf(nil, "HANDL-Vai3o", "reduce.wrong.event.type %s", session.PasswordChangedType)
+	}
+	changedAt := e.ChangedAt
+	if changedAt.IsZero() {
+		changedAt = e.CreationDate()
+	}
+
+	return crdb.NewUpdateStatement(
+		e,
+		[]handler.Column{
+			handler.NewCol(SessionColumnChangeDate, e.CreationDate()),
+			handler.NewCol(SessionColumnSequence, e.Sequence()),
+			handler.NewCol(SessionColumnPasswordCheckedAt, nil),
+		},
+		[]handler.Condition{
+			handler.NewCond(SessionColumnID, e.Aggregate().ID),
+			handler.NewCond(SessionColumnInstanceID, e.Aggregate().InstanceID),
+			// like the SessionWriteModel, a check at the time of the change is invalidated as well
+			crdb.NewLessOrEqualCond(SessionColumnPasswordCheckedAt, changedAt),
+		},
+	), nil
+}
+
 func (p *sessionProjection) reducePasswordChanged(event eventstore.Event) (*handler.Statement, error) {
 	e, ok := event.(*user.HumanPasswordChangedEvent)
 	if !ok {
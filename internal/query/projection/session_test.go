@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/zitadel/zitadel/internal/database"
 	"github.com/zitadel/zitadel/internal/domain"
 	"github.com/zitadel/zitadel/internal/errors"
 	"github.com/zitadel/zitadel/internal/eventstore"
@@ -31,7 +32,8 @@ func TestSessionProjection_reduces(t *testing.T) {
 					session.AddedType,
 					session.AggregateType,
 					[]byte(`{
-						"domain": "domain"
+						"domain": "domain",
+						"clientID": "client-id"
 					}`),
 				), session.AddedEventMapper),
 			},
@@ -43,16 +45,17 @@ func TestSessionProjection_reduces(t *testing.T) {
 				executer: &testExecuter{
 					executions: []execution{
 						{
-							expectedStmt: "INSERT INTO projections.sessions4 (id, instance_id, creation_date, change_date, resource_owner, state, sequence, creator) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
+							expectedStmt: "INSERT INTO projections.sessions6 (id, instance_id, creation_date, change_date, resource_owner, state, sequence, creator, client_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)",
 							expectedArgs: []interface{}{
 								"agg-id",
 								"instance-id",
 								anyArg{},
 								anyArg{},
 								"ro-id",
-								domain.SessionStateActive,
+								domain.SessionStatePending,
 								uint64(15),
 								"editor-user",
+								"client-id",
 							},
 						},
 					},
@@ -79,12 +82,13 @@ func TestSessionProjection_reduces(t *testing.T) {
 				executer: &testExecuter{
 					executions: []execution{
 						{
-							expectedStmt: "UPDATE projections.sessions4 SET (change_date, sequence, user_id, user_checked_at) = ($1, $2, $3, $4) WHERE (id = $5) AND (instance_id = $6)",
+							expectedStmt: "UPDATE projections.sessions6 SET (change_date, sequence, user_id, user_checked_at, state) = ($1, $2, $3, $4, $5) WHERE (id = $6) AND (instance_id = $7)",
 							expectedArgs: []interface{}{
 								anyArg{},
 								anyArg{},
 								"user-id",
 								time.Date(2023, time.May, 4, 0, 0, 0, 0, time.UTC),
+								domain.SessionStateActive,
 								"agg-id",
 								"instance-id",
 							},
@@ -112,7 +116,7 @@ func TestSessionProjection_reduces(t *testing.T) {
 				executer: &testExecuter{
 					executions: []execution{
 						{
-							expectedStmt: "UPDATE projections.sessions4 SET (change_date, sequence, password_checked_at) = ($1, $2, $3) WHERE (id = $4) AND (instance_id = $5)",
+							expectedStmt: "UPDATE projections.sessions6 SET (change_date, sequence, password_checked_at) = ($1, $2, $3) WHERE (id = $4) AND (instance_id = $5)",
 							expectedArgs: []interface{}{
 								anyArg{},
 								anyArg{},
@@ -145,7 +149,7 @@ func TestSessionProjection_reduces(t *testing.T) {
 				executer: &testExecuter{
 					executions: []execution{
 						{
-							expectedStmt: "UPDATE projections.sessions4 SET (change_date, sequence, webauthn_checked_at, webauthn_user_verified) = ($1, $2, $3, $4) WHERE (id = $5) AND (instance_id = $6)",
+							expectedStmt: "UPDATE projections.sessions6 SET (change_date, sequence, webauthn_checked_at, webauthn_user_verified) = ($1, $2, $3, $4) WHERE (id = $5) AND (instance_id = $6)",
 							expectedArgs: []interface{}{
 								anyArg{},
 								anyArg{},
@@ -178,7 +182,7 @@ func TestSessionProjection_reduces(t *testing.T) {
 				executer: &testExecuter{
 					executions: []execution{
 						{
-							expectedStmt: "UPDATE projections.sessions4 SET (change_date, sequence, intent_checked_at) = ($1, $2, $3) WHERE (id = $4) AND (instance_id = $5)",
+							expectedStmt: "UPDATE projections.sessions6 SET (change_date, sequence, intent_checked_at) = ($1, $2, $3) WHERE (id = $4) AND (instance_id = $5)",
 							expectedArgs: []interface{}{
 								anyArg{},
 								anyArg{},
@@ -210,7 +214,168 @@ func TestSessionProjection_reduces(t *testing.T) {
 				executer: &testExecuter{
 					executions: []execution{
 						{
-							expectedStmt: "UPDATE projections.sessions4 SET (change_date, sequence, totp_checked_at) = ($1, $2, $3) WHERE (id = $4) AND (instance_id = $5)",
+							expectedStmt: "UPDATE projections.sessions6 SET (change_date, sequence, totp_checked_at) = ($1, $2, $3) WHERE (id = $4) AND (instance_id = $5)",
+							expectedArgs: []interface{}{
+								anyArg{},
+								anyArg{},
+								time.Date(2023, time.May, 4, 0, 0, 0, 0, time.UTC),
+								"agg-id",
+								"instance-id",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "instance reduceOTPSMSChecked",
+			args: args{
+				event: getEvent(testEvent(
+					session.OTPSMSCheckedType,
+					session.AggregateType,
+					[]byte(`{
+						"checkedAt": "2023-05-04T00:00:00Z"
+					}`),
+				), eventstore.GenericEventMapper[session.OTPSMSCheckedEvent]),
+			},
+			reduce: (&sessionProjection{}).reduceOTPSMSChecked,
+			want: wantReduce{
+				aggregateType:    eventstore.AggregateType("session"),
+				sequence:         15,
+				previousSequence: 10,
+				executer: &testExecuter{
+					executions: []execution{
+						{
+							expectedStmt: "UPDATE projections.sessions6 SET (change_date, sequence, otp_sms_checked_at) = ($1, $2, $3) WHERE (id = $4) AND (instance_id = $5)",
+							expectedArgs: []interface{}{
+								anyArg{},
+								anyArg{},
+								time.Date(2023, time.May, 4, 0, 0, 0, 0, time.UTC),
+								"agg-id",
+								"instance-id",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "instance reduceOTPEmailChecked",
+			args: args{
+				event: getEvent(testEvent(
+					session.OTPEmailCheckedType,
+					session.AggregateType,
+					[]byte(`{
+						"checkedAt": "2023-05-04T00:00:00Z"
+					}`),
+				), eventstore.GenericEventMapper[session.OTPEmailCheckedEvent]),
+			},
+			reduce: (&sessionProjection{}).reduceOTPEmailChecked,
+			want: wantReduce{
+				aggregateType:    eventstore.AggregateType("session"),
+				sequence:         15,
+				previousSequence: 10,
+				executer: &testExecuter{
+					executions: []execution{
+						{
+							expectedStmt: "UPDATE projections.sessions6 SET (change_date, sequence, otp_email_checked_at) = ($1, $2, $3) WHERE (id = $4) AND (instance_id = $5)",
+							expectedArgs: []interface{}{
+								anyArg{},
+								anyArg{},
+								time.Date(2023, time.May, 4, 0, 0, 0, 0, time.UTC),
+								"agg-id",
+								"instance-id",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "instance reduceRecoveryCodeChecked",
+			args: args{
+				event: getEvent(testEvent(
+					session.RecoveryCodeCheckedType,
+					session.AggregateType,
+					[]byte(`{
+						"checkedAt": "2023-05-04T00:00:00Z"
+					}`),
+				), eventstore.GenericEventMapper[session.RecoveryCodeCheckedEvent]),
+			},
+			reduce: (&sessionProjection{}).reduceRecoveryCodeChecked,
+			want: wantReduce{
+				aggregateType:    eventstore.AggregateType("session"),
+				sequence:         15,
+				previousSequence: 10,
+				executer: &testExecuter{
+					executions: []execution{
+						{
+							expectedStmt: "UPDATE projections.sessions6 SET (change_date, sequence, recovery_code_checked_at) = ($1, $2, $3) WHERE (id = $4) AND (instance_id = $5)",
+							expectedArgs: []interface{}{
+								anyArg{},
+								anyArg{},
+								time.Date(2023, time.May, 4, 0, 0, 0, 0, time.UTC),
+								"agg-id",
+								"instance-id",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "instance reduceSessionPasswordChanged",
+			args: args{
+				event: getEvent(testEvent(
+					session.PasswordChangedType,
+					session.AggregateType,
+					[]byte(`{
+						"changedAt": "2023-05-04T00:00:00Z"
+					}`),
+				), eventstore.GenericEventMapper[session.PasswordChangedEvent]),
+			},
+			reduce: (&sessionProjection{}).reduceSessionPasswordChanged,
+			want: wantReduce{
+				aggregateType:    eventstore.AggregateType("session"),
+				sequence:         15,
+				previousSequence: 10,
+				executer: &testExecuter{
+					executions: []execution{
+						{
+							expectedStmt: "UPDATE projections.sessions6 SET (change_date, sequence, password_checked_at) = ($1, $2, $3) WHERE (id = $4) AND (instance_id = $5) AND (password_checked_at <= $6)",
+							expectedArgs: []interface{}{
+								anyArg{},
+								anyArg{},
+								nil,
+								"agg-id",
+								"instance-id",
+								time.Date(2023, time.May, 4, 0, 0, 0, 0, time.UTC),
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "instance reduceClientCertChecked",
+			args: args{
+				event: getEvent(testEvent(
+					session.ClientCertCheckedType,
+					session.AggregateType,
+					[]byte(`{
+						"checkedAt": "2023-05-04T00:00:00Z"
+					}`),
+				), eventstore.GenericEventMapper[session.ClientCertCheckedEvent]),
+			},
+			reduce: (&sessionProjection{}).reduceClientCertChecked,
+			want: wantReduce{
+				aggregateType:    eventstore.AggregateType("session"),
+				sequence:         15,
+				previousSequence: 10,
+				executer: &testExecuter{
+					executions: []execution{
+						{
+							expectedStmt: "UPDATE projections.sessions6 SET (change_date, sequence, client_cert_checked_at) = ($1, $2, $3) WHERE (id = $4) AND (instance_id = $5)",
 							expectedArgs: []interface{}{
 								anyArg{},
 								anyArg{},
@@ -242,7 +407,7 @@ func TestSessionProjection_reduces(t *testing.T) {
 				executer: &testExecuter{
 					executions: []execution{
 						{
-							expectedStmt: "UPDATE projections.sessions4 SET (change_date, sequence, token_id) = ($1, $2, $3) WHERE (id = $4) AND (instance_id = $5)",
+							expectedStmt: "UPDATE projections.sessions6 SET (change_date, sequence, token_id) = ($1, $2, $3) WHERE (id = $4) AND (instance_id = $5)",
 							expectedArgs: []interface{}{
 								anyArg{},
 								anyArg{},
@@ -263,7 +428,12 @@ func TestSessionProjection_reduces(t *testing.T) {
 					session.AggregateType,
 					[]byte(`{
 						"metadata": {
-							"key": "dmFsdWU="
+							"key": "dmFsdWU=",
+							"ttl": "dmFsdWU="
+						},
+						"expirations": {
+							"ttl": "2023-05-04T00:00:00Z",
+							"removed": "2023-05-04T00:00:00Z"
 						}
 					}`),
 				), session.MetadataSetEventMapper),
@@ -276,13 +446,255 @@ func TestSessionProjection_reduces(t *testing.T) {
 				executer: &testExecuter{
 					executions: []execution{
 						{
-							expectedStmt: "UPDATE projections.sessions4 SET (change_date, sequence, metadata) = ($1, $2, $3) WHERE (id = $4) AND (instance_id = $5)",
+							expectedStmt: "UPDATE projections.sessions6 SET (change_date, sequence, metadata, metadata_expirations) = ($1, $2, $3, $4) WHERE (id = $5) AND (instance_id = $6)",
+							expectedArgs: []interface{}{
+								anyArg{},
+								anyArg{},
+								map[string][]byte{
+									"key": []byte("value"),
+									"ttl": []byte("value"),
+								},
+								database.Map[time.Time]{
+									"ttl": time.Date(2023, time.May, 4, 0, 0, 0, 0, time.UTC),
+								},
+								"agg-id",
+								"instance-id",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "instance reduceMetadataBulkSet",
+			args: args{
+				event: getEvent(testEvent(
+					session.MetadataBulkSetType,
+					session.AggregateType,
+					[]byte(`{
+						"metadata": {
+							"key": "dmFsdWU="
+						}
+					}`),
+				), eventstore.GenericEventMapper[session.MetadataBulkSetEvent]),
+			},
+			reduce: (&sessionProjection{}).reduceMetadataBulkSet,
+			want: wantReduce{
+				aggregateType:    eventstore.AggregateType("session"),
+				sequence:         15,
+				previousSequence: 10,
+				executer: &testExecuter{
+					executions: []execution{
+						{
+							expectedStmt: "UPDATE projections.sessions6 SET (change_date, sequence, metadata, metadata_expirations) = ($1, $2, COALESCE(metadata, '{}'::JSONB) || $3::JSONB, metadata_expirations - $4::TEXT[]) WHERE (id = $5) AND (instance_id = $6)",
 							expectedArgs: []interface{}{
 								anyArg{},
 								anyArg{},
 								map[string][]byte{
 									"key": []byte("value"),
 								},
+								database.StringArray{"key"},
+								"agg-id",
+								"instance-id",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "instance reduceMetadataRemoved",
+			args: args{
+				event: getEvent(testEvent(
+					session.MetadataRemovedType,
+					session.AggregateType,
+					[]byte(`{
+						"keys": ["key1", "key2"]
+					}`),
+				), eventstore.GenericEventMapper[session.MetadataRemovedEvent]),
+			},
+			reduce: (&sessionProjection{}).reduceMetadataRemoved,
+			want: wantReduce{
+				aggregateType:    eventstore.AggregateType("session"),
+				sequence:         15,
+				previousSequence: 10,
+				executer: &testExecuter{
+					executions: []execution{
+						{
+							expectedStmt: "UPDATE projections.sessions6 SET (change_date, sequence, metadata, metadata_expirations) = ($1, $2, metadata - $3::TEXT[], metadata_expirations - $4::TEXT[]) WHERE (id = $5) AND (instance_id = $6)",
+							expectedArgs: []interface{}{
+								anyArg{},
+								anyArg{},
+								database.StringArray{"key1", "key2"},
+								database.StringArray{"key1", "key2"},
+								"agg-id",
+								"instance-id",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "instance reduceFactorInvalidated",
+			args: args{
+				event: getEvent(testEvent(
+					session.FactorInvalidatedType,
+					session.AggregateType,
+					[]byte(`{
+						"factor": 1
+					}`),
+				), eventstore.GenericEventMapper[session.FactorInvalidatedEvent]),
+			},
+			reduce: (&sessionProjection{}).reduceFactorInvalidated,
+			want: wantReduce{
+				aggregateType:    eventstore.AggregateType("session"),
+				sequence:         15,
+				previousSequence: 10,
+				executer: &testExecuter{
+					executions: []execution{
+						{
+							expectedStmt: "UPDATE projections.sessions6 SET (change_date, sequence, totp_checked_at) = ($1, $2, $3) WHERE (id = $4) AND (instance_id = $5)",
+							expectedArgs: []interface{}{
+								anyArg{},
+								anyArg{},
+								nil,
+								"agg-id",
+								"instance-id",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "instance reduceFactorInvalidated U2F",
+			args: args{
+				event: getEvent(testEvent(
+					session.FactorInvalidatedType,
+					session.AggregateType,
+					[]byte(`{
+						"factor": 2
+					}`),
+				), eventstore.GenericEventMapper[session.FactorInvalidatedEvent]),
+			},
+			reduce: (&sessionProjection{}).reduceFactorInvalidated,
+			want: wantReduce{
+				aggregateType:    eventstore.AggregateType("session"),
+				sequence:         15,
+				previousSequence: 10,
+				executer: &testExecuter{
+					executions: []execution{
+						{
+							expectedStmt: "UPDATE projections.sessions6 SET (change_date, sequence, webauthn_checked_at, webauthn_user_verified) = ($1, $2, $3, $4) WHERE (id = $5) AND (instance_id = $6) AND (webauthn_user_verified = $7)",
+							expectedArgs: []interface{}{
+								anyArg{},
+								anyArg{},
+								nil,
+								nil,
+								"agg-id",
+								"instance-id",
+								false,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "instance reduceFactorInvalidated Passwordless",
+			args: args{
+				event: getEvent(testEvent(
+					session.FactorInvalidatedType,
+					session.AggregateType,
+					[]byte(`{
+						"factor": 3
+					}`),
+				), eventstore.GenericEventMapper[session.FactorInvalidatedEvent]),
+			},
+			reduce: (&sessionProjection{}).reduceFactorInvalidated,
+			want: wantReduce{
+				aggregateType:    eventstore.AggregateType("session"),
+				sequence:         15,
+				previousSequence: 10,
+				executer: &testExecuter{
+					executions: []execution{
+						{
+							expectedStmt: "UPDATE projections.sessions6 SET (change_date, sequence, webauthn_checked_at, webauthn_user_verified) = ($1, $2, $3, $4) WHERE (id = $5) AND (instance_id = $6) AND (webauthn_user_verified = $7)",
+							expectedArgs: []interface{}{
+								anyArg{},
+								anyArg{},
+								nil,
+								nil,
+								"agg-id",
+								"instance-id",
+								true,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "instance reduceSessionLocked",
+			args: args{
+				event: getEvent(testEvent(
+					session.LockedType,
+					session.AggregateType,
+					nil,
+				), eventstore.GenericEventMapper[session.LockedEvent]),
+			},
+			reduce: (&sessionProjection{}).reduceSessionLocked,
+			want: wantReduce{
+				aggregateType:    eventstore.AggregateType("session"),
+				sequence:         15,
+				previousSequence: 10,
+				executer: &testExecuter{
+					executions: []execution{
+						{
+							expectedStmt: "UPDATE projections.sessions6 SET (change_date, sequence, state) = ($1, $2, $3) WHERE (id = $4) AND (instance_id = $5)",
+							expectedArgs: []interface{}{
+								anyArg{},
+								anyArg{},
+								domain.SessionStateLocked,
+								"agg-id",
+								"instance-id",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "instance reduceSessionUnlocked",
+			args: args{
+				event: getEvent(testEvent(
+					session.UnlockedType,
+					session.AggregateType,
+					nil,
+				), eventstore.GenericEventMapper[session.UnlockedEvent]),
+			},
+			reduce: (&sessionProjection{}).reduceSessionUnlocked,
+			want: wantReduce{
+				aggregateType:    eventstore.AggregateType("session"),
+				sequence:         15,
+				previousSequence: 10,
+				executer: &testExecuter{
+					executions: []execution{
+						{
+							expectedStmt: "UPDATE projections.sessions6 SET (change_date, sequence, state) = ($1, $2, $3) WHERE (id = $4) AND (instance_id = $5)",
+							expectedArgs: []interface{}{
+								anyArg{},
+								anyArg{},
+								domain.SessionStateActive,
+								"agg-id",
+								"instance-id",
+							},
+						},
+						{
+							expectedStmt: "UPDATE projections.sessions6 SET state = $1 WHERE (id = $2) AND (instance_id = $3) AND (user_id IS NULL)",
+							expectedArgs: []interface{}{
+								domain.SessionStatePending,
 								"agg-id",
 								"instance-id",
 							},
@@ -308,7 +720,7 @@ func TestSessionProjection_reduces(t *testing.T) {
 				executer: &testExecuter{
 					executions: []execution{
 						{
-							expectedStmt: "DELETE FROM projections.sessions4 WHERE (id = $1) AND (instance_id = $2)",
+							expectedStmt: "DELETE FROM projections.sessions6 WHERE (id = $1) AND (instance_id = $2)",
 							expectedArgs: []interface{}{
 								"agg-id",
 								"instance-id",
@@ -335,7 +747,7 @@ func TestSessionProjection_reduces(t *testing.T) {
 				executer: &testExecuter{
 					executions: []execution{
 						{
-							expectedStmt: "DELETE FROM projections.sessions4 WHERE (instance_id = $1)",
+							expectedStmt: "DELETE FROM projections.sessions6 WHERE (instance_id = $1)",
 							expectedArgs: []interface{}{
 								"agg-id",
 							},
@@ -366,7 +778,7 @@ func TestSessionProjection_reduces(t *testing.T) {
 				executer: &testExecuter{
 					executions: []execution{
 						{
-							expectedStmt: "UPDATE projections.sessions4 SET password_checked_at = $1 WHERE (user_id = $2) AND (password_checked_at < $3)",
+							expectedStmt: "UPDATE projections.sessions6 SET password_checked_at = $1 WHERE (user_id = $2) AND (password_checked_at < $3)",
 							expectedArgs: []interface{}{
 								nil,
 								"agg-id",
@@ -23,19 +23,23 @@ type Sessions struct {
 }
 
 type Session struct {
-	ID             string
-	CreationDate   time.Time
-	ChangeDate     time.Time
-	Sequence       uint64
-	State          domain.SessionState
-	ResourceOwner  string
-	Creator        string
-	UserFactor     SessionUserFactor
-	PasswordFactor SessionPasswordFactor
-	IntentFactor   SessionIntentFactor
-	WebAuthNFactor SessionWebAuthNFactor
-	TOTPFactor     SessionTOTPFactor
-	Metadata       map[string][]byte
+	ID                 string
+	CreationDate       time.Time
+	ChangeDate         time.Time
+	Sequence           uint64
+	State              domain.SessionState
+	ResourceOwner      string
+	Creator            string
+	UserFactor         SessionUserFactor
+	PasswordFactor     SessionPasswordFactor
+	IntentFactor       SessionIntentFactor
+	WebAuthNFactor     SessionWebAuthNFactor
+	TOTPFactor         SessionTOTPFactor
+	OTPSMSFactor       SessionOTPFactor
+	OTPEmailFactor     SessionOTPFactor
+	RecoveryCodeFactor SessionRecoveryCodeFactor
+	ClientCertFactor   SessionClientCertFactor
+	Metadata           map[string][]byte
 }
 
 type SessionUserFactor struct {
@@ -63,6 +67,18 @@ type SessionTOTPFactor struct {
 	TOTPCheckedAt time.Time
 }
 
+type SessionOTPFactor struct {
+	OTPCheckedAt time.Time
+}
+
+type SessionRecoveryCodeFactor struct {
+	RecoveryCodeCheckedAt time.Time
+}
+
+type SessionClientCertFactor struct {
+	ClientCertCheckedAt time.Time
+}
+
 type SessionsSearchQueries struct {
 	SearchRequest
 	Queries []SearchQuery
@@ -113,6 +129,10 @@ var (
 		name:  projection.SessionColumnCreator,
 		table: sessionsTable,
 	}
+	SessionColumnClientID = Column{
+		name:  projection.SessionColumnClientID,
+		table: sessionsTable,
+	}
 	SessionColumnUserID = Column{
 		name:  projection.SessionColumnUserID,
 		table: sessionsTable,
@@ -141,10 +161,30 @@ var (
 		name:  projection.SessionColumnTOTPCheckedAt,
 		table: sessionsTable,
 	}
+	SessionColumnOTPSMSCheckedAt = Column{
+		name:  projection.SessionColumnOTPSMSCheckedAt,
+		table: sessionsTable,
+	}
+	SessionColumnOTPEmailCheckedAt = Column{
+		name:  projection.SessionColumnOTPEmailCheckedAt,
+		table: sessionsTable,
+	}
+	SessionColumnRecoveryCodeCheckedAt = Column{
+		name:  projection.SessionColumnRecoveryCodeCheckedAt,
+		table: sessionsTable,
+	}
+	SessionColumnClientCertCheckedAt = Column{
+		name:  projection.SessionColumnClientCertCheckedAt,
+		table: sessionsTable,
+	}
 	SessionColumnMetadata = Column{
 		name:  projection.SessionColumnMetadata,
 		table: sessionsTable,
 	}
+	SessionColumnMetadataExpirations = Column{
+		name:  projection.SessionColumnMetadataExpirations,
+		table: sessionsTable,
+	}
 	SessionColumnToken = Column{
 		name:  projection.SessionColumnTokenID,
 		table: sessionsTable,
@@ -221,6 +261,11 @@ func NewSessionCreatorSearchQuery(creator string) (SearchQuery, error) {
 	return NewTextQuery(SessionColumnCreator, creator, TextEquals)
 }
 
+// NewSessionClientIDSearchQuery filters for sessions created by the client (application) with the provided id
+func NewSessionClientIDSearchQuery(clientID string) (SearchQuery, error) {
+	return NewTextQuery(SessionColumnClientID, clientID, TextEquals)
+}
+
 func prepareSessionQuery(ctx context.Context, db prepareDatabase) (sq.SelectBuilder, func(*sql.Row) (*Session, string, error)) {
 	return sq.Select(
 			SessionColumnID.identifier(),
@@ -240,7 +285,12 @@ func prepareSessionQuery(ctx context.Context, db prepareDatabase) (sq.SelectBuil
 			SessionColumnWebAuthNCheckedAt.identifier(),
 			SessionColumnWebAuthNUserVerified.identifier(),
 			SessionColumnTOTPCheckedAt.identifier(),
+			SessionColumnOTPSMSCheckedAt.identifier(),
+			SessionColumnOTPEmailCheckedAt.identifier(),
+			SessionColumnRecoveryCodeCheckedAt.identifier(),
+			SessionColumnClientCertCheckedAt.identifier(),
 			SessionColumnMetadata.identifier(),
+			SessionColumnMetadataExpirations.identifier(),
 			SessionColumnToken.identifier(),
 		).From(sessionsTable.identifier()).
 			LeftJoin(join(LoginNameUserIDCol, SessionColumnUserID)).
@@ -250,18 +300,23 @@ func prepareSessionQuery(ctx context.Context, db prepareDatabase) (sq.SelectBuil
 			session := new(Session)
 
 			var (
-				userID              sql.NullString
-				userCheckedAt       sql.NullTime
-				loginName           sql.NullString
-				displayName         sql.NullString
-				userResourceOwner   sql.NullString
-				passwordCheckedAt   sql.NullTime
-				intentCheckedAt     sql.NullTime
-				webAuthNCheckedAt   sql.NullTime
-				webAuthNUserPresent sql.NullBool
-				totpCheckedAt       sql.NullTime
-				metadata            database.Map[[]byte]
-				token               sql.NullString
+				userID                sql.NullString
+				userCheckedAt         sql.NullTime
+				loginName             sql.NullString
+				displayName           sql.NullString
+				userResourceOwner     sql.NullString
+				passwordCheckedAt     sql.NullTime
+				intentCheckedAt       sql.NullTime
+				webAuthNCheckedAt     sql.NullTime
+				webAuthNUserPresent   sql.NullBool
+				totpCheckedAt         sql.NullTime
+				otpSMSCheckedAt       sql.NullTime
+				otpEmailCheckedAt     sql.NullTime
+				recoveryCodeCheckedAt sql.NullTime
+				clientCertCheckedAt   sql.NullTime
+				metadata              database.Map[[]byte]
+				metadataExpirations   database.Map[time.Time]
+				token                 sql.NullString
 			)
 
 			err := row.Scan(
@@ -282,7 +337,12 @@ func prepareSessionQuery(ctx context.Context, db prepareDatabase) (sq.SelectBuil
 				&webAuthNCheckedAt,
 				&webAuthNUserPresent,
 				&totpCheckedAt,
+				&otpSMSCheckedAt,
+				&otpEmailCheckedAt,
+				&recoveryCodeCheckedAt,
+				&clientCertCheckedAt,
 				&metadata,
+				&metadataExpirations,
 				&token,
 			)
 
@@ -303,7 +363,11 @@ func prepareSessionQuery(ctx context.Context, db prepareDatabase) (sq.SelectBuil
 			session.WebAuthNFactor.WebAuthNCheckedAt = webAuthNCheckedAt.Time
 			session.WebAuthNFactor.UserVerified = webAuthNUserPresent.Bool
 			session.TOTPFactor.TOTPCheckedAt = totpCheckedAt.Time
-			session.Metadata = metadata
+			session.OTPSMSFactor.OTPCheckedAt = otpSMSCheckedAt.Time
+			session.OTPEmailFactor.OTPCheckedAt = otpEmailCheckedAt.Time
+			session.RecoveryCodeFactor.RecoveryCodeCheckedAt = recoveryCodeCheckedAt.Time
+			session.ClientCertFactor.ClientCertCheckedAt = clientCertCheckedAt.Time
+			session.Metadata = unexpiredMetadata(metadata, metadataExpirations, time.Now())
 
 			return session, token.String, nil
 		}
@@ -328,7 +392,12 @@ func prepareSessionsQuery(ctx context.Context, db prepareDatabase) (sq.SelectBui
 			SessionColumnWebAuthNCheckedAt.identifier(),
 			SessionColumnWebAuthNUserVerified.identifier(),
 			SessionColumnTOTPCheckedAt.identifier(),
+			SessionColumnOTPSMSCheckedAt.identifier(),
+			SessionColumnOTPEmailCheckedAt.identifier(),
+			SessionColumnRecoveryCodeCheckedAt.identifier(),
+			SessionColumnClientCertCheckedAt.identifier(),
 			SessionColumnMetadata.identifier(),
+			SessionColumnMetadataExpirations.identifier(),
 			countColumn.identifier(),
 		).From(sessionsTable.identifier()).
 			LeftJoin(join(LoginNameUserIDCol, SessionColumnUserID)).
@@ -341,17 +410,22 @@ func prepareSessionsQuery(ctx context.Context, db prepareDatabase) (sq.SelectBui
 				session := new(Session)
 
 				var (
-					userID              sql.NullString
-					userCheckedAt       sql.NullTime
-					loginName           sql.NullString
-					displayName         sql.NullString
-					userResourceOwner   sql.NullString
-					passwordCheckedAt   sql.NullTime
-					intentCheckedAt     sql.NullTime
-					webAuthNCheckedAt   sql.NullTime
-					webAuthNUserPresent sql.NullBool
-					totpCheckedAt       sql.NullTime
-					metadata            database.Map[[]byte]
+					userID                sql.NullString
+					userCheckedAt         sql.NullTime
+					loginName             sql.NullString
+					displayName           sql.NullString
+					userResourceOwner     sql.NullString
+					passwordCheckedAt     sql.NullTime
+					intentCheckedAt       sql.NullTime
+					webAuthNCheckedAt     sql.NullTime
+					webAuthNUserPresent   sql.NullBool
+					totpCheckedAt         sql.NullTime
+					otpSMSCheckedAt       sql.NullTime
+					otpEmailCheckedAt     sql.NullTime
+					recoveryCodeCheckedAt sql.NullTime
+					clientCertCheckedAt   sql.NullTime
+					metadata              database.Map[[]byte]
+					metadataExpirations   database.Map[time.Time]
 				)
 
 				err := rows.Scan(
@@ -372,7 +446,12 @@ func prepareSessionsQuery(ctx context.Context, db prepareDatabase) (sq.SelectBui
 					&webAuthNCheckedAt,
 					&webAuthNUserPresent,
 					&totpCheckedAt,
+					&otpSMSCheckedAt,
+					&otpEmailCheckedAt,
+					&recoveryCodeCheckedAt,
+					&clientCertCheckedAt,
 					&metadata,
+					&metadataExpirations,
 					&sessions.Count,
 				)
 
@@ -389,7 +468,11 @@ func prepareSessionsQuery(ctx context.Context, db prepareDatabase) (sq.SelectBui
 				session.WebAuthNFactor.WebAuthNCheckedAt = webAuthNCheckedAt.Time
 				session.WebAuthNFactor.UserVerified = webAuthNUserPresent.Bool
 				session.TOTPFactor.TOTPCheckedAt = totpCheckedAt.Time
-				session.Metadata = metadata
+				session.OTPSMSFactor.OTPCheckedAt = otpSMSCheckedAt.Time
+				session.OTPEmailFactor.OTPCheckedAt = otpEmailCheckedAt.Time
+				session.RecoveryCodeFactor.RecoveryCodeCheckedAt = recoveryCodeCheckedAt.Time
+				session.ClientCertFactor.ClientCertCheckedAt = clientCertCheckedAt.Time
+				session.Metadata = unexpiredMetadata(metadata, metadataExpirations, time.Now())
 
 				sessions.Sessions = append(sessions.Sessions, session)
 			}
@@ -397,3 +480,17 @@ func prepareSessionsQuery(ctx context.Context, db prepareDatabase) (sq.SelectBui
 			return sessions, nil
 		}
 }
+
+// unexpiredMetadata returns the metadata without the entries, which were set with a TTL that has passed at the provided time.
+// The expired entries are only removed from the projection by a later change of the metadata.
+func unexpiredMetadata(metadata map[string][]byte, expirations map[string]time.Time, now time.Time) map[string][]byte {
+	if len(expirations) == 0 {
+		return metadata
+	}
+	for key, expiration := range expirations {
+		if now.After(expiration) {
+			delete(metadata, key)
+		}
+	}
+	return metadata
+}
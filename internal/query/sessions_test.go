@@ -17,53 +17,63 @@ import (
 )
 
 var (
-	expectedSessionQuery = regexp.QuoteMeta(`SELECT projections.sessions4.id,` +
-		` projections.sessions4.creation_date,` +
-		` projections.sessions4.change_date,` +
-		` projections.sessions4.sequence,` +
-		` projections.sessions4.state,` +
-		` projections.sessions4.resource_owner,` +
-		` projections.sessions4.creator,` +
-		` projections.sessions4.user_id,` +
-		` projections.sessions4.user_checked_at,` +
+	expectedSessionQuery = regexp.QuoteMeta(`SELECT projections.sessions6.id,` +
+		` projections.sessions6.creation_date,` +
+		` projections.sessions6.change_date,` +
+		` projections.sessions6.sequence,` +
+		` projections.sessions6.state,` +
+		` projections.sessions6.resource_owner,` +
+		` projections.sessions6.creator,` +
+		` projections.sessions6.user_id,` +
+		` projections.sessions6.user_checked_at,` +
 		` projections.login_names2.login_name,` +
 		` projections.users8_humans.display_name,` +
 		` projections.users8.resource_owner,` +
-		` projections.sessions4.password_checked_at,` +
-		` projections.sessions4.intent_checked_at,` +
-		` projections.sessions4.webauthn_checked_at,` +
-		` projections.sessions4.webauthn_user_verified,` +
-		` projections.sessions4.totp_checked_at,` +
-		` projections.sessions4.metadata,` +
-		` projections.sessions4.token_id` +
-		` FROM projections.sessions4` +
-		` LEFT JOIN projections.login_names2 ON projections.sessions4.user_id = projections.login_names2.user_id AND projections.sessions4.instance_id = projections.login_names2.instance_id` +
-		` LEFT JOIN projections.users8_humans ON projections.sessions4.user_id = projections.users8_humans.user_id AND projections.sessions4.instance_id = projections.users8_humans.instance_id` +
-		` LEFT JOIN projections.users8 ON projections.sessions4.user_id = projections.users8.id AND projections.sessions4.instance_id = projections.users8.instance_id` +
+		` projections.sessions6.password_checked_at,` +
+		` projections.sessions6.intent_checked_at,` +
+		` projections.sessions6.webauthn_checked_at,` +
+		` projections.sessions6.webauthn_user_verified,` +
+		` projections.sessions6.totp_checked_at,` +
+		` projections.sessions6.otp_sms_checked_at,` +
+		` projections.sessions6.otp_email_checked_at,` +
+		` projections.sessions6.recovery_code_checked_at,` +
+		` projections.sessions6.client_cert_checked_at,` +
+		` projections.sessions6.metadata,` +
+		` projections.sessions6.metadata_expirations,` +
+		` projections.sessions6.token_id` +
+		` FROM projections.sessions6` +
+		` LEFT JOIN projections.login_names2 ON projections.sessions6.user_id = projections.login_names2.user_id AND projections.sessions6.instance_id = projections.login_names2.instance_id` +
+		` LEFT JOIN projections.users8_humans ON projections.sessions6.user_id = projections.users8_humans.user_id AND projections.sessions6.instance_id = projections.users8_humans.instance_id` +
+		` LEFT JOIN projections.users8 ON projections.sessions6.user_id = projections.users8.id AND projections.sessions6.instance_id = projections.users8.instance_id` +
 		` AS OF SYSTEM TIME '-1 ms'`)
-	expectedSessionsQuery = regexp.QuoteMeta(`SELECT projections.sessions4.id,` +
-		` projections.sessions4.creation_date,` +
-		` projections.sessions4.change_date,` +
-		` projections.sessions4.sequence,` +
-		` projections.sessions4.state,` +
-		` projections.sessions4.resource_owner,` +
-		` projections.sessions4.creator,` +
-		` projections.sessions4.user_id,` +
-		` projections.sessions4.user_checked_at,` +
+	expectedSessionsQuery = regexp.QuoteMeta(`SELECT projections.sessions6.id,` +
+		` projections.sessions6.creation_date,` +
+		` projections.sessions6.change_date,` +
+		` projections.sessions6.sequence,` +
+		` projections.sessions6.state,` +
+		` projections.sessions6.resource_owner,` +
+		` projections.sessions6.creator,` +
+		` projections.sessions6.user_id,` +
+		` projections.sessions6.user_checked_at,` +
 		` projections.login_names2.login_name,` +
 		` projections.users8_humans.display_name,` +
 		` projections.users8.resource_owner,` +
-		` projections.sessions4.password_checked_at,` +
-		` projections.sessions4.intent_checked_at,` +
-		` projections.sessions4.webauthn_checked_at,` +
-		` projections.sessions4.webauthn_user_verified,` +
-		` projections.sessions4.totp_checked_at,` +
-		` projections.sessions4.metadata,` +
+		` projections.sessions6.password_checked_at,` +
+		` projections.sessions6.intent_checked_at,` +
+		` projections.sessions6.webauthn_checked_at,` +
+		` projections.sessions6.webauthn_user_verified,` +
+		` projections.sessions6.totp_checked_at,` +
+		` projections.sessions6.otp_sms_checked_at,` +
+		` projections.sessions6.otp_email_checked_at,` +
+		` projections.sessions6.recovery_code_checked_at,` +
+		` projections.sessions6.client_cert_checked_at,` +
+		` projections.sessions6.metadata,` +
+		` projections.sessions6.metadata_expirations,` +
 		` COUNT(*) OVER ()` +
-		` FROM projections.sessions4` +
-		` LEFT JOIN projections.login_names2 ON projections.sessions4.user_id = projections.login_names2.user_id AND projections.sessions4.instance_id = projections.login_names2.instance_id` +
-		` LEFT JOIN projections.users8_humans ON projections.sessions4.user_id = projections.users8_humans.user_id AND projections.sessions4.instance_id = projections.users8_humans.instance_id` +
-		` LEFT JOIN projections.users8 ON projections.sessions4.user_id = projections.users8.id AND projections.sessions4.instance_id = projections.users8.instance_id` +
+		` FROM projections.sessions6` +
+		` LEFT JOIN projections.login_names2 ON projections.sessions6.user_id = projections.login_names2.user_id AND projections.sessions6.instance_id = projections.login_names2.instance_id` +
+		` LEFT JOIN projections.users8_humans ON projections.sessions6.user_id = projections.users8_humans.user_id AND projections.sessions6.instance_id = projections.users8_humans.instance_id` +
+		` LEFT JOIN projections.users8 ON projections.sessions6.user_id = projections.users8.id AND projections.sessions6.instance_id = projections.users8.instance_id` +
 		` AS OF SYSTEM TIME '-1 ms'`)
 
 	sessionCols = []string{
@@ -84,7 +94,12 @@ var (
 		"webauthn_checked_at",
 		"webauthn_user_verified",
 		"totp_checked_at",
+		"otp_sms_checked_at",
+		"otp_email_checked_at",
+		"recovery_code_checked_at",
+		"client_cert_checked_at",
 		"metadata",
+		"metadata_expirations",
 		"token",
 	}
 
@@ -106,7 +121,12 @@ var (
 		"webauthn_checked_at",
 		"webauthn_user_verified",
 		"totp_checked_at",
+		"otp_sms_checked_at",
+		"otp_email_checked_at",
+		"recovery_code_checked_at",
+		"client_cert_checked_at",
 		"metadata",
+		"metadata_expirations",
 		"count",
 	}
 )
@@ -160,7 +180,12 @@ func Test_SessionsPrepare(t *testing.T) {
 							testNow,
 							true,
 							testNow,
+							testNow,
+							testNow,
+							testNow,
+							testNow,
 							[]byte(`{"key": "dmFsdWU="}`),
+							nil,
 						},
 					},
 				),
@@ -198,6 +223,18 @@ func Test_SessionsPrepare(t *testing.T) {
 						TOTPFactor: SessionTOTPFactor{
 							TOTPCheckedAt: testNow,
 						},
+						OTPSMSFactor: SessionOTPFactor{
+							OTPCheckedAt: testNow,
+						},
+						OTPEmailFactor: SessionOTPFactor{
+							OTPCheckedAt: testNow,
+						},
+						RecoveryCodeFactor: SessionRecoveryCodeFactor{
+							RecoveryCodeCheckedAt: testNow,
+						},
+						ClientCertFactor: SessionClientCertFactor{
+							ClientCertCheckedAt: testNow,
+						},
 						Metadata: map[string][]byte{
 							"key": []byte("value"),
 						},
@@ -231,7 +268,12 @@ func Test_SessionsPrepare(t *testing.T) {
 							testNow,
 							true,
 							testNow,
+							testNow,
+							testNow,
+							testNow,
+							testNow,
 							[]byte(`{"key": "dmFsdWU="}`),
+							nil,
 						},
 						{
 							"session-id2",
@@ -251,7 +293,12 @@ func Test_SessionsPrepare(t *testing.T) {
 							testNow,
 							false,
 							testNow,
+							testNow,
+							testNow,
+							testNow,
+							testNow,
 							[]byte(`{"key": "dmFsdWU="}`),
+							nil,
 						},
 					},
 				),
@@ -289,6 +336,18 @@ func Test_SessionsPrepare(t *testing.T) {
 						TOTPFactor: SessionTOTPFactor{
 							TOTPCheckedAt: testNow,
 						},
+						OTPSMSFactor: SessionOTPFactor{
+							OTPCheckedAt: testNow,
+						},
+						OTPEmailFactor: SessionOTPFactor{
+							OTPCheckedAt: testNow,
+						},
+						RecoveryCodeFactor: SessionRecoveryCodeFactor{
+							RecoveryCodeCheckedAt: testNow,
+						},
+						ClientCertFactor: SessionClientCertFactor{
+							ClientCertCheckedAt: testNow,
+						},
 						Metadata: map[string][]byte{
 							"key": []byte("value"),
 						},
@@ -321,6 +380,18 @@ func Test_SessionsPrepare(t *testing.T) {
 						TOTPFactor: SessionTOTPFactor{
 							TOTPCheckedAt: testNow,
 						},
+						OTPSMSFactor: SessionOTPFactor{
+							OTPCheckedAt: testNow,
+						},
+						OTPEmailFactor: SessionOTPFactor{
+							OTPCheckedAt: testNow,
+						},
+						RecoveryCodeFactor: SessionRecoveryCodeFactor{
+							RecoveryCodeCheckedAt: testNow,
+						},
+						ClientCertFactor: SessionClientCertFactor{
+							ClientCertCheckedAt: testNow,
+						},
 						Metadata: map[string][]byte{
 							"key": []byte("value"),
 						},
@@ -407,7 +478,12 @@ func Test_SessionPrepare(t *testing.T) {
 						testNow,
 						true,
 						testNow,
-						[]byte(`{"key": "dmFsdWU="}`),
+						testNow,
+						testNow,
+						testNow,
+						testNow,
+						[]byte(`{"key": "dmFsdWU=", "ttl": "dmFsdWU=", "expired": "dmFsdWU="}`),
+						[]byte(`{"ttl": "9999-01-01T00:00:00Z", "expired": "2023-05-04T00:00:00Z"}`),
 						"tokenID",
 					},
 				),
@@ -440,8 +516,21 @@ func Test_SessionPrepare(t *testing.T) {
 				TOTPFactor: SessionTOTPFactor{
 					TOTPCheckedAt: testNow,
 				},
+				OTPSMSFactor: SessionOTPFactor{
+					OTPCheckedAt: testNow,
+				},
+				OTPEmailFactor: SessionOTPFactor{
+					OTPCheckedAt: testNow,
+				},
+				RecoveryCodeFactor: SessionRecoveryCodeFactor{
+					RecoveryCodeCheckedAt: testNow,
+				},
+				ClientCertFactor: SessionClientCertFactor{
+					ClientCertCheckedAt: testNow,
+				},
 				Metadata: map[string][]byte{
 					"key": []byte("value"),
+					"ttl": []byte("value"),
 				},
 			},
 		},
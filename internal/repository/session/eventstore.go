@@ -6,11 +6,27 @@ func RegisterEventMappers(es *eventstore.Eventstore) {
 	es.RegisterFilterEventMapper(AggregateType, AddedType, AddedEventMapper).
 		RegisterFilterEventMapper(AggregateType, UserCheckedType, UserCheckedEventMapper).
 		RegisterFilterEventMapper(AggregateType, PasswordCheckedType, PasswordCheckedEventMapper).
+		RegisterFilterEventMapper(AggregateType, PasswordCheckFailedType, eventstore.GenericEventMapper[PasswordCheckFailedEvent]).
+		RegisterFilterEventMapper(AggregateType, PasswordChangedType, eventstore.GenericEventMapper[PasswordChangedEvent]).
 		RegisterFilterEventMapper(AggregateType, IntentCheckedType, IntentCheckedEventMapper).
 		RegisterFilterEventMapper(AggregateType, WebAuthNChallengedType, eventstore.GenericEventMapper[WebAuthNChallengedEvent]).
 		RegisterFilterEventMapper(AggregateType, WebAuthNCheckedType, eventstore.GenericEventMapper[WebAuthNCheckedEvent]).
 		RegisterFilterEventMapper(AggregateType, TOTPCheckedType, eventstore.GenericEventMapper[TOTPCheckedEvent]).
+		RegisterFilterEventMapper(AggregateType, OTPSMSCheckedType, eventstore.GenericEventMapper[OTPSMSCheckedEvent]).
+		RegisterFilterEventMapper(AggregateType, OTPEmailCheckedType, eventstore.GenericEventMapper[OTPEmailCheckedEvent]).
+		RegisterFilterEventMapper(AggregateType, RecoveryCodeCheckedType, eventstore.GenericEventMapper[RecoveryCodeCheckedEvent]).
+		RegisterFilterEventMapper(AggregateType, ClientCertCheckedType, eventstore.GenericEventMapper[ClientCertCheckedEvent]).
 		RegisterFilterEventMapper(AggregateType, TokenSetType, TokenSetEventMapper).
+		RegisterFilterEventMapper(AggregateType, LifetimeSetType, eventstore.GenericEventMapper[LifetimeSetEvent]).
 		RegisterFilterEventMapper(AggregateType, MetadataSetType, MetadataSetEventMapper).
-		RegisterFilterEventMapper(AggregateType, TerminateType, TerminateEventMapper)
+		RegisterFilterEventMapper(AggregateType, MetadataBulkSetType, eventstore.GenericEventMapper[MetadataBulkSetEvent]).
+		RegisterFilterEventMapper(AggregateType, MetadataRemovedType, eventstore.GenericEventMapper[MetadataRemovedEvent]).
+		RegisterFilterEventMapper(AggregateType, ForkedType, eventstore.GenericEventMapper[ForkedEvent]).
+		RegisterFilterEventMapper(AggregateType, DeviceTrustedType, eventstore.GenericEventMapper[DeviceTrustedEvent]).
+		RegisterFilterEventMapper(AggregateType, FactorInvalidatedType, eventstore.GenericEventMapper[FactorInvalidatedEvent]).
+		RegisterFilterEventMapper(AggregateType, LockedType, eventstore.GenericEventMapper[LockedEvent]).
+		RegisterFilterEventMapper(AggregateType, UnlockedType, eventstore.GenericEventMapper[UnlockedEvent]).
+		RegisterFilterEventMapper(AggregateType, ActivityType, eventstore.GenericEventMapper[ActivityEvent]).
+		RegisterFilterEventMapper(AggregateType, TerminateType, TerminateEventMapper).
+		RegisterFilterEventMapper(AggregateType, SnapshotType, eventstore.GenericEventMapper[SnapshotEvent])
 }
@@ -12,21 +12,47 @@ import (
 )
 
 const (
-	sessionEventPrefix     = "session."
-	AddedType              = sessionEventPrefix + "added"
-	UserCheckedType        = sessionEventPrefix + "user.checked"
-	PasswordCheckedType    = sessionEventPrefix + "password.checked"
-	IntentCheckedType      = sessionEventPrefix + "intent.checked"
-	WebAuthNChallengedType = sessionEventPrefix + "webAuthN.challenged"
-	WebAuthNCheckedType    = sessionEventPrefix + "webAuthN.checked"
-	TOTPCheckedType        = sessionEventPrefix + "totp.checked"
-	TokenSetType           = sessionEventPrefix + "token.set"
-	MetadataSetType        = sessionEventPrefix + "metadata.set"
-	TerminateType          = sessionEventPrefix + "terminated"
+	sessionEventPrefix      = "session."
+	AddedType               = sessionEventPrefix + "added"
+	UserCheckedType         = sessionEventPrefix + "user.checked"
+	PasswordCheckedType     = sessionEventPrefix + "password.checked"
+	PasswordCheckFailedType = sessionEventPrefix + "password.check.failed"
+	PasswordChangedType     = sessionEventPrefix + "password.changed"
+	IntentCheckedType       = sessionEventPrefix + "intent.checked"
+	WebAuthNChallengedType  = sessionEventPrefix + "webAuthN.challenged"
+	WebAuthNCheckedType     = sessionEventPrefix + "webAuthN.checked"
+	TOTPCheckedType         = sessionEventPrefix + "totp.checked"
+	OTPSMSCheckedType       = sessionEventPrefix + "otp.sms.checked"
+	OTPEmailCheckedType     = sessionEventPrefix + "otp.email.checked"
+	RecoveryCodeCheckedType = sessionEventPrefix + "recoverycode.checked"
+	ClientCertCheckedType   = sessionEventPrefix + "clientcert.checked"
+	TokenSetType            = sessionEventPrefix + "token.set"
+	LifetimeSetType         = sessionEventPrefix + "lifetime.set"
+	MetadataSetType         = sessionEventPrefix + "metadata.set"
+	MetadataBulkSetType     = sessionEventPrefix + "metadata.bulk.set"
+	MetadataRemovedType     = sessionEventPrefix + "metadata.removed"
+	ForkedType              = sessionEventPrefix + "forked"
+	DeviceTrustedType       = sessionEventPrefix + "device.trusted"
+	FactorInvalidatedType   = sessionEventPrefix + "factor.invalidated"
+	LockedType              = sessionEventPrefix + "locked"
+	UnlockedType            = sessionEventPrefix + "unlocked"
+	ActivityType            = sessionEventPrefix + "activity"
+	TerminateType           = sessionEventPrefix + "terminated"
+	SnapshotType            = sessionEventPrefix + "snapshot"
 )
 
 type AddedEvent struct {
 	eventstore.BaseEvent `json:"-"`
+
+	Lifetime time.Duration `json:"lifetime,omitempty"`
+	// UserAgentFingerprintID is the id of the user agent (browser / device) the session was created with
+	UserAgentFingerprintID string `json:"userAgentFingerprintID,omitempty"`
+	// CreatedFromRequestID is the id of the (auth) request the session was created for
+	CreatedFromRequestID string `json:"createdFromRequestID,omitempty"`
+	// ClientID is the id of the client (application) the session was created by
+	ClientID string `json:"clientID,omitempty"`
+	// AllowedAuthMethods restricts the factors, which can be checked on the session (e.g. for kiosks), empty allows all
+	AllowedAuthMethods []domain.UserAuthMethodType `json:"allowedAuthMethods,omitempty"`
 }
 
 func (e *AddedEvent) Data() interface{} {
@@ -39,6 +65,11 @@ func (e *AddedEvent) UniqueConstraints() []*eventstore.EventUniqueConstraint {
 
 func NewAddedEvent(ctx context.Context,
 	aggregate *eventstore.Aggregate,
+	lifetime time.Duration,
+	userAgentFingerprintID,
+	createdFromRequestID,
+	clientID string,
+	allowedAuthMethods []domain.UserAuthMethodType,
 ) *AddedEvent {
 	return &AddedEvent{
 		BaseEvent: *eventstore.NewBaseEventForPush(
@@ -46,6 +77,11 @@ func NewAddedEvent(ctx context.Context,
 			aggregate,
 			AddedType,
 		),
+		Lifetime:               lifetime,
+		UserAgentFingerprintID: userAgentFingerprintID,
+		CreatedFromRequestID:   createdFromRequestID,
+		ClientID:               clientID,
+		AllowedAuthMethods:     allowedAuthMethods,
 	}
 }
 
@@ -109,6 +145,8 @@ type PasswordCheckedEvent struct {
 	eventstore.BaseEvent `json:"-"`
 
 	CheckedAt time.Time `json:"checkedAt"`
+	// IdempotencyKey is provided by the client to detect retries of the same check
+	IdempotencyKey string `json:"idempotencyKey,omitempty"`
 }
 
 func (e *PasswordCheckedEvent) Data() interface{} {
@@ -123,6 +161,7 @@ func NewPasswordCheckedEvent(
 	ctx context.Context,
 	aggregate *eventstore.Aggregate,
 	checkedAt time.Time,
+	idempotencyKey string,
 ) *PasswordCheckedEvent {
 	return &PasswordCheckedEvent{
 		BaseEvent: *eventstore.NewBaseEventForPush(
@@ -130,7 +169,8 @@ func NewPasswordCheckedEvent(
 			aggregate,
 			PasswordCheckedType,
 		),
-		CheckedAt: checkedAt,
+		CheckedAt:      checkedAt,
+		IdempotencyKey: idempotencyKey,
 	}
 }
 
@@ -146,12 +186,82 @@ func PasswordCheckedEventMapper(event *repository.Event) (eventstore.Event, erro
 	return added, nil
 }
 
-type IntentCheckedEvent struct {
+type PasswordCheckFailedEvent struct {
 	eventstore.BaseEvent `json:"-"`
 
 	CheckedAt time.Time `json:"checkedAt"`
 }
 
+func (e *PasswordCheckFailedEvent) Data() interface{} {
+	return e
+}
+
+func (e *PasswordCheckFailedEvent) UniqueConstraints() []*eventstore.EventUniqueConstraint {
+	return nil
+}
+
+func (e *PasswordCheckFailedEvent) SetBaseEvent(base *eventstore.BaseEvent) {
+	e.BaseEvent = *base
+}
+
+func NewPasswordCheckFailedEvent(
+	ctx context.Context,
+	aggregate *eventstore.Aggregate,
+	checkedAt time.Time,
+) *PasswordCheckFailedEvent {
+	return &PasswordCheckFailedEvent{
+		BaseEvent: *eventstore.NewBaseEventForPush(
+			ctx,
+			aggregate,
+			PasswordCheckFailedType,
+		),
+		CheckedAt: checkedAt,
+	}
+}
+
+// PasswordChangedEvent notifies the session about a change of the password of its user,
+// so a previous password check is no longer valid
+type PasswordChangedEvent struct {
+	eventstore.BaseEvent `json:"-"`
+
+	ChangedAt time.Time `json:"changedAt"`
+}
+
+func (e *PasswordChangedEvent) Data() interface{} {
+	return e
+}
+
+func (e *PasswordChangedEvent) UniqueConstraints() []*eventstore.EventUniqueConstraint {
+	return nil
+}
+
+func (e *PasswordChangedEvent) SetBaseEvent(base *eventstore.BaseEvent) {
+	e.BaseEvent = *base
+}
+
+func NewPasswordChangedEvent(
+	ctx context.Context,
+	aggregate *eventstore.Aggregate,
+	changedAt time.Time,
+) *PasswordChangedEvent {
+	return &PasswordChangedEvent{
+		BaseEvent: *eventstore.NewBaseEventForPush(
+			ctx,
+			aggregate,
+			PasswordChangedType,
+		),
+		ChangedAt: changedAt,
+	}
+}
+
+type IntentCheckedEvent struct {
+	eventstore.BaseEvent `json:"-"`
+
+	CheckedAt time.Time                `json:"checkedAt"`
+	IDPID     string                   `json:"idpID,omitempty"`
+	Protocol  domain.IDPIntentProtocol `json:"protocol,omitempty"`
+}
+
 func (e *IntentCheckedEvent) Data() interface{} {
 	return e
 }
@@ -164,6 +274,8 @@ func NewIntentCheckedEvent(
 	ctx context.Context,
 	aggregate *eventstore.Aggregate,
 	checkedAt time.Time,
+	idpID string,
+	protocol domain.IDPIntentProtocol,
 ) *IntentCheckedEvent {
 	return &IntentCheckedEvent{
 		BaseEvent: *eventstore.NewBaseEventForPush(
@@ -172,6 +284,8 @@ func NewIntentCheckedEvent(
 			IntentCheckedType,
 		),
 		CheckedAt: checkedAt,
+		IDPID:     idpID,
+		Protocol:  protocol,
 	}
 }
 
@@ -190,10 +304,16 @@ func IntentCheckedEventMapper(event *repository.Event) (eventstore.Event, error)
 type WebAuthNChallengedEvent struct {
 	eventstore.BaseEvent `json:"-"`
 
-	Challenge          string                             `json:"challenge,omitempty"`
-	AllowedCrentialIDs [][]byte                           `json:"allowedCrentialIDs,omitempty"`
-	UserVerification   domain.UserVerificationRequirement `json:"userVerification,omitempty"`
-	RPID               string                             `json:"rpid,omitempty"`
+	Challenge          string   `json:"challenge,omitempty"`
+	AllowedCrentialIDs [][]byte `json:"allowedCrentialIDs,omitempty"`
+	// AllowedTransports are the transports (e.g. usb, nfc, ble or internal) of the allowed credentials
+	// by their (base64 URL encoded) credential id
+	AllowedTransports map[string][]string                `json:"allowedTransports,omitempty"`
+	UserVerification  domain.UserVerificationRequirement `json:"userVerification,omitempty"`
+	RPID              string                             `json:"rpid,omitempty"`
+	Expiration        time.Time                          `json:"expiration,omitempty"`
+	// ReplacedChallenge is the challenge, which is discarded in favour of this one
+	ReplacedChallenge string `json:"replacedChallenge,omitempty"`
 }
 
 func (e *WebAuthNChallengedEvent) Data() interface{} {
@@ -213,8 +333,10 @@ func NewWebAuthNChallengedEvent(
 	aggregate *eventstore.Aggregate,
 	challenge string,
 	allowedCrentialIDs [][]byte,
+	allowedTransports map[string][]string,
 	userVerification domain.UserVerificationRequirement,
 	rpid string,
+	expiration time.Time,
 ) *WebAuthNChallengedEvent {
 	return &WebAuthNChallengedEvent{
 		BaseEvent: *eventstore.NewBaseEventForPush(
@@ -224,16 +346,45 @@ func NewWebAuthNChallengedEvent(
 		),
 		Challenge:          challenge,
 		AllowedCrentialIDs: allowedCrentialIDs,
+		AllowedTransports:  allowedTransports,
 		UserVerification:   userVerification,
 		RPID:               rpid,
+		Expiration:         expiration,
 	}
 }
 
+// NewWebAuthNChallengeRefreshedEvent creates a [WebAuthNChallengedEvent], which replaces the replacedChallenge by the new challenge
+func NewWebAuthNChallengeRefreshedEvent(
+	ctx context.Context,
+	aggregate *eventstore.Aggregate,
+	replacedChallenge string,
+	challenge string,
+	allowedCrentialIDs [][]byte,
+	allowedTransports map[string][]string,
+	userVerification domain.UserVerificationRequirement,
+	rpid string,
+	expiration time.Time,
+) *WebAuthNChallengedEvent {
+	event := NewWebAuthNChallengedEvent(ctx, aggregate, challenge, allowedCrentialIDs, allowedTransports, userVerification, rpid, expiration)
+	event.ReplacedChallenge = replacedChallenge
+	return event
+}
+
 type WebAuthNCheckedEvent struct {
 	eventstore.BaseEvent `json:"-"`
 
 	CheckedAt    time.Time `json:"checkedAt"`
 	UserVerified bool      `json:"userVerified,omitempty"`
+	// UserPresent states if the user was present (e.g. touched the authenticator),
+	// silent assertions might be user verified or not, but will not be present
+	UserPresent bool `json:"userPresent,omitempty"`
+	// SignCount is the signature counter reported by the authenticator
+	SignCount uint32 `json:"signCount,omitempty"`
+	// AttestationFormat is the attestation format (e.g. "packed" or "tpm") the used credential was registered with
+	AttestationFormat string `json:"attestationFormat,omitempty"`
+	Challenge         string `json:"challenge,omitempty"`
+	// ExtensionResults are the client extension outputs of the assertion (e.g. credProps or largeBlob)
+	ExtensionResults map[string]interface{} `json:"extensionResults,omitempty"`
 }
 
 func (e *WebAuthNCheckedEvent) Data() interface{} {
@@ -253,6 +404,11 @@ func NewWebAuthNCheckedEvent(
 	aggregate *eventstore.Aggregate,
 	checkedAt time.Time,
 	userVerified bool,
+	userPresent bool,
+	signCount uint32,
+	attestationFormat string,
+	challenge string,
+	extensionResults map[string]interface{},
 ) *WebAuthNCheckedEvent {
 	return &WebAuthNCheckedEvent{
 		BaseEvent: *eventstore.NewBaseEventForPush(
@@ -260,8 +416,13 @@ func NewWebAuthNCheckedEvent(
 			aggregate,
 			WebAuthNCheckedType,
 		),
-		CheckedAt:    checkedAt,
-		UserVerified: userVerified,
+		CheckedAt:         checkedAt,
+		UserVerified:      userVerified,
+		UserPresent:       userPresent,
+		SignCount:         signCount,
+		AttestationFormat: attestationFormat,
+		Challenge:         challenge,
+		ExtensionResults:  extensionResults,
 	}
 }
 
@@ -269,6 +430,10 @@ type TOTPCheckedEvent struct {
 	eventstore.BaseEvent `json:"-"`
 
 	CheckedAt time.Time `json:"checkedAt"`
+	// DeviceID identifies the TOTP authenticator (registration) of the user, which was checked
+	DeviceID string `json:"deviceID,omitempty"`
+	// Step is the time-step the verified code was generated for, which must not be reused
+	Step uint64 `json:"step,omitempty"`
 }
 
 func (e *TOTPCheckedEvent) Data() interface{} {
@@ -287,6 +452,8 @@ func NewTOTPCheckedEvent(
 	ctx context.Context,
 	aggregate *eventstore.Aggregate,
 	checkedAt time.Time,
+	deviceID string,
+	step uint64,
 ) *TOTPCheckedEvent {
 	return &TOTPCheckedEvent{
 		BaseEvent: *eventstore.NewBaseEventForPush(
@@ -295,6 +462,149 @@ func NewTOTPCheckedEvent(
 			TOTPCheckedType,
 		),
 		CheckedAt: checkedAt,
+		DeviceID:  deviceID,
+		Step:      step,
+	}
+}
+
+type RecoveryCodeCheckedEvent struct {
+	eventstore.BaseEvent `json:"-"`
+
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+func (e *RecoveryCodeCheckedEvent) Data() interface{} {
+	return e
+}
+
+func (e *RecoveryCodeCheckedEvent) UniqueConstraints() []*eventstore.EventUniqueConstraint {
+	return nil
+}
+
+func (e *RecoveryCodeCheckedEvent) SetBaseEvent(base *eventstore.BaseEvent) {
+	e.BaseEvent = *base
+}
+
+func NewRecoveryCodeCheckedEvent(
+	ctx context.Context,
+	aggregate *eventstore.Aggregate,
+	checkedAt time.Time,
+) *RecoveryCodeCheckedEvent {
+	return &RecoveryCodeCheckedEvent{
+		BaseEvent: *eventstore.NewBaseEventForPush(
+			ctx,
+			aggregate,
+			RecoveryCodeCheckedType,
+		),
+		CheckedAt: checkedAt,
+	}
+}
+
+// ClientCertCheckedEvent records the authentication of the user by a client certificate (mTLS)
+type ClientCertCheckedEvent struct {
+	eventstore.BaseEvent `json:"-"`
+
+	CheckedAt time.Time `json:"checkedAt"`
+	// Fingerprint is the (hex encoded) sha256 fingerprint of the used certificate
+	Fingerprint string `json:"fingerprint,omitempty"`
+}
+
+func (e *ClientCertCheckedEvent) Data() interface{} {
+	return e
+}
+
+func (e *ClientCertCheckedEvent) UniqueConstraints() []*eventstore.EventUniqueConstraint {
+	return nil
+}
+
+func (e *ClientCertCheckedEvent) SetBaseEvent(base *eventstore.BaseEvent) {
+	e.BaseEvent = *base
+}
+
+func NewClientCertCheckedEvent(
+	ctx context.Context,
+	aggregate *eventstore.Aggregate,
+	checkedAt time.Time,
+	fingerprint string,
+) *ClientCertCheckedEvent {
+	return &ClientCertCheckedEvent{
+		BaseEvent: *eventstore.NewBaseEventForPush(
+			ctx,
+			aggregate,
+			ClientCertCheckedType,
+		),
+		CheckedAt:   checkedAt,
+		Fingerprint: fingerprint,
+	}
+}
+
+type OTPSMSCheckedEvent struct {
+	eventstore.BaseEvent `json:"-"`
+
+	CheckedAt time.Time `json:"checkedAt"`
+	// PhoneSequence is the sequence of the latest change of the user's phone number the OTP was sent to
+	PhoneSequence uint64 `json:"phoneSequence,omitempty"`
+}
+
+func (e *OTPSMSCheckedEvent) Data() interface{} {
+	return e
+}
+
+func (e *OTPSMSCheckedEvent) UniqueConstraints() []*eventstore.EventUniqueConstraint {
+	return nil
+}
+
+func (e *OTPSMSCheckedEvent) SetBaseEvent(base *eventstore.BaseEvent) {
+	e.BaseEvent = *base
+}
+
+func NewOTPSMSCheckedEvent(
+	ctx context.Context,
+	aggregate *eventstore.Aggregate,
+	checkedAt time.Time,
+	phoneSequence uint64,
+) *OTPSMSCheckedEvent {
+	return &OTPSMSCheckedEvent{
+		BaseEvent: *eventstore.NewBaseEventForPush(
+			ctx,
+			aggregate,
+			OTPSMSCheckedType,
+		),
+		CheckedAt:     checkedAt,
+		PhoneSequence: phoneSequence,
+	}
+}
+
+type OTPEmailCheckedEvent struct {
+	eventstore.BaseEvent `json:"-"`
+
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+func (e *OTPEmailCheckedEvent) Data() interface{} {
+	return e
+}
+
+func (e *OTPEmailCheckedEvent) UniqueConstraints() []*eventstore.EventUniqueConstraint {
+	return nil
+}
+
+func (e *OTPEmailCheckedEvent) SetBaseEvent(base *eventstore.BaseEvent) {
+	e.BaseEvent = *base
+}
+
+func NewOTPEmailCheckedEvent(
+	ctx context.Context,
+	aggregate *eventstore.Aggregate,
+	checkedAt time.Time,
+) *OTPEmailCheckedEvent {
+	return &OTPEmailCheckedEvent{
+		BaseEvent: *eventstore.NewBaseEventForPush(
+			ctx,
+			aggregate,
+			OTPEmailCheckedType,
+		),
+		CheckedAt: checkedAt,
 	}
 }
 
@@ -339,10 +649,49 @@ func TokenSetEventMapper(event *repository.Event) (eventstore.Event, error) {
 	return added, nil
 }
 
+type LifetimeSetEvent struct {
+	eventstore.BaseEvent `json:"-"`
+
+	// Lifetime moves the expiration of the session to the creation of the event plus the lifetime (if set)
+	Lifetime    time.Duration `json:"lifetime,omitempty"`
+	IdleTimeout time.Duration `json:"idleTimeout,omitempty"`
+}
+
+func (e *LifetimeSetEvent) Data() interface{} {
+	return e
+}
+
+func (e *LifetimeSetEvent) UniqueConstraints() []*eventstore.EventUniqueConstraint {
+	return nil
+}
+
+func (e *LifetimeSetEvent) SetBaseEvent(base *eventstore.BaseEvent) {
+	e.BaseEvent = *base
+}
+
+func NewLifetimeSetEvent(
+	ctx context.Context,
+	aggregate *eventstore.Aggregate,
+	lifetime,
+	idleTimeout time.Duration,
+) *LifetimeSetEvent {
+	return &LifetimeSetEvent{
+		BaseEvent: *eventstore.NewBaseEventForPush(
+			ctx,
+			aggregate,
+			LifetimeSetType,
+		),
+		Lifetime:    lifetime,
+		IdleTimeout: idleTimeout,
+	}
+}
+
 type MetadataSetEvent struct {
 	eventstore.BaseEvent `json:"-"`
 
 	Metadata map[string][]byte `json:"metadata"`
+	// Expirations contains the time until the value of the key is valid, for keys set with a TTL
+	Expirations map[string]time.Time `json:"expirations,omitempty"`
 }
 
 func (e *MetadataSetEvent) Data() interface{} {
@@ -357,6 +706,7 @@ func NewMetadataSetEvent(
 	ctx context.Context,
 	aggregate *eventstore.Aggregate,
 	metadata map[string][]byte,
+	expirations map[string]time.Time,
 ) *MetadataSetEvent {
 	return &MetadataSetEvent{
 		BaseEvent: *eventstore.NewBaseEventForPush(
@@ -364,7 +714,8 @@ func NewMetadataSetEvent(
 			aggregate,
 			MetadataSetType,
 		),
-		Metadata: metadata,
+		Metadata:    metadata,
+		Expirations: expirations,
 	}
 }
 
@@ -380,8 +731,282 @@ func MetadataSetEventMapper(event *repository.Event) (eventstore.Event, error) {
 	return added, nil
 }
 
+// MetadataBulkSetEvent sets multiple metadata entries at once.
+// Unlike the [MetadataSetEvent], the entries are merged into the existing metadata.
+type MetadataBulkSetEvent struct {
+	eventstore.BaseEvent `json:"-"`
+
+	Metadata map[string][]byte `json:"metadata"`
+}
+
+func (e *MetadataBulkSetEvent) Data() interface{} {
+	return e
+}
+
+func (e *MetadataBulkSetEvent) UniqueConstraints() []*eventstore.EventUniqueConstraint {
+	return nil
+}
+
+func (e *MetadataBulkSetEvent) SetBaseEvent(base *eventstore.BaseEvent) {
+	e.BaseEvent = *base
+}
+
+func NewMetadataBulkSetEvent(
+	ctx context.Context,
+	aggregate *eventstore.Aggregate,
+	metadata map[string][]byte,
+) *MetadataBulkSetEvent {
+	return &MetadataBulkSetEvent{
+		BaseEvent: *eventstore.NewBaseEventForPush(
+			ctx,
+			aggregate,
+			MetadataBulkSetType,
+		),
+		Metadata: metadata,
+	}
+}
+
+type MetadataRemovedEvent struct {
+	eventstore.BaseEvent `json:"-"`
+
+	Keys []string `json:"keys"`
+}
+
+func (e *MetadataRemovedEvent) Data() interface{} {
+	return e
+}
+
+func (e *MetadataRemovedEvent) UniqueConstraints() []*eventstore.EventUniqueConstraint {
+	return nil
+}
+
+func (e *MetadataRemovedEvent) SetBaseEvent(base *eventstore.BaseEvent) {
+	e.BaseEvent = *base
+}
+
+func NewMetadataRemovedEvent(
+	ctx context.Context,
+	aggregate *eventstore.Aggregate,
+	keys []string,
+) *MetadataRemovedEvent {
+	return &MetadataRemovedEvent{
+		BaseEvent: *eventstore.NewBaseEventForPush(
+			ctx,
+			aggregate,
+			MetadataRemovedType,
+		),
+		Keys: keys,
+	}
+}
+
+// ForkedEvent marks the session as child of another (parent) session, e.g. for delegated (impersonation / on-behalf-of) contexts
+type ForkedEvent struct {
+	eventstore.BaseEvent `json:"-"`
+
+	ParentSessionID string `json:"parentSessionID"`
+	// DelegatorUserID is the user of the parent session, who delegated the context
+	DelegatorUserID string `json:"delegatorUserID,omitempty"`
+	// InheritedAuthMethodTypes are the auth method types of the parent session at the time of the fork,
+	// which will be reported by the child session as well
+	InheritedAuthMethodTypes []domain.UserAuthMethodType `json:"inheritedAuthMethodTypes,omitempty"`
+}
+
+func (e *ForkedEvent) Data() interface{} {
+	return e
+}
+
+func (e *ForkedEvent) UniqueConstraints() []*eventstore.EventUniqueConstraint {
+	return nil
+}
+
+func (e *ForkedEvent) SetBaseEvent(base *eventstore.BaseEvent) {
+	e.BaseEvent = *base
+}
+
+func NewForkedEvent(
+	ctx context.Context,
+	aggregate *eventstore.Aggregate,
+	parentSessionID,
+	delegatorUserID string,
+	inheritedAuthMethodTypes []domain.UserAuthMethodType,
+) *ForkedEvent {
+	return &ForkedEvent{
+		BaseEvent: *eventstore.NewBaseEventForPush(
+			ctx,
+			aggregate,
+			ForkedType,
+		),
+		ParentSessionID:          parentSessionID,
+		DelegatorUserID:          delegatorUserID,
+		InheritedAuthMethodTypes: inheritedAuthMethodTypes,
+	}
+}
+
+// DeviceTrustedEvent marks the device (user agent) of the session as trusted (remembered) until the expiration,
+// e.g. to skip the second factor on subsequent logins
+type DeviceTrustedEvent struct {
+	eventstore.BaseEvent `json:"-"`
+
+	Expiration time.Time `json:"expiration,omitempty"`
+}
+
+func (e *DeviceTrustedEvent) Data() interface{} {
+	return e
+}
+
+func (e *DeviceTrustedEvent) UniqueConstraints() []*eventstore.EventUniqueConstraint {
+	return nil
+}
+
+func (e *DeviceTrustedEvent) SetBaseEvent(base *eventstore.BaseEvent) {
+	e.BaseEvent = *base
+}
+
+func NewDeviceTrustedEvent(
+	ctx context.Context,
+	aggregate *eventstore.Aggregate,
+	expiration time.Time,
+) *DeviceTrustedEvent {
+	return &DeviceTrustedEvent{
+		BaseEvent: *eventstore.NewBaseEventForPush(
+			ctx,
+			aggregate,
+			DeviceTrustedType,
+		),
+		Expiration: expiration,
+	}
+}
+
+// FactorInvalidatedEvent invalidates a previous check of the factor,
+// e.g. because the used credential was removed by the user
+type FactorInvalidatedEvent struct {
+	eventstore.BaseEvent `json:"-"`
+
+	Factor domain.UserAuthMethodType `json:"factor"`
+}
+
+func (e *FactorInvalidatedEvent) Data() interface{} {
+	return e
+}
+
+func (e *FactorInvalidatedEvent) UniqueConstraints() []*eventstore.EventUniqueConstraint {
+	return nil
+}
+
+func (e *FactorInvalidatedEvent) SetBaseEvent(base *eventstore.BaseEvent) {
+	e.BaseEvent = *base
+}
+
+func NewFactorInvalidatedEvent(
+	ctx context.Context,
+	aggregate *eventstore.Aggregate,
+	factor domain.UserAuthMethodType,
+) *FactorInvalidatedEvent {
+	return &FactorInvalidatedEvent{
+		BaseEvent: *eventstore.NewBaseEventForPush(
+			ctx,
+			aggregate,
+			FactorInvalidatedType,
+		),
+		Factor: factor,
+	}
+}
+
+// LockedEvent temporarily suspends the session, e.g. because of suspicious activity
+type LockedEvent struct {
+	eventstore.BaseEvent `json:"-"`
+}
+
+func (e *LockedEvent) Data() interface{} {
+	return nil
+}
+
+func (e *LockedEvent) UniqueConstraints() []*eventstore.EventUniqueConstraint {
+	return nil
+}
+
+func (e *LockedEvent) SetBaseEvent(base *eventstore.BaseEvent) {
+	e.BaseEvent = *base
+}
+
+func NewLockedEvent(
+	ctx context.Context,
+	aggregate *eventstore.Aggregate,
+) *LockedEvent {
+	return &LockedEvent{
+		BaseEvent: *eventstore.NewBaseEventForPush(
+			ctx,
+			aggregate,
+			LockedType,
+		),
+	}
+}
+
+// UnlockedEvent resumes a session suspended by the [LockedEvent]
+type UnlockedEvent struct {
+	eventstore.BaseEvent `json:"-"`
+}
+
+func (e *UnlockedEvent) Data() interface{} {
+	return nil
+}
+
+func (e *UnlockedEvent) UniqueConstraints() []*eventstore.EventUniqueConstraint {
+	return nil
+}
+
+func (e *UnlockedEvent) SetBaseEvent(base *eventstore.BaseEvent) {
+	e.BaseEvent = *base
+}
+
+func NewUnlockedEvent(
+	ctx context.Context,
+	aggregate *eventstore.Aggregate,
+) *UnlockedEvent {
+	return &UnlockedEvent{
+		BaseEvent: *eventstore.NewBaseEventForPush(
+			ctx,
+			aggregate,
+			UnlockedType,
+		),
+	}
+}
+
+// ActivityEvent records the use of the session (e.g. the validation of its token)
+// without any (re-)authentication, so the idle timeout can be based on the last activity
+type ActivityEvent struct {
+	eventstore.BaseEvent `json:"-"`
+}
+
+func (e *ActivityEvent) Data() interface{} {
+	return nil
+}
+
+func (e *ActivityEvent) UniqueConstraints() []*eventstore.EventUniqueConstraint {
+	return nil
+}
+
+func (e *ActivityEvent) SetBaseEvent(base *eventstore.BaseEvent) {
+	e.BaseEvent = *base
+}
+
+func NewActivityEvent(
+	ctx context.Context,
+	aggregate *eventstore.Aggregate,
+) *ActivityEvent {
+	return &ActivityEvent{
+		BaseEvent: *eventstore.NewBaseEventForPush(
+			ctx,
+			aggregate,
+			ActivityType,
+		),
+	}
+}
+
 type TerminateEvent struct {
 	eventstore.BaseEvent `json:"-"`
+
+	Reason domain.SessionTerminationType `json:"reason,omitempty"`
 }
 
 func (e *TerminateEvent) Data() interface{} {
@@ -395,6 +1020,7 @@ func (e *TerminateEvent) UniqueConstraints() []*eventstore.EventUniqueConstraint
 func NewTerminateEvent(
 	ctx context.Context,
 	aggregate *eventstore.Aggregate,
+	reason domain.SessionTerminationType,
 ) *TerminateEvent {
 	return &TerminateEvent{
 		BaseEvent: *eventstore.NewBaseEventForPush(
@@ -402,11 +1028,134 @@ func NewTerminateEvent(
 			aggregate,
 			TerminateType,
 		),
+		Reason: reason,
 	}
 }
 
 func TerminateEventMapper(event *repository.Event) (eventstore.Event, error) {
-	return &TerminateEvent{
+	terminated := &TerminateEvent{
 		BaseEvent: *eventstore.BaseEventFromRepo(event),
-	}, nil
+	}
+	// events created before the reason was introduced do not have any data
+	if len(event.Data) == 0 {
+		return terminated, nil
+	}
+	err := json.Unmarshal(event.Data, terminated)
+	if err != nil {
+		return nil, errors.ThrowInternal(err, "SESSION-Xo7ei", "unable to unmarshal session terminated")
+	}
+
+	return terminated, nil
+}
+
+// SnapshotState is the aggregated state of a session at the time of a [SnapshotEvent]
+type SnapshotState struct {
+	TokenID                   string                                                 `json:"tokenID,omitempty"`
+	PreviousTokenID           string                                                 `json:"previousTokenID,omitempty"`
+	RevokedTokenIDs           []string                                               `json:"revokedTokenIDs,omitempty"`
+	UserID                    string                                                 `json:"userID,omitempty"`
+	UserAgentFingerprintID    string                                                 `json:"userAgentFingerprintID,omitempty"`
+	CreatedFromRequestID      string                                                 `json:"createdFromRequestID,omitempty"`
+	ClientID                  string                                                 `json:"clientID,omitempty"`
+	AllowedAuthMethods        []domain.UserAuthMethodType                            `json:"allowedAuthMethods,omitempty"`
+	UserCheckedAt             time.Time                                              `json:"userCheckedAt,omitempty"`
+	PasswordCheckedAt         time.Time                                              `json:"passwordCheckedAt,omitempty"`
+	PasswordCheckFailures     int                                                    `json:"passwordCheckFailures,omitempty"`
+	PasswordChangedAt         time.Time                                              `json:"passwordChangedAt,omitempty"`
+	IntentCheckedAt           time.Time                                              `json:"intentCheckedAt,omitempty"`
+	IntentIDPID               string                                                 `json:"intentIDPID,omitempty"`
+	IntentProtocol            domain.IDPIntentProtocol                               `json:"intentProtocol,omitempty"`
+	WebAuthNCheckedAt         time.Time                                              `json:"webAuthNCheckedAt,omitempty"`
+	WebAuthNUserVerified      bool                                                   `json:"webAuthNUserVerified,omitempty"`
+	WebAuthNUserPresent       bool                                                   `json:"webAuthNUserPresent,omitempty"`
+	WebAuthNSignCount         uint32                                                 `json:"webAuthNSignCount,omitempty"`
+	WebAuthNAttestationFormat string                                                 `json:"webAuthNAttestationFormat,omitempty"`
+	WebAuthNExtensionResults  map[string]interface{}                                 `json:"webAuthNExtensionResults,omitempty"`
+	WebAuthNIsPasswordless    bool                                                   `json:"webAuthNIsPasswordless,omitempty"`
+	TOTPCheckedAt             time.Time                                              `json:"totpCheckedAt,omitempty"`
+	TOTPDeviceID              string                                                 `json:"totpDeviceID,omitempty"`
+	TOTPLastStep              uint64                                                 `json:"totpLastStep,omitempty"`
+	RecoveryCodeCheckedAt     time.Time                                              `json:"recoveryCodeCheckedAt,omitempty"`
+	ClientCertCheckedAt       time.Time                                              `json:"clientCertCheckedAt,omitempty"`
+	ClientCertFingerprint     string                                                 `json:"clientCertFingerprint,omitempty"`
+	OTPSMSCheckedAt           time.Time                                              `json:"otpSMSCheckedAt,omitempty"`
+	OTPSMSPhoneSequence       uint64                                                 `json:"otpSMSPhoneSequence,omitempty"`
+	OTPEmailCheckedAt         time.Time                                              `json:"otpEmailCheckedAt,omitempty"`
+	Metadata                  map[string][]byte                                      `json:"metadata,omitempty"`
+	MetadataExpirations       map[string]time.Time                                   `json:"metadataExpirations,omitempty"`
+	State                     domain.SessionState                                    `json:"state,omitempty"`
+	TerminationReason         domain.SessionTerminationType                          `json:"terminationReason,omitempty"`
+	Expiration                time.Time                                              `json:"expiration,omitempty"`
+	IdleTimeout               time.Duration                                          `json:"idleTimeout,omitempty"`
+	IdleExpiration            time.Time                                              `json:"idleExpiration,omitempty"`
+	LastActivityAt            time.Time                                              `json:"lastActivityAt,omitempty"`
+	WebAuthNChallenges        []*SnapshotWebAuthNChallenge                           `json:"webAuthNChallenges,omitempty"`
+	LatestWebAuthNChallenge   string                                                 `json:"latestWebAuthNChallenge,omitempty"`
+	CheckHistory              []*SnapshotFactorCheck                                 `json:"checkHistory,omitempty"`
+	ParentSessionID           string                                                 `json:"parentSessionID,omitempty"`
+	DelegatorUserID           string                                                 `json:"delegatorUserID,omitempty"`
+	InheritedAuthMethodTypes  []domain.UserAuthMethodType                            `json:"inheritedAuthMethodTypes,omitempty"`
+	TrustedDevice             bool                                                   `json:"trustedDevice,omitempty"`
+	TrustedDeviceExpiration   time.Time                                              `json:"trustedDeviceExpiration,omitempty"`
+	SecurityLevelDegraded     bool                                                   `json:"securityLevelDegraded,omitempty"`
+	UserMismatch              bool                                                   `json:"userMismatch,omitempty"`
+	IdempotentChecks          map[domain.UserAuthMethodType]*SnapshotIdempotentCheck `json:"idempotentChecks,omitempty"`
+}
+
+// SnapshotIdempotentCheck is the latest check of a factor made with an idempotency key of a [SnapshotState]
+type SnapshotIdempotentCheck struct {
+	IdempotencyKey string    `json:"idempotencyKey,omitempty"`
+	CheckedAt      time.Time `json:"checkedAt,omitempty"`
+}
+
+// SnapshotFactorCheck is an entry of the check history of a [SnapshotState]
+type SnapshotFactorCheck struct {
+	Factor    domain.UserAuthMethodType `json:"factor,omitempty"`
+	CheckedAt time.Time                 `json:"checkedAt,omitempty"`
+	Succeeded bool                      `json:"succeeded,omitempty"`
+}
+
+// SnapshotWebAuthNChallenge is a not yet checked WebAuthN challenge of a [SnapshotState]
+type SnapshotWebAuthNChallenge struct {
+	Challenge            string                             `json:"challenge,omitempty"`
+	AllowedCredentialIDs [][]byte                           `json:"allowedCredentialIDs,omitempty"`
+	AllowedTransports    map[string][]string                `json:"allowedTransports,omitempty"`
+	UserVerification     domain.UserVerificationRequirement `json:"userVerification,omitempty"`
+	RPID                 string                             `json:"rpid,omitempty"`
+	Expiration           time.Time                          `json:"expiration,omitempty"`
+}
+
+// SnapshotEvent contains the aggregated state of the session,
+// so the session can be reduced from it without replaying all previous events.
+type SnapshotEvent struct {
+	eventstore.BaseEvent `json:"-"`
+
+	SnapshotState
+}
+
+func (e *SnapshotEvent) Data() interface{} {
+	return e
+}
+
+func (e *SnapshotEvent) UniqueConstraints() []*eventstore.EventUniqueConstraint {
+	return nil
+}
+
+func (e *SnapshotEvent) SetBaseEvent(base *eventstore.BaseEvent) {
+	e.BaseEvent = *base
+}
+
+func NewSnapshotEvent(
+	ctx context.Context,
+	aggregate *eventstore.Aggregate,
+	state SnapshotState,
+) *SnapshotEvent {
+	return &SnapshotEvent{
+		BaseEvent: *eventstore.NewBaseEventForPush(
+			ctx,
+			aggregate,
+			SnapshotType,
+		),
+		SnapshotState: state,
+	}
 }
@@ -149,11 +149,31 @@ func (w *Config) BeginLogin(ctx context.Context, user *domain.Human, userVerific
 		Challenge:               sessionData.Challenge,
 		CredentialAssertionData: cred,
 		AllowedCredentialIDs:    sessionData.AllowedCredentialIDs,
+		AllowedTransports:       allowedTransports(assertion.Response.AllowedCredentials),
 		UserVerification:        userVerification,
 		RPID:                    webAuthNServer.Config.RPID,
 	}, nil
 }
 
+// allowedTransports returns the transports of the credentials by their (base64 URL encoded) id
+func allowedTransports(credentials []protocol.CredentialDescriptor) map[string][]string {
+	var transports map[string][]string
+	for _, credential := range credentials {
+		if len(credential.Transport) == 0 {
+			continue
+		}
+		if transports == nil {
+			transports = make(map[string][]string, len(credentials))
+		}
+		list := make([]string, len(credential.Transport))
+		for i, transport := range credential.Transport {
+			list[i] = string(transport)
+		}
+		transports[credential.CredentialID.String()] = list
+	}
+	return transports
+}
+
 func (w *Config) FinishLogin(ctx context.Context, user *domain.Human, webAuthN *domain.WebAuthNLogin, credData []byte, webAuthNs ...*domain.WebAuthNToken) (*webauthn.Credential, error) {
 	assertionData, err := protocol.ParseCredentialRequestResponseBody(bytes.NewReader(credData))
 	if err != nil {
@@ -178,6 +198,24 @@ func (w *Config) FinishLogin(ctx context.Context, user *domain.Human, webAuthN *
 	return credential, nil
 }
 
+// AssertionClientData returns the client data (e.g. challenge and origin) of the provided credential assertion
+func AssertionClientData(credData []byte) (*protocol.CollectedClientData, error) {
+	assertionData, err := protocol.ParseCredentialRequestResponseBody(bytes.NewReader(credData))
+	if err != nil {
+		return nil, caos_errs.ThrowInvalidArgument(err, "WEBAU-Ohg4u", "Errors.User.WebAuthN.ValidateLoginFailed")
+	}
+	return &assertionData.Response.CollectedClientData, nil
+}
+
+// AssertionExtensionResults returns the client extension outputs (e.g. credProps or largeBlob) of the provided credential assertion
+func AssertionExtensionResults(credData []byte) (map[string]interface{}, error) {
+	assertionData, err := protocol.ParseCredentialRequestResponseBody(bytes.NewReader(credData))
+	if err != nil {
+		return nil, caos_errs.ThrowInvalidArgument(err, "WEBAU-ahR4e", "Errors.User.WebAuthN.ValidateLoginFailed")
+	}
+	return assertionData.ClientExtensionResults, nil
+}
+
 func (w *Config) serverFromContext(ctx context.Context, id, origin string) (*webauthn.WebAuthn, error) {
 	config := w.config(id, origin)
 	if id == "" {